@@ -0,0 +1,221 @@
+// Command migrate inspects and changes the database schema outside the server.
+//
+//	migrate status                 list applied and pending migrations
+//	migrate up [-to N] [-dry-run]  apply pending migrations (up to version N)
+//	migrate down [-to N] [-dry-run]
+//	                               roll back to version N (default: the latest one only)
+//	migrate new <description>      scaffold NNN_description.sql and its .down.sql
+//
+// It reads the same environment (.env, DB_TYPE, DB_PATH, DB_HOST, ...) as the server.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+	"github.com/whatisrealfreedom/freedom-website/internal/config"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	dir := flag.String("dir", "", "migrations directory (default: auto-detect)")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	if *dir == "" {
+		found, err := repository.FindMigrationsDir()
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		*dir = found
+	}
+
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+	if cmd == "new" {
+		runNew(*dir, args)
+		return
+	}
+
+	for _, envPath := range []string{"../../.env", "../.env", ".env"} {
+		if err := godotenv.Load(envPath); err == nil {
+			break
+		}
+	}
+
+	cfg := config.Load()
+	db, err := repository.OpenDatabase(cfg)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	m := repository.NewMigrator(db.GetDB(), db.Dialect(), *dir)
+
+	switch cmd {
+	case "status":
+		runStatus(m)
+	case "up":
+		runUp(m, args)
+	case "down":
+		runDown(m, args)
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: migrate [-dir path] <command> [flags]
+
+Commands:
+  status                    list applied and pending migrations
+  up   [-to N] [-dry-run]   apply pending migrations, optionally only up to version N
+  down [-to N] [-dry-run]   roll back migrations newer than version N (default: the latest one)
+  new  <description>        create the next numbered migration and its down file
+`)
+}
+
+func runStatus(m *repository.Migrator) {
+	statuses, err := m.Status()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	pending, drifted := 0, 0
+	for _, s := range statuses {
+		state, appliedAt := "pending", ""
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		switch {
+		case s.Missing:
+			state = "applied (file missing)"
+		case s.Drifted:
+			state = "applied (CHECKSUM MISMATCH)"
+			drifted++
+		case s.Applied:
+			state = "applied"
+		default:
+			pending++
+		}
+		if s.Migration != nil && s.Migration.DownPath == "" {
+			state += ", irreversible"
+		}
+		fmt.Fprintf(w, "%03d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d migration(s), %d pending\n", len(statuses), pending)
+	if drifted > 0 {
+		log.Fatalf("❌ %d applied migration(s) were modified after being applied", drifted)
+	}
+}
+
+func runUp(m *repository.Migrator, args []string) {
+	fs := flag.NewFlagSet("up", flag.ExitOnError)
+	to := fs.Int("to", 0, "apply migrations up to and including this version (default: latest)")
+	dryRun := fs.Bool("dry-run", false, "print the SQL that would run without executing it")
+	fs.Parse(args)
+
+	if *dryRun {
+		pending, err := m.PlanUp(*to)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		printPlan("apply", "applied", pending, func(mig *repository.Migration) (string, string) { return mig.UpPath, mig.UpSQL })
+		return
+	}
+
+	applied, err := m.Up(*to)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("%d migration(s) applied\n", len(applied))
+}
+
+func runDown(m *repository.Migrator, args []string) {
+	fs := flag.NewFlagSet("down", flag.ExitOnError)
+	to := fs.Int("to", -1, "roll back every migration newer than this version (default: only the latest)")
+	dryRun := fs.Bool("dry-run", false, "print the SQL that would run without executing it")
+	fs.Parse(args)
+
+	target := *to
+	if target < 0 {
+		var err error
+		if target, err = previousVersion(m); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	}
+
+	if *dryRun {
+		plan, err := m.PlanDown(target)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		printPlan("roll back", "rolled back", plan, func(mig *repository.Migration) (string, string) { return mig.DownPath, mig.DownSQL })
+		return
+	}
+
+	rolledBack, err := m.Down(target)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("%d migration(s) rolled back\n", len(rolledBack))
+}
+
+func runNew(dir string, args []string) {
+	if len(args) != 1 {
+		log.Fatal("usage: migrate new <description>")
+	}
+
+	upPath, downPath, err := repository.CreateMigration(dir, args[0])
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("Created %s\nCreated %s\n", upPath, downPath)
+}
+
+// previousVersion returns the version just below the latest applied one
+func previousVersion(m *repository.Migrator) (int, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return 0, err
+	}
+
+	latest, previous := 0, 0
+	for _, s := range statuses {
+		if s.Applied {
+			previous, latest = latest, s.Version
+		}
+	}
+	if latest == 0 {
+		return 0, fmt.Errorf("no applied migrations to roll back")
+	}
+	return previous, nil
+}
+
+func printPlan(verb, pastTense string, plan []*repository.Migration, source func(*repository.Migration) (string, string)) {
+	if len(plan) == 0 {
+		fmt.Printf("Nothing to %s\n", verb)
+		return
+	}
+
+	for _, mig := range plan {
+		path, sql := source(mig)
+		fmt.Printf("-- ==== %s %s (%s)\n%s\n", verb, mig.Name, path, sql)
+	}
+	fmt.Printf("-- dry run: %d migration(s) would be %s\n", len(plan), pastTense)
+}
@@ -1,17 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/whatisrealfreedom/freedom-website/internal/config"
 	"github.com/whatisrealfreedom/freedom-website/internal/handlers"
 	"github.com/whatisrealfreedom/freedom-website/internal/middleware"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
 	"github.com/whatisrealfreedom/freedom-website/internal/repository"
 	"github.com/whatisrealfreedom/freedom-website/internal/services"
+	"github.com/whatisrealfreedom/freedom-website/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -40,6 +45,20 @@ func main() {
 
 	// Load configuration
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("❌ Refusing to start: %v", err)
+	}
+
+	// Sign tokens with the Ed25519 key when configured, else with JWT_SECRET
+	if cfg.JWTSigningKeyFile != "" {
+		if err := utils.InitJWTKeys(cfg.JWTSigningKeyFile, cfg.VerifyKeyFiles()); err != nil {
+			log.Fatalf("❌ Refusing to start: %v", err)
+		}
+		log.Println("✅ JWT signing key loaded")
+	} else {
+		utils.InitJWT(cfg.JWTSecret)
+		log.Println("⚠️  JWT_SIGNING_KEY_FILE not set, signing tokens with JWT_SECRET (HS256)")
+	}
 
 	// Initialize database with retry logic
 	var db repository.Database
@@ -50,6 +69,11 @@ func main() {
 		if dbErr == nil {
 			break
 		}
+		if errors.Is(dbErr, repository.ErrChecksumMismatch) {
+			// An applied migration was edited; retrying or running on a schema
+			// that no longer matches the migration files is not safe
+			log.Fatalf("❌ Refusing to start: %v", dbErr)
+		}
 		log.Printf("⚠️  Database connection attempt %d/%d failed: %v", i+1, maxRetries, dbErr)
 		if i < maxRetries-1 {
 			log.Println("Retrying in 2 seconds...")
@@ -72,6 +96,9 @@ func main() {
 	if db != nil {
 		repo = repository.NewRepository(db)
 		log.Println("✅ Repository initialized")
+		if err := repo.Search.Rebuild(); err != nil {
+			log.Printf("⚠️  Failed to build search index: %v", err)
+		}
 	} else {
 		log.Println("⚠️  Repository not initialized - database unavailable")
 		log.Println("⚠️  Server will start but most endpoints will return 503")
@@ -82,36 +109,115 @@ func main() {
 
 	// Initialize services
 	var chapterService services.ChapterService
+	var localeService services.LocaleService
 	var resourceService services.ResourceService
+	var searchService services.SearchService
+	var sessionService services.SessionService
+	var twoFactorService services.TwoFactorService
 	if repo != nil {
+		localeService = services.NewLocaleService(repo.User, cfg.LocaleFallback)
 		if repo.Chapter != nil {
-			chapterService = services.NewChapterService(repo.Chapter)
+			chapterService = services.NewChapterService(repo.Chapter, localeService.Default())
 		}
 		if repo.Resource != nil {
 			resourceService = services.NewResourceService(repo.Resource)
 		}
+		if repo.Search != nil {
+			searchService = services.NewSearchService(repo.Search)
+		}
+		sessionService = services.NewSessionService(repo.Session, repo.User, cfg.AccessTokenTTL(), cfg.RefreshTokenTTL())
+		twoFactorService = services.NewTwoFactorService(repo.TwoFactor, cfg.MailFromName)
 	}
-	emailService := services.NewEmailService(cfg)
 
 	// Initialize handlers
 	var chapterHandler *handlers.ChapterHandler
 	var resourceHandler *handlers.ResourceHandler
+	var searchHandler *handlers.SearchHandler
+	var adminChapterHandler *handlers.AdminChapterHandler
+	var adminUserHandler *handlers.AdminUserHandler
 	var authHandler *handlers.AuthHandler
 	var discussionHandler *handlers.DiscussionHandler
+	var moderationHandler *handlers.ModerationHandler
+	var profileHandler *handlers.ProfileHandler
+	var avatarHandler *handlers.AvatarHandler
+	var accountHandler *handlers.AccountHandler
+	var referralHandler *handlers.ReferralHandler
+	var telegramHandler *handlers.TelegramHandler
+	var oauthHandler *handlers.OAuthHandler
+	var twoFactorHandler *handlers.TwoFactorHandler
 	
 	if chapterService != nil {
-		chapterHandler = handlers.NewChapterHandler(chapterService)
+		chapterHandler = handlers.NewChapterHandler(chapterService, localeService)
 	}
 	if resourceService != nil {
 		resourceHandler = handlers.NewResourceHandler(resourceService)
 	}
+	if searchService != nil {
+		searchHandler = handlers.NewSearchHandler(searchService)
+	}
+	if repo != nil && repo.Chapter != nil {
+		adminChapterHandler = handlers.NewAdminChapterHandler(services.NewChapterAdminService(repo.Chapter, repo.Search))
+		adminUserHandler = handlers.NewAdminUserHandler(services.NewRoleService(repo.Role, repo.User))
+	}
 	healthHandler := handlers.NewHealthHandlerWithDB(db)
 	if repo != nil && repo.User != nil {
-		authHandler = handlers.NewAuthHandler(repo.User, emailService, cfg.JWTSecret, cfg.JWTExpiry)
+		// Requests only queue email; the worker delivers it in the background
+		mailer, err := services.NewMailer(cfg, os.Stdout)
+		if err != nil {
+			log.Fatalf("❌ Refusing to start: %v", err)
+		}
+		go services.NewOutboxWorker(repo.Outbox, mailer).Run(context.Background())
+		log.Println("✅ Email outbox worker started")
+
+		emailService, err := services.NewEmailService(cfg, repo.Outbox, localeService)
+		if err != nil {
+			log.Fatalf("❌ Refusing to start: %v", err)
+		}
+		guard := services.NewAttemptGuard(services.NewMemoryAttemptStore(), repo.Audit, services.DefaultAccountPolicy, services.DefaultIPPolicy)
+		referralService := services.NewReferralService(repo.User, repo.Points)
+		twoFactorHandler = handlers.NewTwoFactorHandler(twoFactorService, repo.User, guard)
+		var telegramAuth *services.TelegramAuth
+		if cfg.TelegramBotToken != "" {
+			telegramAuth = services.NewTelegramAuth(cfg.TelegramBotToken)
+		}
+		reauthService := services.NewReauthService(repo.User, twoFactorService, emailService, telegramAuth)
+		authHandler = handlers.NewAuthHandler(repo.User, emailService, sessionService, guard, referralService, twoFactorService, reauthService)
+		referralHandler = handlers.NewReferralHandler(referralService)
+		if telegramAuth != nil {
+			telegramHandler = handlers.NewTelegramHandler(telegramAuth,
+				services.NewTelegramService(repo.User, repo.Identity, referralService), sessionService, twoFactorService)
+			log.Println("✅ Telegram login enabled")
+		}
+		var providers []services.OAuthProvider
+		if cfg.GoogleClientID != "" {
+			providers = append(providers, services.NewOIDCProvider(models.ProviderGoogle, services.GoogleIssuer, services.OAuthClientConfig{
+				ClientID: cfg.GoogleClientID, ClientSecret: cfg.GoogleClientSecret, RedirectURL: cfg.OAuthCallbackURL(models.ProviderGoogle),
+			}))
+		}
+		if cfg.GitHubClientID != "" {
+			providers = append(providers, services.NewGitHubProvider(services.OAuthClientConfig{
+				ClientID: cfg.GitHubClientID, ClientSecret: cfg.GitHubClientSecret, RedirectURL: cfg.OAuthCallbackURL(models.ProviderGitHub),
+			}))
+		}
+		oauthService := services.NewOAuthService(repo.Identity, repo.User, referralService, providers...)
+		oauthHandler = handlers.NewOAuthHandler(oauthService, sessionService, twoFactorService)
+		if names := oauthService.Providers(); len(names) > 0 {
+			log.Printf("✅ OAuth login enabled: %s", strings.Join(names, ", "))
+		}
+		profileHandler = handlers.NewProfileHandler(services.NewProfileService(repo.User))
+		avatarService := services.NewAvatarService(repo.User, services.NewLocalStorage(cfg.StorageDir))
+		avatarHandler = handlers.NewAvatarHandler(avatarService)
+		accountService := services.NewAccountService(repo.Account, repo.User, avatarService, repo.Audit)
+		accountHandler = handlers.NewAccountHandler(accountService, reauthService, guard)
+		// Anonymizes the accounts whose deletion grace period is over
+		go services.RunAccountPurge(context.Background(), accountService, time.Hour)
 	}
 	if repo != nil && repo.Thread != nil && repo.Comment != nil && repo.Vote != nil && repo.Reaction != nil {
 		discussionHandler = handlers.NewDiscussionHandler(repo.Thread, repo.Comment, repo.Vote, repo.Reaction)
 	}
+	if repo != nil && repo.Moderation != nil {
+		moderationHandler = handlers.NewModerationHandler(repo.Moderation)
+	}
 
 	// Setup router
 	if cfg.Env == "production" {
@@ -119,15 +225,40 @@ func main() {
 	}
 
 	router := gin.Default()
+	// c.ClientIP() keys the rate limits and lockouts; only the configured
+	// proxies may set it through X-Forwarded-For
+	if err := router.SetTrustedProxies(cfg.TrustedProxyList()); err != nil {
+		log.Fatalf("❌ Refusing to start: %v", err)
+	}
 
 	// Serve static files (PDFs)
 	router.Static("/files", "./files")
 
+	// Public keys for other services to verify our tokens
+	router.GET("/.well-known/jwks.json", handlers.NewJWKSHandler().Get)
+
 	// CORS middleware
 	router.Use(corsMiddleware(cfg))
 
+	// Rate limits per route group (validated with the config)
+	rateLimit := func(rule string) gin.HandlerFunc {
+		limit, _ := config.ParseRateLimit(rule)
+		if limit.Requests == 0 {
+			return middleware.RateLimit(nil)
+		}
+		return middleware.RateLimit(middleware.NewRateLimiter(limit.Requests, limit.Period))
+	}
+	emailLimit := rateLimit(cfg.RateLimitEmail)
+	writeLimit := rateLimit(cfg.RateLimitWrite)
+	reactLimit := rateLimit(cfg.RateLimitReact)
+
+	telegramUnavailable := func(c *gin.Context) {
+		c.JSON(503, gin.H{"error": "Telegram login is not configured"})
+	}
+
 	// Routes
 	api := router.Group("/api/v1")
+	api.Use(rateLimit(cfg.RateLimitAPI))
 	{
 		// Health check
 		api.GET("/health", healthHandler.Check)
@@ -135,18 +266,66 @@ func main() {
 		// Authentication routes (public)
 		if authHandler != nil {
 			auth := api.Group("/auth")
+			auth.Use(rateLimit(cfg.RateLimitAuth))
 			{
-				auth.POST("/register", authHandler.Register)
+				// These send email
+				auth.POST("/register", emailLimit, authHandler.Register)
+				auth.POST("/resend-code", emailLimit, authHandler.ResendVerificationCode)
+				auth.POST("/forgot-password", emailLimit, authHandler.ForgotPassword)
+
 				auth.POST("/verify-email", authHandler.VerifyEmail)
-				auth.POST("/resend-code", authHandler.ResendVerificationCode)
 				auth.POST("/login", authHandler.Login)
+				auth.POST("/reset-password", authHandler.ResetPassword)
+				auth.POST("/refresh", authHandler.Refresh)
+				auth.POST("/logout", authHandler.Logout)
+				auth.POST("/2fa", authHandler.CompleteTwoFactor)
+				if telegramHandler != nil {
+					auth.POST("/telegram", telegramHandler.Login)
+				} else {
+					auth.POST("/telegram", telegramUnavailable)
+				}
+
+				// Unconfigured providers answer 404
+				auth.GET("/oauth", oauthHandler.Providers)
+				auth.POST("/oauth/:provider", oauthHandler.Start)
+				auth.POST("/oauth/:provider/callback", oauthHandler.Callback)
 			}
 
 			// Protected routes
 			protected := api.Group("")
-			protected.Use(middleware.AuthMiddleware())
+			protected.Use(middleware.AuthMiddleware(sessionService))
 			{
 				protected.GET("/me", authHandler.GetMe)
+				protected.PATCH("/me", writeLimit, profileHandler.UpdateMe)
+				protected.DELETE("/me", writeLimit, accountHandler.Delete)
+				protected.POST("/me/restore", writeLimit, accountHandler.CancelDeletion)
+				protected.GET("/me/reauth", accountHandler.ReauthMethods)
+				protected.POST("/me/reauth/code", emailLimit, accountHandler.SendReauthCode)
+				protected.POST("/me/email", emailLimit, authHandler.ChangeEmail)
+				protected.POST("/me/email/verify", writeLimit, authHandler.ConfirmEmailChange)
+				// Exports are built in memory; writeLimit keeps them rare
+				protected.GET("/me/export", writeLimit, accountHandler.Export)
+				protected.POST("/me/avatar", writeLimit, avatarHandler.Upload)
+				protected.GET("/me/referrals", referralHandler.Get)
+				if telegramHandler != nil {
+					protected.POST("/me/telegram", telegramHandler.Link)
+					protected.DELETE("/me/telegram", telegramHandler.Unlink)
+				} else {
+					protected.POST("/me/telegram", telegramUnavailable)
+					protected.DELETE("/me/telegram", telegramUnavailable)
+				}
+				protected.GET("/me/2fa", twoFactorHandler.Status)
+				protected.POST("/me/2fa/setup", writeLimit, twoFactorHandler.Setup)
+				protected.POST("/me/2fa/enable", writeLimit, twoFactorHandler.Enable)
+				protected.POST("/me/2fa/disable", writeLimit, twoFactorHandler.Disable)
+				protected.POST("/me/2fa/recovery-codes", writeLimit, twoFactorHandler.RegenerateRecoveryCodes)
+				protected.GET("/me/identities", oauthHandler.ListIdentities)
+				protected.POST("/me/identities/:provider", oauthHandler.StartLink)
+				protected.POST("/me/identities/:provider/callback", oauthHandler.LinkCallback)
+				protected.DELETE("/me/identities/:provider", oauthHandler.Unlink)
+				protected.GET("/me/sessions", authHandler.ListSessions)
+				protected.DELETE("/me/sessions", authHandler.RevokeOtherSessions)
+				protected.DELETE("/me/sessions/:id", authHandler.RevokeSession)
 			}
 		} else {
 			api.POST("/auth/*path", func(c *gin.Context) {
@@ -157,12 +336,27 @@ func main() {
 			})
 		}
 
+		// Public profiles
+		if profileHandler != nil {
+			api.GET("/users/:id", profileHandler.GetPublic)
+			api.GET("/avatars/:user/:file", avatarHandler.Serve)
+		} else {
+			api.GET("/users/:id", func(c *gin.Context) {
+				c.JSON(503, gin.H{"error": "Database service unavailable"})
+			})
+			api.GET("/avatars/:user/:file", func(c *gin.Context) {
+				c.JSON(503, gin.H{"error": "Database service unavailable"})
+			})
+		}
+
 		// Chapters (only if handler is available)
 		if chapterHandler != nil {
 			chapters := api.Group("/chapters")
+			chapters.Use(middleware.OptionalAuthMiddleware(sessionService))
 			{
 				chapters.GET("", chapterHandler.GetAll)
 				chapters.GET("/:id", chapterHandler.GetByID)
+				chapters.GET("/by-slug/:locale/:slug", chapterHandler.GetByLocaleSlug)
 			}
 		} else {
 			api.GET("/chapters", func(c *gin.Context) {
@@ -171,6 +365,9 @@ func main() {
 			api.GET("/chapters/:id", func(c *gin.Context) {
 				c.JSON(503, gin.H{"error": "Database service unavailable"})
 			})
+			api.GET("/chapters/by-slug/:locale/:slug", func(c *gin.Context) {
+				c.JSON(503, gin.H{"error": "Database service unavailable"})
+			})
 		}
 
 		// Resources (only if handler is available)
@@ -189,6 +386,48 @@ func main() {
 			})
 		}
 
+		// Search
+		if searchHandler != nil {
+			api.GET("/search", searchHandler.Search)
+		} else {
+			api.GET("/search", func(c *gin.Context) {
+				c.JSON(503, gin.H{"error": "Database service unavailable"})
+			})
+		}
+
+		// Admin (content and user management, guarded per permission)
+		if adminChapterHandler != nil {
+			admin := api.Group("/admin")
+			admin.Use(middleware.AuthMiddleware(sessionService))
+			if cfg.StaffTwoFactor {
+				admin.Use(middleware.RequireTwoFactor(twoFactorService))
+			}
+
+			adminChapters := admin.Group("/chapters")
+			adminChapters.Use(middleware.RequirePermission(repo.Role, models.PermissionManageChapters))
+			{
+				adminChapters.GET("", adminChapterHandler.ListChapters)
+				adminChapters.POST("", adminChapterHandler.CreateChapter)
+				adminChapters.PUT("/order", adminChapterHandler.ReorderChapters)
+				adminChapters.PATCH("/:id", adminChapterHandler.UpdateChapter)
+				adminChapters.DELETE("/:id", adminChapterHandler.DeleteChapter)
+				adminChapters.GET("/:id/translations", adminChapterHandler.ListTranslations)
+				adminChapters.PUT("/:id/translations/:locale", adminChapterHandler.SaveTranslation)
+				adminChapters.DELETE("/:id/translations/:locale", adminChapterHandler.DeleteTranslation)
+			}
+
+			adminUsers := admin.Group("")
+			adminUsers.Use(middleware.RequirePermission(repo.Role, models.PermissionManageUsers))
+			{
+				adminUsers.GET("/roles", adminUserHandler.ListRoles)
+				adminUsers.PUT("/users/:id/role", adminUserHandler.SetRole)
+			}
+		} else {
+			api.Any("/admin/*path", func(c *gin.Context) {
+				c.JSON(503, gin.H{"error": "Database service unavailable"})
+			})
+		}
+
 		// Discussions (public read, protected write)
 		if discussionHandler != nil {
 			discussions := api.Group("/discussions")
@@ -198,16 +437,18 @@ func main() {
 				
 				// Protected routes
 				discussionsProtected := discussions.Group("")
-				discussionsProtected.Use(middleware.AuthMiddleware())
+				discussionsProtected.Use(middleware.AuthMiddleware(sessionService))
 				{
-					discussionsProtected.POST("", discussionHandler.CreateThread)
-					discussionsProtected.PUT("/:id", discussionHandler.UpdateThread)
-					discussionsProtected.POST("/:id/comments", discussionHandler.CreateComment)
-					discussionsProtected.PUT("/comments/:id", discussionHandler.UpdateComment)
-					discussionsProtected.POST("/:id/vote", discussionHandler.VoteThread)
-					discussionsProtected.POST("/comments/:id/vote", discussionHandler.VoteComment)
-					discussionsProtected.POST("/:id/react", discussionHandler.ReactThread)
-					discussionsProtected.POST("/comments/:id/react", discussionHandler.ReactComment)
+					discussionsProtected.POST("", writeLimit, discussionHandler.CreateThread)
+					discussionsProtected.PUT("/:id", writeLimit, discussionHandler.UpdateThread)
+					discussionsProtected.DELETE("/:id", writeLimit, discussionHandler.DeleteThread)
+					discussionsProtected.POST("/:id/comments", writeLimit, discussionHandler.CreateComment)
+					discussionsProtected.PUT("/comments/:id", writeLimit, discussionHandler.UpdateComment)
+					discussionsProtected.DELETE("/comments/:id", writeLimit, discussionHandler.DeleteComment)
+					discussionsProtected.POST("/:id/vote", reactLimit, discussionHandler.VoteThread)
+					discussionsProtected.POST("/comments/:id/vote", reactLimit, discussionHandler.VoteComment)
+					discussionsProtected.POST("/:id/react", reactLimit, discussionHandler.ReactThread)
+					discussionsProtected.POST("/comments/:id/react", reactLimit, discussionHandler.ReactComment)
 				}
 			}
 		} else {
@@ -218,6 +459,24 @@ func main() {
 				c.JSON(503, gin.H{"error": "Database service unavailable"})
 			})
 		}
+
+		// Moderation (pin, lock, delete and restore; every action is logged with a reason)
+		if moderationHandler != nil {
+			moderation := api.Group("/moderation")
+			moderation.Use(middleware.AuthMiddleware(sessionService), middleware.RequirePermission(repo.Role, models.PermissionModerateDiscussions))
+			if cfg.StaffTwoFactor {
+				moderation.Use(middleware.RequireTwoFactor(twoFactorService))
+			}
+			{
+				moderation.GET("/actions", moderationHandler.GetActions)
+				moderation.POST("/threads/:id/:action", moderationHandler.ModerateThread)
+				moderation.POST("/comments/:id/:action", moderationHandler.ModerateComment)
+			}
+		} else {
+			api.Any("/moderation/*path", func(c *gin.Context) {
+				c.JSON(503, gin.H{"error": "Database service unavailable"})
+			})
+		}
 	}
 
 	// Start server
@@ -235,6 +494,7 @@ func corsMiddleware(cfg *config.Config) gin.HandlerFunc {
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "RateLimit-Policy, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -1,9 +1,19 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// defaultJWTSecret is the placeholder used when JWT_SECRET is unset; like the
+// one in .env.example it starts with "change-me"
+const defaultJWTSecret = "change-me-in-production"
+
 type Config struct {
 	ServerHost         string
 	ServerPort         string
@@ -16,9 +26,14 @@ type Config struct {
 	DBUser             string
 	DBPassword         string
 	DBSSLMode          string
+	AutoMigrate        bool
 	CORSAllowedOrigins string
+	LocaleFallback     string
 	JWTSecret          string
 	JWTExpiry          string
+	RefreshTokenExpiry string
+	JWTSigningKeyFile  string
+	JWTVerifyKeyFiles  string
 	MailHost           string
 	MailPort           string
 	MailUsername       string
@@ -26,6 +41,33 @@ type Config struct {
 	MailEncryption     string
 	MailFromAddress    string
 	MailFromName       string
+	MailTransport      string
+	MailLogFile        string
+	// StorageDir is where uploads such as avatars are kept
+	StorageDir string
+	// TelegramBotToken enables Telegram login; empty disables it
+	TelegramBotToken string
+	// OAuth clients; a provider is enabled when its client ID is set
+	GoogleClientID     string
+	GoogleClientSecret string
+	GitHubClientID     string
+	GitHubClientSecret string
+	// OAuthRedirectURL is the frontend page providers send users back to,
+	// followed by "/<provider>"
+	OAuthRedirectURL string
+	// StaffTwoFactor keeps admins and moderators out of the admin and
+	// moderation routes until they turn on two-factor authentication
+	StaffTwoFactor bool
+	// TrustedProxies lists the addresses or CIDR ranges of reverse proxies
+	// whose X-Forwarded-For is believed; empty means no proxy, and the
+	// client IP is the connection's remote address
+	TrustedProxies string
+	// Rate limits per route group, see ParseRateLimit
+	RateLimitAPI   string
+	RateLimitAuth  string
+	RateLimitEmail string
+	RateLimitWrite string
+	RateLimitReact string
 }
 
 // GetDBPath returns the database path for migration helper
@@ -33,6 +75,97 @@ func (c *Config) GetDBPath() string {
 	return c.DBPath
 }
 
+// AccessTokenTTL is the lifetime of access tokens (JWT_EXPIRY)
+func (c *Config) AccessTokenTTL() time.Duration {
+	return parseDuration(c.JWTExpiry, 15*time.Minute)
+}
+
+// RefreshTokenTTL is how long a session lasts without a refresh (REFRESH_TOKEN_EXPIRY)
+func (c *Config) RefreshTokenTTL() time.Duration {
+	return parseDuration(c.RefreshTokenExpiry, 30*24*time.Hour)
+}
+
+// VerifyKeyFiles lists the retired JWT keys still accepted (JWT_VERIFY_KEY_FILES)
+func (c *Config) VerifyKeyFiles() []string {
+	var files []string
+	for _, file := range strings.Split(c.JWTVerifyKeyFiles, ",") {
+		if file = strings.TrimSpace(file); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// TrustedProxyList splits TRUSTED_PROXIES; nil when there is no proxy
+func (c *Config) TrustedProxyList() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(c.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// OAuthCallbackURL is the redirect URL registered at provider
+func (c *Config) OAuthCallbackURL(provider string) string {
+	return strings.TrimSuffix(c.OAuthRedirectURL, "/") + "/" + provider
+}
+
+// Validate rejects settings the server must not run with
+func (c *Config) Validate() error {
+	// Without a signing key tokens are signed with JWT_SECRET, and a
+	// placeholder secret would let anyone mint admin tokens
+	if c.Env == "production" && c.JWTSigningKeyFile == "" &&
+		(c.JWTSecret == "" || strings.HasPrefix(c.JWTSecret, "change-me")) {
+		return errors.New("JWT_SECRET is a placeholder; set JWT_SIGNING_KEY_FILE or a strong JWT_SECRET in production")
+	}
+	for _, proxy := range c.TrustedProxyList() {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return fmt.Errorf("TRUSTED_PROXIES: %q is not an IP address or CIDR range", proxy)
+			}
+		}
+	}
+	for name, rule := range map[string]string{
+		"RATE_LIMIT_API":   c.RateLimitAPI,
+		"RATE_LIMIT_AUTH":  c.RateLimitAuth,
+		"RATE_LIMIT_EMAIL": c.RateLimitEmail,
+		"RATE_LIMIT_WRITE": c.RateLimitWrite,
+		"RATE_LIMIT_REACT": c.RateLimitReact,
+	} {
+		if _, err := ParseRateLimit(rule); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// RateLimit allows Requests per Period; the zero value means no limit
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseRateLimit parses "<requests>/<period>", e.g. "10/1m". "off" disables
+// the limit.
+func ParseRateLimit(rule string) (RateLimit, error) {
+	rule = strings.TrimSpace(rule)
+	if rule == "off" {
+		return RateLimit{}, nil
+	}
+	requests, period, ok := strings.Cut(rule, "/")
+	n, err := strconv.Atoi(requests)
+	if !ok || err != nil || n <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, want e.g. 10/1m or off", rule)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, want e.g. 10/1m or off", rule)
+	}
+	return RateLimit{Requests: n, Period: d}, nil
+}
+
 func Load() *Config {
 	return &Config{
 		ServerHost:         getEnv("SERVER_HOST", "0.0.0.0"),
@@ -46,9 +179,14 @@ func Load() *Config {
 		DBUser:             getEnv("DB_USER", "freedom_user"),
 		DBPassword:         getEnv("DB_PASSWORD", ""),
 		DBSSLMode:          getEnv("DB_SSL_MODE", "disable"),
+		AutoMigrate:        getEnv("AUTO_MIGRATE", "true") != "false",
 		CORSAllowedOrigins: getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:3000,http://localhost:8098"),
-		JWTSecret:          getEnv("JWT_SECRET", "change-me-in-production"),
-		JWTExpiry:          getEnv("JWT_EXPIRY", "24h"),
+		LocaleFallback:     getEnv("LOCALE_FALLBACK", "fa,en"),
+		JWTSecret:          getEnv("JWT_SECRET", defaultJWTSecret),
+		JWTExpiry:          getEnv("JWT_EXPIRY", "15m"),
+		RefreshTokenExpiry: getEnv("REFRESH_TOKEN_EXPIRY", "720h"),
+		JWTSigningKeyFile:  getEnv("JWT_SIGNING_KEY_FILE", ""),
+		JWTVerifyKeyFiles:  getEnv("JWT_VERIFY_KEY_FILES", ""),
 		MailHost:           getEnv("MAIL_HOST", "smtp.gmail.com"),
 		MailPort:           getEnv("MAIL_PORT", "587"),
 		MailUsername:       getEnv("MAIL_USERNAME", ""),
@@ -56,6 +194,22 @@ func Load() *Config {
 		MailEncryption:     getEnv("MAIL_ENCRYPTION", "tls"),
 		MailFromAddress:    getEnv("MAIL_FROM_ADDRESS", ""),
 		MailFromName:       getEnv("MAIL_FROM_NAME", "RealFreedom"),
+		MailTransport:      getEnv("MAIL_TRANSPORT", ""),
+		MailLogFile:        getEnv("MAIL_LOG_FILE", ""),
+		StorageDir:         getEnv("STORAGE_DIR", "./data/storage"),
+		TelegramBotToken:   getEnv("TELEGRAM_BOT_TOKEN", ""),
+		GoogleClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
+		GoogleClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
+		GitHubClientID:     getEnv("GITHUB_CLIENT_ID", ""),
+		GitHubClientSecret: getEnv("GITHUB_CLIENT_SECRET", ""),
+		OAuthRedirectURL:   getEnv("OAUTH_REDIRECT_URL", "http://localhost:8098/oauth/callback"),
+		StaffTwoFactor:     getEnv("REQUIRE_STAFF_TWO_FACTOR", "false") == "true",
+		TrustedProxies:     getEnv("TRUSTED_PROXIES", ""),
+		RateLimitAPI:       getEnv("RATE_LIMIT_API", "300/1m"),
+		RateLimitAuth:      getEnv("RATE_LIMIT_AUTH", "20/1m"),
+		RateLimitEmail:     getEnv("RATE_LIMIT_EMAIL", "5/15m"),
+		RateLimitWrite:     getEnv("RATE_LIMIT_WRITE", "10/1m"),
+		RateLimitReact:     getEnv("RATE_LIMIT_REACT", "60/1m"),
 	}
 }
 
@@ -65,3 +219,12 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// parseDuration parses value, falling back to defaultValue when it is not a
+// positive duration
+func parseDuration(value string, defaultValue time.Duration) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	return defaultValue
+}
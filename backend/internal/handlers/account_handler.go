@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+// AccountHandler serves the personal data export, account deletion and the
+// re-authentication it needs; every route must be behind AuthMiddleware
+type AccountHandler struct {
+	service services.AccountService
+	reauth  services.ReauthService
+	guard   *services.AttemptGuard
+}
+
+func NewAccountHandler(service services.AccountService, reauth services.ReauthService, guard *services.AttemptGuard) *AccountHandler {
+	return &AccountHandler{service: service, reauth: reauth, guard: guard}
+}
+
+// reauthenticate checks that the signed-in user owns the account before a
+// change that could take it over. Wrong proof counts towards the lockout
+// like a wrong password at sign-in. It writes the response and returns
+// false when the request must stop.
+func reauthenticate(c *gin.Context, reauth services.ReauthService, guard *services.AttemptGuard, userID int64, proof models.ReauthRequest) bool {
+	account := strconv.FormatInt(userID, 10)
+	if lockedOut(c, guard, services.ScopeReauth, account) {
+		return false
+	}
+
+	err := reauth.Check(userID, proof)
+	if errors.Is(err, services.ErrWrongPassword) || errors.Is(err, services.ErrInvalidTwoFactorCode) ||
+		errors.Is(err, services.ErrInvalidReauthCode) || errors.Is(err, services.ErrInvalidReauthTelegram) {
+		guard.Fail(services.ScopeReauth, account, c.ClientIP())
+	}
+	if err != nil {
+		respondServiceError(c, err, "Failed to confirm it's you")
+		return false
+	}
+	guard.Succeed(services.ScopeReauth, account)
+	return true
+}
+
+// ReauthMethods handles GET /me/reauth: what DELETE /me and POST /me/email
+// must carry (models.ReauthMethods)
+func (h *AccountHandler) ReauthMethods(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	methods, err := h.reauth.Methods(userID.(int64))
+	if err != nil {
+		respondServiceError(c, err, "Failed to load confirmation methods")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": models.ReauthMethods{Methods: methods}})
+}
+
+// SendReauthCode handles POST /me/reauth/code: emails a confirmation code to
+// accounts that have neither a password nor two-factor authentication
+func (h *AccountHandler) SendReauthCode(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	if err := h.reauth.SendCode(userID.(int64)); err != nil {
+		respondServiceError(c, err, "Failed to send confirmation code")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Confirmation code sent"})
+}
+
+// Export handles GET /me/export: a ZIP of JSON files with everything the user
+// wrote or saved
+func (h *AccountHandler) Export(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	data, err := h.service.Export(userID.(int64))
+	if err != nil {
+		respondServiceError(c, err, "Failed to export your data")
+		return
+	}
+
+	name := fmt.Sprintf("realfreedom-export-%s.zip", time.Now().UTC().Format("2006-01-02"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", data)
+}
+
+// Delete handles DELETE /me: schedules the account for deletion after
+// models.AccountDeletionGrace
+func (h *AccountHandler) Delete(c *gin.Context) {
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if !reauthenticate(c, h.reauth, h.guard, userID.(int64), req.ReauthRequest) {
+		return
+	}
+	deletion, err := h.service.RequestDeletion(userID.(int64))
+	if err != nil {
+		respondServiceError(c, err, "Failed to delete account")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"data": deletion})
+}
+
+// CancelDeletion handles POST /me/restore: keeps an account whose deletion
+// is still pending
+func (h *AccountHandler) CancelDeletion(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	if err := h.service.CancelDeletion(userID.(int64)); err != nil {
+		respondServiceError(c, err, "Failed to cancel account deletion")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deletion cancelled"})
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/middleware"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+func TestAccountHandler(t *testing.T) {
+	_, db := setupAdminRouter(t)
+	users := repository.NewUserRepository(db)
+	accounts := services.NewAccountService(repository.NewAccountRepository(db), users,
+		services.NewAvatarService(users, services.NewLocalStorage(t.TempDir())), repository.NewAuditRepository(db))
+	handler := NewAccountHandler(accounts, newReauthService(t, db), newAttemptGuard(db))
+
+	router := gin.New()
+	protected := router.Group("/api/v1", middleware.AuthMiddleware(newSessionService(db)))
+	protected.GET("/me/export", handler.Export)
+	protected.DELETE("/me", handler.Delete)
+	protected.POST("/me/restore", handler.CancelDeletion)
+	protected.GET("/me/reauth", handler.ReauthMethods)
+	protected.POST("/me/reauth/code", handler.SendReauthCode)
+
+	userID, token := createTestUser(t, db, "reader@example.com", models.RoleReader)
+
+	t.Run("export", func(t *testing.T) {
+		w := adminRequest(router, "GET", "/api/v1/me/export", token, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+		zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		require.NoError(t, err)
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		assert.ElementsMatch(t, []string{"profile.json", "threads.json", "comments.json", "votes.json", "reactions.json", "drafts.json"}, names)
+
+		w = adminRequest(router, "GET", "/api/v1/me/export", "", nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("delete and restore", func(t *testing.T) {
+		w := adminRequest(router, "POST", "/api/v1/me/restore", token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "nothing to cancel")
+
+		w = adminRequest(router, "DELETE", "/api/v1/me", token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = adminRequest(router, "DELETE", "/api/v1/me", token, gin.H{"password": "wrong"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// createTestUser's password is "x"
+		w = adminRequest(router, "DELETE", "/api/v1/me", token, gin.H{"password": "x"})
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		var body struct{ Data models.AccountDeletion }
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, body.Data.RequestedAt.Add(models.AccountDeletionGrace), body.Data.DeleteAfter)
+
+		user, err := users.GetByID(userID)
+		require.NoError(t, err)
+		require.NotNil(t, user.DeletionRequestedAt)
+
+		// The account keeps working until the grace period is over
+		w = adminRequest(router, "POST", "/api/v1/me/restore", token, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		user, err = users.GetByID(userID)
+		require.NoError(t, err)
+		assert.Nil(t, user.DeletionRequestedAt)
+	})
+	t.Run("accounts without a password confirm with an emailed code", func(t *testing.T) {
+		oauthID, oauthToken := createTestUser(t, db, "oauth@example.com", models.RoleReader)
+		_, err := db.Exec(`UPDATE users SET registration_src = ?, password_set = FALSE WHERE id = ?`, models.ProviderGoogle, oauthID)
+		require.NoError(t, err)
+
+		w := adminRequest(router, "GET", "/api/v1/me/reauth", oauthToken, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var methods struct{ Data models.ReauthMethods }
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &methods))
+		assert.Equal(t, []string{models.ReauthEmailCode}, methods.Data.Methods)
+
+		// Being signed in is not enough, and neither is the random password
+		w = adminRequest(router, "DELETE", "/api/v1/me", oauthToken, gin.H{})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = adminRequest(router, "DELETE", "/api/v1/me", oauthToken, gin.H{"password": "x"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = adminRequest(router, "POST", "/api/v1/me/reauth/code", oauthToken, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var code string
+		require.NoError(t, db.QueryRow(
+			`SELECT code FROM email_verification_codes WHERE user_id = ? AND purpose = ?`, oauthID, models.CodePurposeReauth,
+		).Scan(&code))
+		assert.Contains(t, outboxTo(t, repository.NewOutboxRepository(db), "oauth@example.com").TextBody, code)
+
+		w = adminRequest(router, "DELETE", "/api/v1/me", oauthToken, gin.H{"code": code})
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		user, err := users.GetByID(oauthID)
+		require.NoError(t, err)
+		assert.NotNil(t, user.DeletionRequestedAt)
+	})
+
+	t.Run("wrong proof locks re-authentication", func(t *testing.T) {
+		lockedID, lockedToken := createTestUser(t, db, "locked@example.com", models.RoleReader)
+		for i := 0; i < services.DefaultAccountPolicy.MaxFailures; i++ {
+			w := adminRequest(router, "DELETE", "/api/v1/me", lockedToken, gin.H{"password": "wrong"})
+			require.Equal(t, http.StatusBadRequest, w.Code)
+		}
+
+		w := adminRequest(router, "DELETE", "/api/v1/me", lockedToken, gin.H{"password": "x"})
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+		user, err := users.GetByID(lockedID)
+		require.NoError(t, err)
+		assert.Nil(t, user.DeletionRequestedAt)
+	})
+}
+
+func TestAccountHandler_TelegramReauth(t *testing.T) {
+	_, db := setupAdminRouter(t)
+	users := repository.NewUserRepository(db)
+	sessions := newSessionService(db)
+	accounts := services.NewAccountService(repository.NewAccountRepository(db), users,
+		services.NewAvatarService(users, services.NewLocalStorage(t.TempDir())), repository.NewAuditRepository(db))
+	auth := services.NewTelegramAuth("123456:TEST-BOT-TOKEN")
+	auth.Now = func() time.Time { return time.Unix(1760000000, 0).Add(time.Minute) }
+	reauth := services.NewReauthService(users, newTwoFactorService(db), newEmailService(t, db), auth)
+	handler := NewAccountHandler(accounts, reauth, newAttemptGuard(db))
+
+	router := gin.New()
+	protected := router.Group("/api/v1", middleware.AuthMiddleware(sessions))
+	protected.DELETE("/me", handler.Delete)
+	protected.GET("/me/reauth", handler.ReauthMethods)
+
+	// Ali signed up with Telegram and shares no email
+	telegram := services.NewTelegramService(users, repository.NewIdentityRepository(db), newReferralService(db))
+	user, _, err := telegram.SignIn(&models.TelegramUser{ID: 987654321, FirstName: "Ali"}, "")
+	require.NoError(t, err)
+	pair, err := sessions.Start(user, "test", "127.0.0.1")
+	require.NoError(t, err)
+
+	w := adminRequest(router, "GET", "/api/v1/me/reauth", pair.Token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var methods struct{ Data models.ReauthMethods }
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &methods))
+	assert.Equal(t, []string{models.ReauthTelegram}, methods.Data.Methods)
+
+	w = adminRequest(router, "DELETE", "/api/v1/me", pair.Token, gin.H{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = adminRequest(router, "DELETE", "/api/v1/me", pair.Token, gin.H{"telegram": telegramSara})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "Sara's login does not confirm Ali")
+
+	w = adminRequest(router, "DELETE", "/api/v1/me", pair.Token, gin.H{"telegram": telegramAli})
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	user, err = users.GetByID(user.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, user.DeletionRequestedAt)
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+// AdminChapterHandler serves /api/v1/admin/chapters; routes must be behind
+// AuthMiddleware and RequireRole(admin)
+type AdminChapterHandler struct {
+	service services.ChapterAdminService
+}
+
+func NewAdminChapterHandler(service services.ChapterAdminService) *AdminChapterHandler {
+	return &AdminChapterHandler{service: service}
+}
+
+// ListChapters handles GET /admin/chapters
+func (h *AdminChapterHandler) ListChapters(c *gin.Context) {
+	chapters, err := h.service.ListChapters()
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch chapters")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  chapters,
+		"count": len(chapters),
+	})
+}
+
+// CreateChapter handles POST /admin/chapters
+func (h *AdminChapterHandler) CreateChapter(c *gin.Context) {
+	var input models.ChapterInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	chapter, err := h.service.CreateChapter(&input)
+	if err != nil {
+		respondServiceError(c, err, "Failed to create chapter")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": chapter})
+}
+
+// UpdateChapter handles PATCH /admin/chapters/:id, including featuring and
+// moving a single chapter
+func (h *AdminChapterHandler) UpdateChapter(c *gin.Context) {
+	id, ok := chapterIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input models.ChapterInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	chapter, err := h.service.UpdateChapter(id, &input)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update chapter")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": chapter})
+}
+
+// DeleteChapter handles DELETE /admin/chapters/:id
+func (h *AdminChapterHandler) DeleteChapter(c *gin.Context) {
+	id, ok := chapterIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteChapter(id); err != nil {
+		respondServiceError(c, err, "Failed to delete chapter")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Chapter deleted"})
+}
+
+// ReorderChapters handles PUT /admin/chapters/order with {"ids": [3, 1, 2]}
+func (h *AdminChapterHandler) ReorderChapters(c *gin.Context) {
+	var req models.ReorderChaptersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	if err := h.service.ReorderChapters(req.IDs); err != nil {
+		respondServiceError(c, err, "Failed to reorder chapters")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Chapters reordered"})
+}
+
+// ListTranslations handles GET /admin/chapters/:id/translations
+func (h *AdminChapterHandler) ListTranslations(c *gin.Context) {
+	id, ok := chapterIDParam(c)
+	if !ok {
+		return
+	}
+
+	translations, err := h.service.ListTranslations(id)
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch translations")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  translations,
+		"count": len(translations),
+	})
+}
+
+// SaveTranslation handles PUT /admin/chapters/:id/translations/:locale
+func (h *AdminChapterHandler) SaveTranslation(c *gin.Context) {
+	id, ok := chapterIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input models.TranslationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	translation, err := h.service.SaveTranslation(id, c.Param("locale"), &input)
+	if err != nil {
+		respondServiceError(c, err, "Failed to save translation")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": translation})
+}
+
+// DeleteTranslation handles DELETE /admin/chapters/:id/translations/:locale
+func (h *AdminChapterHandler) DeleteTranslation(c *gin.Context) {
+	id, ok := chapterIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTranslation(id, c.Param("locale")); err != nil {
+		respondServiceError(c, err, "Failed to delete translation")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Translation deleted"})
+}
+
+func chapterIDParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chapter ID"})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/middleware"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+	"github.com/whatisrealfreedom/freedom-website/internal/utils"
+)
+
+func setupAdminRouter(t *testing.T) (*gin.Engine, *sql.DB) {
+	gin.SetMode(gin.TestMode)
+	utils.InitJWT("test-secret")
+
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=1")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, repository.RunMigrations(db, repository.DialectSQLite, "../../migrations"))
+
+	users := repository.NewUserRepository(db)
+	roles := repository.NewRoleRepository(db)
+	handler := NewAdminChapterHandler(services.NewChapterAdminService(repository.NewChapterRepository(db), nil))
+	userHandler := NewAdminUserHandler(services.NewRoleService(roles, users))
+
+	router := gin.New()
+	admin := router.Group("/api/v1/admin")
+	admin.Use(middleware.AuthMiddleware(newSessionService(db)))
+	chapters := admin.Group("/chapters", middleware.RequirePermission(roles, models.PermissionManageChapters))
+	chapters.POST("", handler.CreateChapter)
+	chapters.PATCH("/:id", handler.UpdateChapter)
+	chapters.PUT("/:id/translations/:locale", handler.SaveTranslation)
+	admin.PUT("/users/:id/role", middleware.RequirePermission(roles, models.PermissionManageUsers), userHandler.SetRole)
+	return router, db
+}
+
+// newSessionService is what main passes to AuthMiddleware
+func newSessionService(db *sql.DB) services.SessionService {
+	return services.NewSessionService(repository.NewSessionRepository(db), repository.NewUserRepository(db), time.Hour, 24*time.Hour)
+}
+
+func adminToken(t *testing.T, db *sql.DB, email, role string) string {
+	_, token := createTestUser(t, db, email, role)
+	return token
+}
+
+func createTestUser(t *testing.T, db *sql.DB, email, role string) (int64, string) {
+	user := &models.User{Email: email, Password: "x", Role: role}
+	require.NoError(t, repository.NewUserRepository(db).Create(user))
+	token, err := utils.GenerateToken(user.ID, user.Email, user.Role, user.TokenVersion, time.Hour)
+	require.NoError(t, err)
+	return user.ID, token
+}
+
+func adminRequest(router *gin.Engine, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req, _ := http.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminChapterHandler_RequiresAdmin(t *testing.T) {
+	router, db := setupAdminRouter(t)
+	chapter := gin.H{"number": 99, "title": "T", "slug": "t", "description": "D"}
+
+	w := adminRequest(router, "POST", "/api/v1/admin/chapters", "", chapter)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	reader := adminToken(t, db, "reader@example.com", models.RoleReader)
+	w = adminRequest(router, "POST", "/api/v1/admin/chapters", reader, chapter)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// The role is read from the database, so a promotion applies to existing tokens
+	_, err := db.Exec(`UPDATE users SET role = 'admin' WHERE email = 'reader@example.com'`)
+	require.NoError(t, err)
+	w = adminRequest(router, "POST", "/api/v1/admin/chapters", reader, chapter)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestAdminChapterHandler_ChapterAndTranslation(t *testing.T) {
+	router, db := setupAdminRouter(t)
+	token := adminToken(t, db, "admin@example.com", models.RoleAdmin)
+
+	w := adminRequest(router, "POST", "/api/v1/admin/chapters", token,
+		gin.H{"number": 99, "title": "Draft", "slug": "draft", "description": "D"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.Chapter `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	path := "/api/v1/admin/chapters/" + strconv.Itoa(created.Data.ID)
+
+	w = adminRequest(router, "POST", "/api/v1/admin/chapters", token,
+		gin.H{"number": 100, "title": "Dup", "slug": "draft", "description": "D"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = adminRequest(router, "PATCH", path, token, gin.H{"featured": true})
+	require.Equal(t, http.StatusOK, w.Code)
+	var featured bool
+	require.NoError(t, db.QueryRow(`SELECT featured FROM chapters WHERE id = ?`, created.Data.ID).Scan(&featured))
+	assert.True(t, featured)
+
+	w = adminRequest(router, "PUT", path+"/translations/en", token,
+		gin.H{"title": "Draft", "slug": "Not A Slug", "description": "D"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = adminRequest(router, "PUT", path+"/translations/en", token,
+		gin.H{"title": "Draft", "slug": "draft-chapter", "description": "D", "content": "<p>Body</p>"})
+	require.Equal(t, http.StatusOK, w.Code)
+	var title string
+	require.NoError(t, db.QueryRow(`SELECT title FROM chapter_translations WHERE chapter_id = ? AND locale = 'en'`, created.Data.ID).Scan(&title))
+	assert.Equal(t, "Draft", title)
+
+	w = adminRequest(router, "PATCH", "/api/v1/admin/chapters/9999", token, gin.H{"title": "x"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAdminUserHandler_SetRole(t *testing.T) {
+	router, db := setupAdminRouter(t)
+	adminID, token := createTestUser(t, db, "admin@example.com", models.RoleAdmin)
+	userID, userToken := createTestUser(t, db, "user@example.com", models.RoleReader)
+	path := "/api/v1/admin/users/" + strconv.FormatInt(userID, 10) + "/role"
+
+	w := adminRequest(router, "PUT", path, userToken, gin.H{"role": "admin"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = adminRequest(router, "PUT", path, token, gin.H{"role": "superuser"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = adminRequest(router, "PUT", "/api/v1/admin/users/"+strconv.FormatInt(adminID, 10)+"/role", token, gin.H{"role": "reader"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = adminRequest(router, "PUT", path, token, gin.H{"role": "moderator"})
+	require.Equal(t, http.StatusOK, w.Code)
+	role, err := repository.NewUserRepository(db).GetRole(userID)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleModerator, role)
+
+	// Moderators may not manage chapters
+	w = adminRequest(router, "POST", "/api/v1/admin/chapters", userToken,
+		gin.H{"number": 99, "title": "T", "slug": "t", "description": "D"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+// AdminUserHandler serves /api/v1/admin roles and user management; routes must
+// be behind AuthMiddleware and RequirePermission(users.manage)
+type AdminUserHandler struct {
+	service services.RoleService
+}
+
+func NewAdminUserHandler(service services.RoleService) *AdminUserHandler {
+	return &AdminUserHandler{service: service}
+}
+
+// ListRoles handles GET /admin/roles
+func (h *AdminUserHandler) ListRoles(c *gin.Context) {
+	roles, err := h.service.ListRoles()
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch roles")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  roles,
+		"count": len(roles),
+	})
+}
+
+// SetRole handles PUT /admin/users/:id/role with {"role": "moderator"}
+func (h *AdminUserHandler) SetRole(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.SetRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	actorID, _ := c.Get("user_id")
+	user, err := h.service.SetUserRole(actorID.(int64), userID, req.Role)
+	if err != nil {
+		respondServiceError(c, err, "Failed to set user role")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
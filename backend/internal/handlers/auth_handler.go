@@ -1,51 +1,68 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/whatisrealfreedom/freedom-website/internal/models"
 	"github.com/whatisrealfreedom/freedom-website/internal/repository"
 	"github.com/whatisrealfreedom/freedom-website/internal/services"
-	"github.com/whatisrealfreedom/freedom-website/internal/utils"
 )
 
 type AuthHandler struct {
 	userRepo     repository.UserRepository
 	emailService *services.EmailService
-	config       interface {
-		GetJWTSecret() string
-		GetJWTExpiry() string
-	}
+	sessions     services.SessionService
+	guard        *services.AttemptGuard
+	referrals    services.ReferralService
+	twoFactor    services.TwoFactorService
+	reauth       services.ReauthService
 }
 
-func NewAuthHandler(userRepo repository.UserRepository, emailService *services.EmailService, jwtSecret, jwtExpiry string) *AuthHandler {
-	// Initialize JWT
-	utils.InitJWT(jwtSecret)
-
+func NewAuthHandler(userRepo repository.UserRepository, emailService *services.EmailService, sessions services.SessionService, guard *services.AttemptGuard, referrals services.ReferralService, twoFactor services.TwoFactorService, reauth services.ReauthService) *AuthHandler {
 	return &AuthHandler{
 		userRepo:     userRepo,
 		emailService: emailService,
-		config: &configWrapper{
-			jwtSecret: jwtSecret,
-			jwtExpiry: jwtExpiry,
-		},
+		sessions:     sessions,
+		guard:        guard,
+		referrals:    referrals,
+		twoFactor:    twoFactor,
+		reauth:       reauth,
 	}
 }
 
-type configWrapper struct {
-	jwtSecret string
-	jwtExpiry string
+func (h *AuthHandler) lockedOut(c *gin.Context, scope, email string) bool {
+	return lockedOut(c, h.guard, scope, email)
 }
 
-func (c *configWrapper) GetJWTSecret() string {
-	return c.jwtSecret
+// lockedOut answers 429 when too many attempts in scope failed for email or
+// from the client's address
+func lockedOut(c *gin.Context, guard *services.AttemptGuard, scope, email string) bool {
+	wait := guard.Check(scope, email, c.ClientIP())
+	if wait <= 0 {
+		return false
+	}
+
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many failed attempts. Please try again later.",
+		"retry_after": seconds,
+	})
+	return true
 }
 
-func (c *configWrapper) GetJWTExpiry() string {
-	return c.jwtExpiry
+// codeFailed records a wrong code guess; enough of them also burn the codes
+func (h *AuthHandler) codeFailed(c *gin.Context, scope, email, purpose string) {
+	h.guard.Fail(scope, email, c.ClientIP())
+	if err := h.userRepo.RecordCodeFailure(email, purpose); err != nil {
+		fmt.Printf("⚠️  Failed to record code failure: %v\n", err)
+	}
 }
 
 // Register handles user registration
@@ -63,11 +80,19 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// The inviter is credited once this user verifies their email
+	invitedBy, err := h.referrals.Inviter(req.ReferralCode)
+	if err != nil {
+		respondServiceError(c, err, "Failed to check referral code")
+		return
+	}
+
 	// Create user
 	user := &models.User{
-		Email:    req.Email,
-		Password: req.Password,
-		IsActive: false, // User is inactive until email is verified
+		Email:     req.Email,
+		Password:  req.Password,
+		IsActive:  false, // User is inactive until email is verified
+		InvitedBy: invitedBy,
 	}
 
 	if req.Name != "" {
@@ -83,17 +108,15 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	code := h.emailService.GenerateVerificationCode()
 
 	// Save verification code
-	if err := h.userRepo.CreateVerificationCode(user.ID, user.Email, code); err != nil {
+	if err := h.userRepo.CreateVerificationCode(user.ID, user.Email, code, models.CodePurposeVerifyEmail); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create verification code", "details": err.Error()})
 		return
 	}
 
-	// Send verification email
-	if err := h.emailService.SendVerificationEmail(user.Email, code); err != nil {
-		// Log error but don't fail registration
-		// In production, you might want to queue this for retry
+	// Queue verification email; the outbox worker retries while mail is down
+	if err := h.emailService.SendVerificationEmail(user.ID, user.Email, code); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "User created but failed to send verification email",
+			"error":   "User created but failed to queue verification email",
 			"details": err.Error(),
 		})
 		return
@@ -113,24 +136,20 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 		return
 	}
 
-	// Normalize code: remove spaces and ensure it's exactly 5 digits
-	code := strings.TrimSpace(req.Code)
-	// Remove any non-digit characters
-	code = strings.Map(func(r rune) rune {
-		if r >= '0' && r <= '9' {
-			return r
-		}
-		return -1
-	}, code)
-
+	code := normalizeCode(req.Code)
 	if len(code) != 5 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification code must be exactly 5 digits"})
 		return
 	}
 
+	if h.lockedOut(c, services.ScopeVerifyEmail, req.Email) {
+		return
+	}
+
 	// Get verification code
-	vc, err := h.userRepo.GetVerificationCode(req.Email, code)
+	vc, err := h.userRepo.GetVerificationCode(req.Email, code, models.CodePurposeVerifyEmail)
 	if err != nil {
+		h.codeFailed(c, services.ScopeVerifyEmail, req.Email, models.CodePurposeVerifyEmail)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification code", "details": err.Error()})
 		return
 	}
@@ -145,6 +164,7 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 	if err := h.userRepo.MarkVerificationCodeAsUsed(vc.ID); err != nil {
 		// Log error but don't fail verification
 	}
+	h.guard.Succeed(services.ScopeVerifyEmail, req.Email)
 
 	// Get user
 	user, err := h.userRepo.GetByID(vc.UserID)
@@ -153,9 +173,13 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	expiry, _ := time.ParseDuration(h.config.GetJWTExpiry())
-	token, err := utils.GenerateToken(user.ID, user.Email, expiry)
+	if err := h.referrals.CreditVerified(user); err != nil {
+		// Verification must not fail over points
+		fmt.Printf("⚠️  Failed to credit referral for user %d: %v\n", user.ID, err)
+	}
+
+	// Start a session: short-lived access token plus refresh token
+	tokens, err := h.sessions.Start(user, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token", "details": err.Error()})
 		return
@@ -165,8 +189,8 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 	user.Password = ""
 
 	c.JSON(http.StatusOK, models.AuthResponse{
-		Token: token,
-		User:  user,
+		TokenPair: *tokens,
+		User:      user,
 	})
 }
 
@@ -178,18 +202,25 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if h.lockedOut(c, services.ScopeLogin, req.Email) {
+		return
+	}
+
 	// Get user
 	user, err := h.userRepo.GetByEmail(req.Email)
 	if err != nil {
+		h.guard.Fail(services.ScopeLogin, req.Email, c.ClientIP())
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
 	// Check password
 	if !repository.CheckPassword(req.Password, user.Password) {
+		h.guard.Fail(services.ScopeLogin, req.Email, c.ClientIP())
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
+	h.guard.Succeed(services.ScopeLogin, req.Email)
 
 	// Check if email is verified
 	if user.EmailVerifiedAt == nil {
@@ -207,21 +238,9 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	expiry, _ := time.ParseDuration(h.config.GetJWTExpiry())
-	token, err := utils.GenerateToken(user.ID, user.Email, expiry)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token", "details": err.Error()})
-		return
-	}
-
-	// Clear password from response
-	user.Password = ""
-
-	c.JSON(http.StatusOK, models.AuthResponse{
-		Token: token,
-		User:  user,
-	})
+	// Start a session: short-lived access token plus refresh token, unless
+	// the user still owes a second factor
+	startSession(c, h.sessions, h.twoFactor, user, http.StatusOK)
 }
 
 // ResendVerificationCode handles resending verification code
@@ -251,15 +270,15 @@ func (h *AuthHandler) ResendVerificationCode(c *gin.Context) {
 	code := h.emailService.GenerateVerificationCode()
 
 	// Save verification code
-	if err := h.userRepo.CreateVerificationCode(user.ID, user.Email, code); err != nil {
+	if err := h.userRepo.CreateVerificationCode(user.ID, user.Email, code, models.CodePurposeVerifyEmail); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create verification code", "details": err.Error()})
 		return
 	}
 
-	// Send verification email
-	if err := h.emailService.SendVerificationEmail(user.Email, code); err != nil {
+	// Queue verification email
+	if err := h.emailService.SendVerificationEmail(user.ID, user.Email, code); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to send verification email",
+			"error":   "Failed to queue verification email",
 			"details": err.Error(),
 		})
 		return
@@ -270,6 +289,218 @@ func (h *AuthHandler) ResendVerificationCode(c *gin.Context) {
 	})
 }
 
+// ForgotPassword emails a single-use password reset code. It answers the same
+// whether or not the email belongs to an account.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	response := gin.H{"message": "If an account exists for this email, a password reset code has been sent."}
+
+	user, err := h.userRepo.GetByEmail(req.Email)
+	if err != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	code := h.emailService.GenerateVerificationCode()
+	if err := h.userRepo.CreateVerificationCode(user.ID, user.Email, code, models.CodePurposePasswordReset); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reset code", "details": err.Error()})
+		return
+	}
+
+	if err := h.emailService.SendPasswordResetEmail(user.ID, user.Email, code); err != nil {
+		// Answering with an error here would reveal that the account exists
+		fmt.Printf("⚠️  Failed to queue password reset email to user %d: %v\n", user.ID, err)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ResetPassword sets a new password with a reset code and signs the user out
+// everywhere by revoking all issued tokens
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	code := normalizeCode(req.Code)
+	if len(code) != 5 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reset code must be exactly 5 digits"})
+		return
+	}
+
+	if h.lockedOut(c, services.ScopeResetPassword, req.Email) {
+		return
+	}
+
+	vc, err := h.userRepo.GetVerificationCode(req.Email, code, models.CodePurposePasswordReset)
+	if err != nil {
+		h.codeFailed(c, services.ScopeResetPassword, req.Email, models.CodePurposePasswordReset)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset code"})
+		return
+	}
+
+	// Claim the code before changing anything so it cannot be used twice
+	if err := h.userRepo.MarkVerificationCodeAsUsed(vc.ID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset code"})
+		return
+	}
+
+	if err := h.userRepo.ResetPassword(vc.UserID, req.Password); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password", "details": err.Error()})
+		return
+	}
+	h.guard.Succeed(services.ScopeResetPassword, req.Email)
+
+	// The token_version bump already rejects access tokens; this ends the
+	// refresh tokens too
+	if _, err := h.sessions.RevokeAll(vc.UserID, ""); err != nil {
+		fmt.Printf("⚠️  Failed to revoke sessions of user %d: %v\n", vc.UserID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Your password has been reset. Please log in with your new password.",
+	})
+}
+
+// ChangeEmail handles POST /me/email: emails a code to the new address and
+// tells the current one. Nothing changes until ConfirmEmailChange; a new
+// request replaces one still in flight.
+func (h *AuthHandler) ChangeEmail(c *gin.Context) {
+	var req models.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	user, err := h.userRepo.GetByID(userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if h.lockedOut(c, services.ScopeChangeEmail, user.Email) {
+		return
+	}
+
+	if !reauthenticate(c, h.reauth, h.guard, user.ID, req.ReauthRequest) {
+		return
+	}
+
+	if req.Email == user.Email {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This is already your email"})
+		return
+	}
+	if models.IsPlaceholderEmail(req.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This email cannot be used"})
+		return
+	}
+	if existing, _ := h.userRepo.GetByEmail(req.Email); existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User with this email already exists"})
+		return
+	}
+
+	// Only the latest requested address can be confirmed
+	if err := h.userRepo.CancelVerificationCodes(user.ID, models.CodePurposeChangeEmail); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel earlier email change", "details": err.Error()})
+		return
+	}
+
+	code := h.emailService.GenerateVerificationCode()
+	if err := h.userRepo.CreateVerificationCode(user.ID, req.Email, code, models.CodePurposeChangeEmail); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create verification code", "details": err.Error()})
+		return
+	}
+
+	if err := h.emailService.SendEmailChangeEmail(user.ID, req.Email, code); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue verification email", "details": err.Error()})
+		return
+	}
+	// Accounts with a placeholder email have no old address to tell
+	if err := h.emailService.SendEmailChangeNotice(user.ID, user.Email, req.Email); err != nil {
+		fmt.Printf("⚠️  Failed to queue email change notice to user %d: %v\n", user.ID, err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Please check the new address for a verification code. Your email changes once you confirm it.",
+		"email":   req.Email,
+	})
+}
+
+// ConfirmEmailChange handles POST /me/email/verify: switches to the new
+// address with the code sent there and signs every other session out.
+func (h *AuthHandler) ConfirmEmailChange(c *gin.Context) {
+	var req models.ConfirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	code := normalizeCode(req.Code)
+	if len(code) != 5 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification code must be exactly 5 digits"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	user, err := h.userRepo.GetByID(userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if h.lockedOut(c, services.ScopeChangeEmail, user.Email) {
+		return
+	}
+
+	vc, err := h.userRepo.GetVerificationCode(req.Email, code, models.CodePurposeChangeEmail)
+	if err != nil || vc.UserID != user.ID {
+		h.guard.Fail(services.ScopeChangeEmail, user.Email, c.ClientIP())
+		if err := h.userRepo.RecordCodeFailure(req.Email, models.CodePurposeChangeEmail); err != nil {
+			fmt.Printf("⚠️  Failed to record code failure: %v\n", err)
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification code"})
+		return
+	}
+
+	// Claim the code before changing anything so it cannot be used twice
+	if err := h.userRepo.MarkVerificationCodeAsUsed(vc.ID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification code"})
+		return
+	}
+
+	// The address may have been taken since the code was sent
+	if err := h.userRepo.ChangeEmail(user.ID, vc.Email); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "User with this email already exists"})
+			return
+		}
+		respondServiceError(c, err, "Failed to change email")
+		return
+	}
+	h.guard.Succeed(services.ScopeChangeEmail, user.Email)
+	// Whoever else was signed in may be the reason for the change
+	if _, err := h.sessions.RevokeAll(user.ID, c.GetString("session_id")); err != nil {
+		fmt.Printf("⚠️  Failed to revoke sessions of user %d: %v\n", user.ID, err)
+	}
+
+	user, err = h.userRepo.GetByID(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user", "details": err.Error()})
+		return
+	}
+	user.Password = ""
+
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
+
 // GetMe returns the current authenticated user
 func (h *AuthHandler) GetMe(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
@@ -290,3 +521,95 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"data": user})
 }
+
+// Refresh exchanges a refresh token for a new token pair. Each refresh token
+// works once; reusing one signs its session out.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	tokens, err := h.sessions.Refresh(req.RefreshToken, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout ends the session of a refresh token. It succeeds for unknown tokens
+// so that logging out twice is harmless.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	if err := h.sessions.Logout(req.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// ListSessions returns the signed-in devices of the current user
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+
+	sessions, err := h.sessions.List(userID, c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get sessions", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": sessions})
+}
+
+// RevokeSession signs one device of the current user out
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+
+	if err := h.sessions.Revoke(userID, c.Param("id")); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// RevokeOtherSessions signs every device of the current user out except the
+// one making the request
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+
+	revoked, err := h.sessions.RevokeAll(userID, c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Other sessions revoked", "revoked": revoked})
+}
+
+// normalizeCode keeps only the digits of a code typed by the user
+func normalizeCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, strings.TrimSpace(code))
+}
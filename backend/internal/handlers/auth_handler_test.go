@@ -0,0 +1,430 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/config"
+	"github.com/whatisrealfreedom/freedom-website/internal/middleware"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+	"github.com/whatisrealfreedom/freedom-website/internal/utils"
+)
+
+func TestAuthHandler_PasswordReset(t *testing.T) {
+	_, db := setupAdminRouter(t)
+	users := repository.NewUserRepository(db)
+	sessions := newSessionService(db)
+	handler := NewAuthHandler(users, newEmailService(t, db), sessions, newAttemptGuard(db), newReferralService(db), newTwoFactorService(db), newReauthService(t, db))
+
+	router := gin.New()
+	auth := router.Group("/api/v1/auth")
+	auth.POST("/login", handler.Login)
+	auth.POST("/verify-email", handler.VerifyEmail)
+	auth.POST("/forgot-password", handler.ForgotPassword)
+	auth.POST("/reset-password", handler.ResetPassword)
+	router.GET("/api/v1/me", middleware.AuthMiddleware(sessions), handler.GetMe)
+
+	user := &models.User{Email: "reader@example.com", Password: "old-password"}
+	require.NoError(t, users.Create(user))
+	require.NoError(t, users.VerifyEmail(user.ID))
+
+	login := func(password string) (int, string) {
+		w := adminRequest(router, "POST", "/api/v1/auth/login", "", gin.H{"email": user.Email, "password": password})
+		var body models.AuthResponse
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body.Token
+	}
+	code, oldToken := login("old-password")
+	require.Equal(t, http.StatusOK, code)
+
+	unknown := adminRequest(router, "POST", "/api/v1/auth/forgot-password", "", gin.H{"email": "nobody@example.com"})
+	known := adminRequest(router, "POST", "/api/v1/auth/forgot-password", "", gin.H{"email": user.Email})
+	require.Equal(t, http.StatusOK, known.Code)
+	assert.Equal(t, unknown.Code, known.Code)
+	assert.Equal(t, unknown.Body.String(), known.Body.String())
+
+	var resetCode string
+	require.NoError(t, db.QueryRow(
+		`SELECT code FROM email_verification_codes WHERE user_id = ? AND purpose = ?`,
+		user.ID, models.CodePurposePasswordReset,
+	).Scan(&resetCode))
+
+	// A reset code is not a verification code
+	w := adminRequest(router, "POST", "/api/v1/auth/verify-email", "", gin.H{"email": user.Email, "code": resetCode})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	reset := gin.H{"email": user.Email, "code": resetCode, "password": "new-password"}
+	w = adminRequest(router, "POST", "/api/v1/auth/reset-password", "", reset)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = adminRequest(router, "POST", "/api/v1/auth/reset-password", "", reset)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "reset codes are single-use")
+
+	w = adminRequest(router, "GET", "/api/v1/me", oldToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "tokens issued before the reset are revoked")
+
+	code, _ = login("old-password")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, newToken := login("new-password")
+	require.Equal(t, http.StatusOK, code)
+	w = adminRequest(router, "GET", "/api/v1/me", newToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func newAttemptGuard(db *sql.DB) *services.AttemptGuard {
+	return services.NewAttemptGuard(services.NewMemoryAttemptStore(), repository.NewAuditRepository(db),
+		services.DefaultAccountPolicy, services.DefaultIPPolicy)
+}
+
+func newReferralService(db *sql.DB) services.ReferralService {
+	return services.NewReferralService(repository.NewUserRepository(db), repository.NewPointsRepository(db))
+}
+
+func newTwoFactorService(db *sql.DB) services.TwoFactorService {
+	return services.NewTwoFactorService(repository.NewTwoFactorRepository(db), "RealFreedom")
+}
+
+func newEmailService(t *testing.T, db *sql.DB) *services.EmailService {
+	emails, err := services.NewEmailService(&config.Config{LocaleFallback: "fa,en"}, repository.NewOutboxRepository(db), nil)
+	require.NoError(t, err)
+	return emails
+}
+
+func newReauthService(t *testing.T, db *sql.DB) services.ReauthService {
+	return services.NewReauthService(repository.NewUserRepository(db), newTwoFactorService(db), newEmailService(t, db), nil)
+}
+
+func TestAuthHandler_RefreshAndSessions(t *testing.T) {
+	_, db := setupAdminRouter(t)
+	users := repository.NewUserRepository(db)
+	sessions := newSessionService(db)
+	handler := NewAuthHandler(users, newEmailService(t, db), sessions, newAttemptGuard(db), newReferralService(db), newTwoFactorService(db), newReauthService(t, db))
+
+	router := gin.New()
+	auth := router.Group("/api/v1/auth")
+	auth.POST("/login", handler.Login)
+	auth.POST("/refresh", handler.Refresh)
+	auth.POST("/logout", handler.Logout)
+	me := router.Group("/api/v1/me", middleware.AuthMiddleware(sessions))
+	me.GET("", handler.GetMe)
+	me.GET("/sessions", handler.ListSessions)
+	me.DELETE("/sessions", handler.RevokeOtherSessions)
+	me.DELETE("/sessions/:id", handler.RevokeSession)
+
+	user := &models.User{Email: "reader@example.com", Password: "password"}
+	require.NoError(t, users.Create(user))
+	require.NoError(t, users.VerifyEmail(user.ID))
+
+	login := func() models.AuthResponse {
+		w := adminRequest(router, "POST", "/api/v1/auth/login", "", gin.H{"email": user.Email, "password": "password"})
+		require.Equal(t, http.StatusOK, w.Code)
+		var body models.AuthResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		require.NotEmpty(t, body.RefreshToken)
+		return body
+	}
+	refresh := func(token string) (int, models.TokenPair) {
+		w := adminRequest(router, "POST", "/api/v1/auth/refresh", "", gin.H{"refresh_token": token})
+		var body models.TokenPair
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body
+	}
+	listSessions := func(token string) []models.Session {
+		w := adminRequest(router, "GET", "/api/v1/me/sessions", token, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var body struct {
+			Data []models.Session `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body.Data
+	}
+
+	t.Run("refresh tokens rotate and reuse revokes the family", func(t *testing.T) {
+		first := login()
+		code, second := refresh(first.RefreshToken)
+		require.Equal(t, http.StatusOK, code)
+		assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+		assert.Equal(t, http.StatusOK, adminRequest(router, "GET", "/api/v1/me", second.Token, nil).Code)
+
+		// Replaying the first token looks like theft: the whole session ends
+		code, _ = refresh(first.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, code)
+		code, _ = refresh(second.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, code, "tokens rotated from the reused one are revoked too")
+		assert.Equal(t, http.StatusUnauthorized, adminRequest(router, "GET", "/api/v1/me", second.Token, nil).Code)
+	})
+
+	t.Run("logout revokes the access token", func(t *testing.T) {
+		session := login()
+		w := adminRequest(router, "POST", "/api/v1/auth/logout", "", gin.H{"refresh_token": session.RefreshToken})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusUnauthorized, adminRequest(router, "GET", "/api/v1/me", session.Token, nil).Code)
+
+		w = adminRequest(router, "POST", "/api/v1/auth/logout", "", gin.H{"refresh_token": session.RefreshToken})
+		assert.Equal(t, http.StatusOK, w.Code, "logging out twice is harmless")
+		code, _ := refresh(session.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+
+	t.Run("sessions are listed and revoked per device", func(t *testing.T) {
+		phone, laptop, tablet := login(), login(), login()
+
+		listed := listSessions(laptop.Token)
+		require.Len(t, listed, 3)
+		current := 0
+		for _, s := range listed {
+			if s.Current {
+				current++
+			}
+		}
+		assert.Equal(t, 1, current)
+
+		phoneClaims, err := utils.ValidateToken(phone.Token)
+		require.NoError(t, err)
+		phoneSession := "/api/v1/me/sessions/" + phoneClaims.SessionID
+
+		_, otherToken := createTestUser(t, db, "other@example.com", "")
+		w := adminRequest(router, "DELETE", phoneSession, otherToken, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, "other users' sessions are not found")
+
+		w = adminRequest(router, "DELETE", phoneSession, laptop.Token, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, listSessions(laptop.Token), 2)
+		assert.Equal(t, http.StatusUnauthorized, adminRequest(router, "GET", "/api/v1/me", phone.Token, nil).Code)
+
+		w = adminRequest(router, "DELETE", "/api/v1/me/sessions", laptop.Token, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		remaining := listSessions(laptop.Token)
+		require.Len(t, remaining, 1)
+		assert.True(t, remaining[0].Current)
+
+		code, _ := refresh(tablet.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+}
+
+func TestAuthHandler_BruteForceProtection(t *testing.T) {
+	_, db := setupAdminRouter(t)
+	users := repository.NewUserRepository(db)
+	audit := repository.NewAuditRepository(db)
+	lenient := services.LockoutPolicy{MaxFailures: 100, Window: time.Hour, BaseLockout: time.Minute, MaxLockout: time.Hour, Memory: time.Hour}
+	newRouter := func(account services.LockoutPolicy) *gin.Engine {
+		guard := services.NewAttemptGuard(services.NewMemoryAttemptStore(), audit, account, lenient)
+		handler := NewAuthHandler(users, newEmailService(t, db), newSessionService(db), guard, newReferralService(db), newTwoFactorService(db), newReauthService(t, db))
+		router := gin.New()
+		router.POST("/api/v1/auth/login", handler.Login)
+		router.POST("/api/v1/auth/verify-email", handler.VerifyEmail)
+		return router
+	}
+
+	user := &models.User{Email: "reader@example.com", Password: "password"}
+	require.NoError(t, users.Create(user))
+
+	t.Run("codes stop working after too many wrong guesses", func(t *testing.T) {
+		router := newRouter(lenient)
+		require.NoError(t, users.CreateVerificationCode(user.ID, user.Email, "12345", models.CodePurposeVerifyEmail))
+		for i := 0; i < models.MaxCodeAttempts; i++ {
+			w := adminRequest(router, "POST", "/api/v1/auth/verify-email", "", gin.H{"email": user.Email, "code": "99999"})
+			require.Equal(t, http.StatusBadRequest, w.Code)
+		}
+		w := adminRequest(router, "POST", "/api/v1/auth/verify-email", "", gin.H{"email": user.Email, "code": "12345"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// A new code starts from zero
+		require.NoError(t, users.CreateVerificationCode(user.ID, user.Email, "54321", models.CodePurposeVerifyEmail))
+		w = adminRequest(router, "POST", "/api/v1/auth/verify-email", "", gin.H{"email": user.Email, "code": "54321"})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("repeated failed logins lock the account", func(t *testing.T) {
+		router := newRouter(services.DefaultAccountPolicy)
+		login := func(password string) *httptest.ResponseRecorder {
+			return adminRequest(router, "POST", "/api/v1/auth/login", "", gin.H{"email": user.Email, "password": password})
+		}
+		for i := 0; i < services.DefaultAccountPolicy.MaxFailures; i++ {
+			require.Equal(t, http.StatusUnauthorized, login("wrong").Code)
+		}
+
+		w := login("password")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, "even the right password waits out the lockout")
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+		entries, err := audit.List(models.AuditLockout, 10)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "login:account:"+user.Email, entries[0].Subject)
+	})
+}
+
+func TestAuthHandler_RegisterQueuesVerificationEmail(t *testing.T) {
+	_, db := setupAdminRouter(t)
+	outbox := repository.NewOutboxRepository(db)
+	handler := NewAuthHandler(repository.NewUserRepository(db), newEmailService(t, db), newSessionService(db), newAttemptGuard(db), newReferralService(db), newTwoFactorService(db), newReauthService(t, db))
+	router := gin.New()
+	router.POST("/api/v1/auth/register", handler.Register)
+
+	// No mail server is configured; registration must not depend on one
+	w := adminRequest(router, "POST", "/api/v1/auth/register", "", gin.H{"email": "new@example.com", "password": "password"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	queued, err := outbox.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", queued.Recipient)
+	assert.Equal(t, models.OutboxPending, queued.Status)
+	assert.Contains(t, queued.HTMLBody, `dir="rtl"`, "new readers get the default Persian email")
+	assert.NotEmpty(t, queued.TextBody)
+}
+
+func TestAuthHandler_ChangeEmail(t *testing.T) {
+	_, db := setupAdminRouter(t)
+	users := repository.NewUserRepository(db)
+	outbox := repository.NewOutboxRepository(db)
+	sessions := newSessionService(db)
+	reauth := newReauthService(t, db)
+	handler := NewAuthHandler(users, newEmailService(t, db), sessions, newAttemptGuard(db), newReferralService(db), newTwoFactorService(db), reauth)
+
+	router := gin.New()
+	protected := router.Group("/api/v1", middleware.AuthMiddleware(sessions))
+	protected.GET("/me", handler.GetMe)
+	protected.POST("/me/email", handler.ChangeEmail)
+	protected.POST("/me/email/verify", handler.ConfirmEmailChange)
+
+	userID, token := createTestUser(t, db, "reader@example.com", models.RoleReader)
+	createTestUser(t, db, "taken@example.com", models.RoleReader)
+
+	pendingCode := func(email string) string {
+		var code string
+		require.NoError(t, db.QueryRow(
+			`SELECT code FROM email_verification_codes WHERE email = ? AND purpose = ? AND used = FALSE`,
+			email, models.CodePurposeChangeEmail,
+		).Scan(&code))
+		return code
+	}
+	request := func(email string) int {
+		w := adminRequest(router, "POST", "/api/v1/me/email", token, gin.H{"email": email, "password": "x"})
+		return w.Code
+	}
+	confirm := func(email, code string) int {
+		w := adminRequest(router, "POST", "/api/v1/me/email/verify", token, gin.H{"email": email, "code": code})
+		return w.Code
+	}
+
+	t.Run("rejects bad requests", func(t *testing.T) {
+		w := adminRequest(router, "POST", "/api/v1/me/email", token, gin.H{"email": "new@example.com", "password": "wrong"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, http.StatusBadRequest, request("reader@example.com"))
+		assert.Equal(t, http.StatusBadRequest, request("x@deleted.invalid"))
+		assert.Equal(t, http.StatusConflict, request("taken@example.com"))
+	})
+
+	t.Run("only the latest request can be confirmed", func(t *testing.T) {
+		require.Equal(t, http.StatusAccepted, request("first@example.com"))
+		first := pendingCode("first@example.com")
+		require.Equal(t, http.StatusAccepted, request("second@example.com"))
+
+		assert.Equal(t, http.StatusBadRequest, confirm("first@example.com", first))
+
+		code, notice := outboxTo(t, outbox, "second@example.com"), outboxTo(t, outbox, "reader@example.com")
+		assert.Contains(t, code.TextBody, pendingCode("second@example.com"))
+		assert.Contains(t, notice.TextBody, "second@example.com")
+		assert.NotContains(t, notice.TextBody, pendingCode("second@example.com"), "the old address never sees the code")
+	})
+
+	t.Run("the email changes once the code is confirmed", func(t *testing.T) {
+		require.NoError(t, users.CreateVerificationCode(userID, "reader@example.com", "24680", models.CodePurposePasswordReset))
+		code := pendingCode("second@example.com")
+		user, err := users.GetByID(userID)
+		require.NoError(t, err)
+		current, err := sessions.Start(user, "test", "127.0.0.1")
+		require.NoError(t, err)
+		other, err := sessions.Start(user, "test", "127.0.0.1")
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, confirm("second@example.com", "00000"))
+		user, err = users.GetByID(userID)
+		require.NoError(t, err)
+		assert.Equal(t, "reader@example.com", user.Email, "nothing changes before confirmation")
+
+		w := adminRequest(router, "POST", "/api/v1/me/email/verify", current.Token, gin.H{"email": "second@example.com", "code": code})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct {
+			Data models.User `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "second@example.com", resp.Data.Email)
+		assert.NotNil(t, resp.Data.EmailVerifiedAt)
+
+		assert.Equal(t, http.StatusBadRequest, confirm("second@example.com", code), "codes are single-use")
+		_, err = users.GetVerificationCode("reader@example.com", "24680", models.CodePurposePasswordReset)
+		assert.Error(t, err, "codes sent to the old address stop working")
+		_, err = users.GetByEmail("reader@example.com")
+		assert.Error(t, err)
+
+		// Every other session is signed out; the one that made the change stays
+		w = adminRequest(router, "GET", "/api/v1/me", other.Token, nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		_, err = sessions.Refresh(other.RefreshToken, "test", "127.0.0.1")
+		assert.Error(t, err)
+		w = adminRequest(router, "GET", "/api/v1/me", current.Token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("an address taken in the meantime conflicts", func(t *testing.T) {
+		require.Equal(t, http.StatusAccepted, request("third@example.com"))
+		code := pendingCode("third@example.com")
+		require.NoError(t, users.Create(&models.User{Email: "third@example.com", Password: "password"}))
+
+		assert.Equal(t, http.StatusConflict, confirm("third@example.com", code))
+		user, err := users.GetByID(userID)
+		require.NoError(t, err)
+		assert.Equal(t, "second@example.com", user.Email)
+	})
+
+	t.Run("accounts without a password confirm with a code sent to the old address", func(t *testing.T) {
+		oauthID, oauthToken := createTestUser(t, db, "oauth@example.com", models.RoleReader)
+		_, err := db.Exec(`UPDATE users SET registration_src = ?, password_set = FALSE WHERE id = ?`, models.ProviderGitHub, oauthID)
+		require.NoError(t, err)
+
+		// Being signed in is not enough, and neither is the random password
+		w := adminRequest(router, "POST", "/api/v1/me/email", oauthToken, gin.H{"email": "fourth@example.com"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = adminRequest(router, "POST", "/api/v1/me/email", oauthToken, gin.H{"email": "fourth@example.com", "password": "x"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		require.NoError(t, reauth.SendCode(oauthID))
+		var code string
+		require.NoError(t, db.QueryRow(
+			`SELECT code FROM email_verification_codes WHERE user_id = ? AND purpose = ?`, oauthID, models.CodePurposeReauth,
+		).Scan(&code))
+		assert.Contains(t, outboxTo(t, outbox, "oauth@example.com").TextBody, code)
+
+		w = adminRequest(router, "POST", "/api/v1/me/email", oauthToken, gin.H{"email": "fourth@example.com", "code": code})
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		assert.Contains(t, outboxTo(t, outbox, "fourth@example.com").TextBody, pendingCode("fourth@example.com"))
+	})
+}
+
+// outboxTo returns the latest email queued for recipient
+func outboxTo(t *testing.T, outbox repository.OutboxRepository, recipient string) *models.OutboxEmail {
+	var found *models.OutboxEmail
+	for id := int64(1); ; id++ {
+		email, err := outbox.Get(id)
+		if err != nil {
+			break
+		}
+		if email.Recipient == recipient {
+			found = email
+		}
+	}
+	require.NotNil(t, found, "no email to %s", recipient)
+	return found
+}
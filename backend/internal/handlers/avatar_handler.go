@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+// AvatarHandler uploads and serves avatars
+type AvatarHandler struct {
+	service services.AvatarService
+}
+
+func NewAvatarHandler(service services.AvatarService) *AvatarHandler {
+	return &AvatarHandler{service: service}
+}
+
+// Upload handles POST /me/avatar with the image in the multipart field
+// "avatar"; must be behind AuthMiddleware
+func (h *AvatarHandler) Upload(c *gin.Context) {
+	// Room for the multipart framing around the largest accepted image
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxAvatarBytes+64<<10)
+
+	header, err := c.FormFile("avatar")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image is too large", "details": "the image must be at most 5 MB"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": "send the image in the multipart field \"avatar\""})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	userID, _ := c.Get("user_id")
+	avatar, err := h.service.Upload(userID.(int64), file)
+	if err != nil {
+		respondServiceError(c, err, "Failed to save avatar")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": avatar})
+}
+
+// Serve handles GET /avatars/:user/:file. Avatar files are named by content,
+// so clients may cache them forever.
+func (h *AvatarHandler) Serve(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("user"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Avatar not found"})
+		return
+	}
+
+	name := c.Param("file")
+	file, err := h.service.Open(userID, name)
+	if errors.Is(err, services.ErrStorageNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Avatar not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch avatar", "details": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	contentType := "image/jpeg"
+	if path.Ext(name) == ".png" {
+		contentType = "image/png"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("ETag", `"`+name+`"`)
+	c.Header("X-Content-Type-Options", "nosniff")
+	http.ServeContent(c.Writer, c.Request, name, file.ModTime, file)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/middleware"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+func avatarUpload(router *gin.Engine, token, field string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	// The client's file name and content type are not trusted
+	part, _ := form.CreateFormFile(field, "photo.gif")
+	part.Write(data)
+	form.Close()
+
+	req, _ := http.NewRequest("POST", "/api/v1/me/avatar", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAvatarHandler(t *testing.T) {
+	_, db := setupAdminRouter(t)
+	handler := NewAvatarHandler(services.NewAvatarService(repository.NewUserRepository(db), services.NewLocalStorage(t.TempDir())))
+
+	router := gin.New()
+	router.GET("/api/v1/avatars/:user/:file", handler.Serve)
+	protected := router.Group("/api/v1", middleware.AuthMiddleware(newSessionService(db)))
+	protected.POST("/me/avatar", handler.Upload)
+
+	_, token := createTestUser(t, db, "reader@example.com", models.RoleReader)
+
+	var img bytes.Buffer
+	require.NoError(t, png.Encode(&img, image.NewGray(image.Rect(0, 0, 120, 90))))
+
+	w := avatarUpload(router, token, "avatar", img.Bytes())
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Data models.Avatar `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotEmpty(t, resp.Data.PhotoURL)
+
+	w = adminRequest(router, "GET", resp.Data.PhotoURL, "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Cache-Control"), "immutable")
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	req, _ := http.NewRequest("GET", resp.Data.PhotoURL, nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = avatarUpload(router, token, "avatar", []byte("GIF89a but not really"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = avatarUpload(router, token, "photo", img.Bytes())
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	for _, path := range []string{"/api/v1/avatars/1/..%2F..%2Fsecret", "/api/v1/avatars/1/abc.jpg", "/api/v1/avatars/x/abc.jpg"} {
+		w = adminRequest(router, "GET", path, "", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+}
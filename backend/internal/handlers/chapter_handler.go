@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -10,14 +12,23 @@ import (
 
 type ChapterHandler struct {
 	service services.ChapterService
+	locales services.LocaleService
 }
 
-func NewChapterHandler(service services.ChapterService) *ChapterHandler {
-	return &ChapterHandler{service: service}
+func NewChapterHandler(service services.ChapterService, locales services.LocaleService) *ChapterHandler {
+	return &ChapterHandler{service: service, locales: locales}
+}
+
+// localePreferences negotiates from ?locale, the signed-in user's language
+// (set by OptionalAuthMiddleware) and Accept-Language
+func (h *ChapterHandler) localePreferences(c *gin.Context) []string {
+	userID, _ := c.Get("user_id")
+	id, _ := userID.(int64)
+	return h.locales.Preferences(c.Query("locale"), c.GetHeader("Accept-Language"), id)
 }
 
 func (h *ChapterHandler) GetAll(c *gin.Context) {
-	chapters, err := h.service.GetAllChapters()
+	chapters, err := h.service.GetAllChapters(h.localePreferences(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to fetch chapters",
@@ -26,6 +37,8 @@ func (h *ChapterHandler) GetAll(c *gin.Context) {
 		return
 	}
 
+	// Each chapter carries the locale it is served in; fallbacks can mix them
+	c.Header("Vary", "Accept-Language, Authorization")
 	c.JSON(http.StatusOK, gin.H{
 		"data":  chapters,
 		"count": len(chapters),
@@ -42,7 +55,7 @@ func (h *ChapterHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	chapter, err := h.service.GetChapterByID(id)
+	chapter, err := h.service.GetChapterByID(id, h.localePreferences(c))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Chapter not found",
@@ -50,14 +63,47 @@ func (h *ChapterHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	// Get locale from query parameter (default to 'fa')
-	locale := c.DefaultQuery("locale", "fa")
-	if locale == "en" && chapter.ContentEn != "" {
-		// Return English content if available
-		chapter.Content = chapter.ContentEn
+	c.Header("Vary", "Accept-Language, Authorization")
+	c.Header("Content-Language", chapter.Locale)
+	c.JSON(http.StatusOK, gin.H{
+		"data":              chapter,
+		"locale":            chapter.Locale,
+		"available_locales": chapter.AvailableLocales,
+	})
+}
+
+// GetByLocaleSlug handles GET /chapters/by-slug/:locale/:slug. Former slugs
+// answer with a permanent redirect to the current URL.
+func (h *ChapterHandler) GetByLocaleSlug(c *gin.Context) {
+	locale := services.NormalizeLocale(c.Param("locale"))
+	if locale == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid locale",
+		})
+		return
+	}
+
+	chapter, err := h.service.GetChapterByLocaleSlug(locale, c.Param("slug"))
+	var moved *services.SlugMovedError
+	if errors.As(err, &moved) {
+		location := "/api/v1/chapters/by-slug/" + url.PathEscape(moved.Locale) + "/" + url.PathEscape(moved.Slug)
+		if c.Request.URL.RawQuery != "" {
+			location += "?" + c.Request.URL.RawQuery
+		}
+		c.Redirect(http.StatusMovedPermanently, location)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Chapter not found",
+		})
+		return
 	}
 
+	c.Header("Content-Language", chapter.Locale)
 	c.JSON(http.StatusOK, gin.H{
-		"data": chapter,
+		"data":              chapter,
+		"locale":            chapter.Locale,
+		"available_locales": chapter.AvailableLocales,
 	})
 }
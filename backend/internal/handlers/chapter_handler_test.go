@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+func setupChapterRouter(t *testing.T) (*gin.Engine, *sql.DB) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=1")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, repository.RunMigrations(db, repository.DialectSQLite, "../../migrations"))
+
+	locales := services.NewLocaleService(nil, "fa,en")
+	handler := NewChapterHandler(services.NewChapterService(repository.NewChapterRepository(db), locales.Default()), locales)
+
+	router := gin.New()
+	chapters := router.Group("/api/v1/chapters")
+	chapters.GET("", handler.GetAll)
+	chapters.GET("/:id", handler.GetByID)
+	chapters.GET("/by-slug/:locale/:slug", handler.GetByLocaleSlug)
+	return router, db
+}
+
+func TestChapterHandler_GetByID_Locale(t *testing.T) {
+	router, db := setupChapterRouter(t)
+	_, err := db.Exec(`UPDATE chapter_translations SET title = 'Freedom' WHERE chapter_id = 1 AND locale = 'en'`)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", "/api/v1/chapters/1", nil)
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data struct {
+			Title string `json:"title"`
+		} `json:"data"`
+		Locale           string   `json:"locale"`
+		AvailableLocales []string `json:"available_locales"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "en", body.Locale)
+	assert.Equal(t, "Freedom", body.Data.Title)
+	assert.ElementsMatch(t, []string{"en", "fa"}, body.AvailableLocales)
+
+	// ?locale wins over Accept-Language
+	req, _ = http.NewRequest("GET", "/api/v1/chapters/1?locale=fa", nil)
+	req.Header.Set("Accept-Language", "en")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "fa", body.Locale)
+}
+
+func TestChapterHandler_GetAll_Fallback(t *testing.T) {
+	router, db := setupChapterRouter(t)
+	_, err := db.Exec(`DELETE FROM chapter_translations WHERE chapter_id = 1 AND locale = 'en'`)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", "/api/v1/chapters?locale=en", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var body map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.NotContains(t, body, "locale", "the chapters need not share one locale")
+
+	var chapters []struct {
+		ID     int    `json:"id"`
+		Locale string `json:"locale"`
+	}
+	require.NoError(t, json.Unmarshal(body["data"], &chapters))
+	served := map[int]string{}
+	for _, chapter := range chapters {
+		served[chapter.ID] = chapter.Locale
+	}
+	assert.Equal(t, "fa", served[1], "a missing translation falls back")
+	assert.Equal(t, "en", served[2])
+}
+
+func TestChapterHandler_GetByLocaleSlug_RedirectsOldSlug(t *testing.T) {
+	router, db := setupChapterRouter(t)
+
+	var oldSlug string
+	require.NoError(t, db.QueryRow(`SELECT slug FROM chapter_translations WHERE chapter_id = 1 AND locale = 'en'`).Scan(&oldSlug))
+	_, err := db.Exec(`UPDATE chapter_translations SET slug = 'freedom-as-property' WHERE chapter_id = 1 AND locale = 'en'`)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", "/api/v1/chapters/by-slug/en/freedom-as-property", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/v1/chapters/by-slug/en/"+oldSlug+"?x=1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/api/v1/chapters/by-slug/en/freedom-as-property?x=1", w.Header().Get("Location"))
+
+	// The old slug is free again once another translation takes it
+	_, err = db.Exec(`UPDATE chapter_translations SET slug = ? WHERE chapter_id = 2 AND locale = 'en'`, oldSlug)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/v1/chapters/by-slug/en/no-such-chapter", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
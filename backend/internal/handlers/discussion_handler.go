@@ -30,6 +30,61 @@ func NewDiscussionHandler(
 	}
 }
 
+// ErrCodeThreadLocked is the "code" of responses rejected because the thread is locked
+const ErrCodeThreadLocked = "thread_locked"
+
+// ErrCodeThreadDeleted is the "code" of responses rejected because the
+// thread's author deleted it
+const ErrCodeThreadDeleted = "thread_deleted"
+
+// openThread loads a thread that is about to receive a comment, vote or
+// reaction. It responds 404 for missing threads and 423 for locked ones and
+// "[deleted]" tombstones, and returns nil, in which case the handler must stop.
+func (h *DiscussionHandler) openThread(c *gin.Context, threadID int64) *models.Thread {
+	thread, err := h.threadRepo.GetByID(threadID, nil)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Thread not found",
+		})
+		return nil
+	}
+	
+	if thread.IsDeleted {
+		c.JSON(http.StatusLocked, gin.H{
+			"error": "Thread was deleted by its author",
+			"code":  ErrCodeThreadDeleted,
+		})
+		return nil
+	}
+	
+	if thread.IsLocked {
+		c.JSON(http.StatusLocked, gin.H{
+			"error": "Thread is locked",
+			"code":  ErrCodeThreadLocked,
+		})
+		return nil
+	}
+	
+	return thread
+}
+
+// openComment is openThread for the thread of a comment; deleted comments are 404
+func (h *DiscussionHandler) openComment(c *gin.Context, commentID int64) *models.Comment {
+	comment, err := h.commentRepo.GetByID(commentID, nil)
+	if err != nil || comment.IsDeleted {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Comment not found",
+		})
+		return nil
+	}
+	
+	if h.openThread(c, comment.ThreadID) == nil {
+		return nil
+	}
+	
+	return comment
+}
+
 // GetThreads returns a list of threads with pagination
 func (h *DiscussionHandler) GetThreads(c *gin.Context) {
 	sortBy := c.DefaultQuery("sort", "newest")
@@ -60,6 +115,10 @@ func (h *DiscussionHandler) GetThreads(c *gin.Context) {
 		return
 	}
 	
+	for _, thread := range threads {
+		thread.Redact()
+	}
+	
 	c.JSON(http.StatusOK, models.ThreadListResponse{
 		Threads: threads,
 		Total:   total,
@@ -108,6 +167,7 @@ func (h *DiscussionHandler) GetThread(c *gin.Context) {
 		return
 	}
 	
+	thread.Redact()
 	c.JSON(http.StatusOK, models.ThreadDetailResponse{
 		Thread:   thread,
 		Comments: comments,
@@ -207,6 +267,52 @@ func (h *DiscussionHandler) UpdateThread(c *gin.Context) {
 	c.JSON(http.StatusOK, updatedThread)
 }
 
+// DeleteThread lets the author delete their thread. A thread with comments
+// stays visible as a "[deleted]" tombstone.
+func (h *DiscussionHandler) DeleteThread(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+	
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid thread ID",
+		})
+		return
+	}
+	
+	thread, err := h.threadRepo.GetByID(id, nil)
+	if err != nil || thread.IsDeleted {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Thread not found",
+		})
+		return
+	}
+	
+	if thread.UserID != userID.(int64) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "You can only delete your own thread",
+		})
+		return
+	}
+	
+	if err := h.threadRepo.Delete(id, userID.(int64)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete thread",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Thread deleted"})
+}
+
 // CreateComment creates a new comment
 func (h *DiscussionHandler) CreateComment(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -226,6 +332,10 @@ func (h *DiscussionHandler) CreateComment(c *gin.Context) {
 		return
 	}
 	
+	if h.openThread(c, threadID) == nil {
+		return
+	}
+	
 	var req models.CreateCommentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -235,6 +345,17 @@ func (h *DiscussionHandler) CreateComment(c *gin.Context) {
 		return
 	}
 	
+	// Replies must stay within the thread and cannot start under a deleted comment
+	if req.ParentID != nil {
+		parent, err := h.commentRepo.GetByID(*req.ParentID, nil)
+		if err != nil || parent.ThreadID != threadID || parent.IsDeleted {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid parent comment",
+			})
+			return
+		}
+	}
+	
 	comment := &models.Comment{
 		ThreadID: threadID,
 		UserID:   userID.(int64),
@@ -320,6 +441,52 @@ func (h *DiscussionHandler) UpdateComment(c *gin.Context) {
 	c.JSON(http.StatusOK, updatedComment)
 }
 
+// DeleteComment lets the author delete their comment. Replies keep it in the
+// thread as a "[deleted]" tombstone.
+func (h *DiscussionHandler) DeleteComment(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+	
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid comment ID",
+		})
+		return
+	}
+	
+	comment, err := h.commentRepo.GetByID(id, nil)
+	if err != nil || comment.IsDeleted {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Comment not found",
+		})
+		return
+	}
+	
+	if comment.UserID != userID.(int64) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "You can only delete your own comment",
+		})
+		return
+	}
+	
+	if err := h.commentRepo.Delete(id, userID.(int64)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete comment",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
+
 // VoteThread votes on a thread
 func (h *DiscussionHandler) VoteThread(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -339,12 +506,9 @@ func (h *DiscussionHandler) VoteThread(c *gin.Context) {
 		return
 	}
 	
-	// Check if user owns the thread
-	thread, err := h.threadRepo.GetByID(id, nil)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Thread not found",
-		})
+	// Check the thread is open and the user does not own it
+	thread := h.openThread(c, id)
+	if thread == nil {
 		return
 	}
 	
@@ -415,12 +579,9 @@ func (h *DiscussionHandler) VoteComment(c *gin.Context) {
 		return
 	}
 	
-	// Check if user owns the comment
-	comment, err := h.commentRepo.GetByID(id, nil)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Comment not found",
-		})
+	// Check the comment's thread is open and the user does not own the comment
+	comment := h.openComment(c, id)
+	if comment == nil {
 		return
 	}
 	
@@ -491,12 +652,9 @@ func (h *DiscussionHandler) ReactThread(c *gin.Context) {
 		return
 	}
 	
-	// Check if user owns the thread
-	thread, err := h.threadRepo.GetByID(id, nil)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Thread not found",
-		})
+	// Check the thread is open and the user does not own it
+	thread := h.openThread(c, id)
+	if thread == nil {
 		return
 	}
 	
@@ -552,12 +710,9 @@ func (h *DiscussionHandler) ReactComment(c *gin.Context) {
 		return
 	}
 	
-	// Check if user owns the comment
-	comment, err := h.commentRepo.GetByID(id, nil)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Comment not found",
-		})
+	// Check the comment's thread is open and the user does not own the comment
+	comment := h.openComment(c, id)
+	if comment == nil {
 		return
 	}
 	
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/middleware"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+func setupDiscussionRouter(t *testing.T) (*gin.Engine, *sql.DB) {
+	_, db := setupAdminRouter(t)
+
+	handler := NewDiscussionHandler(
+		repository.NewThreadRepository(db), repository.NewCommentRepository(db),
+		repository.NewVoteRepository(db), repository.NewReactionRepository(db),
+	)
+
+	router := gin.New()
+	discussions := router.Group("/api/v1/discussions")
+	discussions.GET("", handler.GetThreads)
+	discussions.GET("/:id", handler.GetThread)
+	protected := discussions.Group("", middleware.AuthMiddleware(newSessionService(db)))
+	protected.POST("", handler.CreateThread)
+	protected.DELETE("/:id", handler.DeleteThread)
+	protected.POST("/:id/comments", handler.CreateComment)
+	protected.DELETE("/comments/:id", handler.DeleteComment)
+	return router, db
+}
+
+func createThread(t *testing.T, router *gin.Engine, token string) int64 {
+	w := adminRequest(router, "POST", "/api/v1/discussions", token,
+		gin.H{"title": "On liberty", "content": "What does freedom require?"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var thread models.Thread
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &thread))
+	return thread.ID
+}
+
+func createComment(t *testing.T, router *gin.Engine, token string, threadID int64, parentID *int64) int64 {
+	w := adminRequest(router, "POST", fmt.Sprintf("/api/v1/discussions/%d/comments", threadID), token,
+		gin.H{"content": "A reply", "parent_id": parentID})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var comment models.Comment
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &comment))
+	return comment.ID
+}
+
+func getThread(t *testing.T, router *gin.Engine, threadID int64) (int, models.ThreadDetailResponse) {
+	w := adminRequest(router, "GET", fmt.Sprintf("/api/v1/discussions/%d", threadID), "", nil)
+	var body models.ThreadDetailResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	}
+	return w.Code, body
+}
+
+func TestDiscussionHandler_DeleteComment_Tombstone(t *testing.T) {
+	router, db := setupDiscussionRouter(t)
+	_, alice := createTestUser(t, db, "alice@example.com", models.RoleReader)
+	_, bob := createTestUser(t, db, "bob@example.com", models.RoleReader)
+
+	threadID := createThread(t, router, alice)
+	parent := createComment(t, router, alice, threadID, nil)
+	reply := createComment(t, router, bob, threadID, &parent)
+	leaf := createComment(t, router, alice, threadID, nil)
+
+	w := adminRequest(router, "DELETE", fmt.Sprintf("/api/v1/discussions/comments/%d", parent), bob, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	for _, id := range []int64{parent, leaf} {
+		w = adminRequest(router, "DELETE", fmt.Sprintf("/api/v1/discussions/comments/%d", id), alice, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	w = adminRequest(router, "DELETE", fmt.Sprintf("/api/v1/discussions/comments/%d", leaf), alice, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	code, body := getThread(t, router, threadID)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, body.Thread.CommentCount)
+	require.Len(t, body.Comments, 2, "the leaf is gone, the parent stays as a tombstone")
+	assert.Equal(t, parent, body.Comments[0].ID)
+	assert.True(t, body.Comments[0].IsDeleted)
+	assert.Equal(t, models.DeletedPlaceholder, body.Comments[0].Content)
+	assert.Nil(t, body.Comments[0].Author)
+	assert.Equal(t, reply, body.Comments[1].ID)
+
+	// No new replies under a tombstone
+	w = adminRequest(router, "POST", fmt.Sprintf("/api/v1/discussions/%d/comments", threadID), bob,
+		gin.H{"content": "Hello?", "parent_id": parent})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDiscussionHandler_DeleteThread(t *testing.T) {
+	router, db := setupDiscussionRouter(t)
+	_, alice := createTestUser(t, db, "alice@example.com", models.RoleReader)
+	_, bob := createTestUser(t, db, "bob@example.com", models.RoleReader)
+
+	empty := createThread(t, router, alice)
+	discussed := createThread(t, router, alice)
+	createComment(t, router, bob, discussed, nil)
+
+	w := adminRequest(router, "DELETE", fmt.Sprintf("/api/v1/discussions/%d", discussed), bob, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	for _, id := range []int64{empty, discussed} {
+		w = adminRequest(router, "DELETE", fmt.Sprintf("/api/v1/discussions/%d", id), alice, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	code, _ := getThread(t, router, empty)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body := getThread(t, router, discussed)
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, body.Thread.IsDeleted)
+	assert.Equal(t, models.DeletedPlaceholder, body.Thread.Title)
+	assert.Nil(t, body.Thread.Author)
+	assert.Len(t, body.Comments, 1)
+
+	w = adminRequest(router, "POST", fmt.Sprintf("/api/v1/discussions/%d/comments", discussed), bob,
+		gin.H{"content": "Still here?"})
+	assert.Equal(t, http.StatusLocked, w.Code, "a tombstone takes no new comments")
+	assert.Contains(t, w.Body.String(), ErrCodeThreadDeleted)
+
+	w = adminRequest(router, "GET", "/api/v1/discussions", "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list models.ThreadListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, list.Total)
+}
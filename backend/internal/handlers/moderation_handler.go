@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+// ModerationHandler serves /api/v1/moderation; routes must be behind
+// AuthMiddleware and RequirePermission(discussions.moderate)
+type ModerationHandler struct {
+	moderationRepo repository.ModerationRepository
+}
+
+func NewModerationHandler(moderationRepo repository.ModerationRepository) *ModerationHandler {
+	return &ModerationHandler{moderationRepo: moderationRepo}
+}
+
+// ModerateThread handles POST /moderation/threads/:id/:action where action is
+// pin, unpin, lock, unlock, delete or restore
+func (h *ModerationHandler) ModerateThread(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid thread ID",
+		})
+		return
+	}
+
+	h.apply(c, &models.ModerationAction{ThreadID: &id, Action: c.Param("action")})
+}
+
+// ModerateComment handles POST /moderation/comments/:id/:action where action
+// is delete or restore
+func (h *ModerationHandler) ModerateComment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid comment ID",
+		})
+		return
+	}
+
+	h.apply(c, &models.ModerationAction{CommentID: &id, Action: c.Param("action")})
+}
+
+// GetActions handles GET /moderation/actions?thread_id=&limit=
+func (h *ModerationHandler) GetActions(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	var threadID *int64
+	if raw := c.Query("thread_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid thread ID",
+			})
+			return
+		}
+		threadID = &id
+	}
+
+	actions, err := h.moderationRepo.ListActions(threadID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch moderation actions",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  actions,
+		"count": len(actions),
+	})
+}
+
+func (h *ModerationHandler) apply(c *gin.Context, action *models.ModerationAction) {
+	var req models.ModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "A reason is required",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	moderatorID := c.GetInt64("user_id")
+	action.ModeratorID = &moderatorID
+	action.Reason = req.Reason
+
+	if err := h.moderationRepo.Apply(action); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrUnsupportedAction):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to apply moderation action",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": action})
+}
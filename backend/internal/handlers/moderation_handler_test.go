@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/middleware"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+func setupModerationRouter(t *testing.T) (*gin.Engine, *sql.DB) {
+	// Shares the migrated database and JWT setup of the admin tests
+	_, db := setupAdminRouter(t)
+
+	discussions := NewDiscussionHandler(
+		repository.NewThreadRepository(db), repository.NewCommentRepository(db),
+		repository.NewVoteRepository(db), repository.NewReactionRepository(db),
+	)
+	moderation := NewModerationHandler(repository.NewModerationRepository(db))
+
+	router := gin.New()
+	api := router.Group("/api/v1")
+	api.GET("/discussions/:id", discussions.GetThread)
+	protected := api.Group("/discussions", middleware.AuthMiddleware(newSessionService(db)))
+	protected.POST("", discussions.CreateThread)
+	protected.POST("/:id/comments", discussions.CreateComment)
+	protected.POST("/:id/vote", discussions.VoteThread)
+	protected.DELETE("/:id", discussions.DeleteThread)
+	protected.DELETE("/comments/:id", discussions.DeleteComment)
+	mod := api.Group("/moderation", middleware.AuthMiddleware(newSessionService(db)),
+		middleware.RequirePermission(repository.NewRoleRepository(db), models.PermissionModerateDiscussions))
+	mod.GET("/actions", moderation.GetActions)
+	mod.POST("/threads/:id/:action", moderation.ModerateThread)
+	mod.POST("/comments/:id/:action", moderation.ModerateComment)
+	return router, db
+}
+
+func TestModerationHandler_LockRejectsWrites(t *testing.T) {
+	router, db := setupModerationRouter(t)
+	_, author := createTestUser(t, db, "author@example.com", models.RoleReader)
+	_, reader := createTestUser(t, db, "reader@example.com", models.RoleReader)
+	_, moderator := createTestUser(t, db, "mod@example.com", models.RoleModerator)
+
+	w := adminRequest(router, "POST", "/api/v1/discussions", author,
+		gin.H{"title": "On liberty", "content": "What does freedom require?"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var thread models.Thread
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &thread))
+	base := fmt.Sprintf("/api/v1/moderation/threads/%d", thread.ID)
+
+	w = adminRequest(router, "POST", base+"/lock", reader, gin.H{"reason": "off topic"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = adminRequest(router, "POST", base+"/lock", moderator, gin.H{})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "a reason is required")
+
+	w = adminRequest(router, "POST", base+"/explode", moderator, gin.H{"reason": "why not"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = adminRequest(router, "POST", base+"/lock", moderator, gin.H{"reason": "heated"})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	for _, path := range []string{"/comments", "/vote"} {
+		w = adminRequest(router, "POST", fmt.Sprintf("/api/v1/discussions/%d%s", thread.ID, path), reader,
+			gin.H{"content": "me too", "vote_type": 1})
+		assert.Equal(t, http.StatusLocked, w.Code, path)
+		assert.Contains(t, w.Body.String(), ErrCodeThreadLocked, path)
+	}
+
+	w = adminRequest(router, "POST", base+"/unlock", moderator, gin.H{"reason": "calmer now"})
+	require.Equal(t, http.StatusOK, w.Code)
+	w = adminRequest(router, "POST", fmt.Sprintf("/api/v1/discussions/%d/comments", thread.ID), reader,
+		gin.H{"content": "me too"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = adminRequest(router, "GET", fmt.Sprintf("/api/v1/moderation/actions?thread_id=%d", thread.ID), moderator, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var log struct {
+		Data []models.ModerationAction `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &log))
+	require.Len(t, log.Data, 2)
+	assert.Equal(t, models.ModerationUnlock, log.Data[0].Action)
+	assert.Equal(t, "heated", log.Data[1].Reason)
+}
+
+func TestModerationHandler_DeleteAndRestore(t *testing.T) {
+	router, db := setupModerationRouter(t)
+	_, author := createTestUser(t, db, "author@example.com", models.RoleReader)
+	_, moderator := createTestUser(t, db, "mod@example.com", models.RoleModerator)
+
+	w := adminRequest(router, "POST", "/api/v1/discussions", author,
+		gin.H{"title": "On liberty", "content": "What does freedom require?"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var thread models.Thread
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &thread))
+	threadPath := fmt.Sprintf("/api/v1/discussions/%d", thread.ID)
+
+	w = adminRequest(router, "POST", threadPath+"/comments", author, gin.H{"content": "First!"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var comment models.Comment
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &comment))
+
+	commentCount := func() int {
+		var n int
+		require.NoError(t, db.QueryRow(`SELECT comment_count FROM threads WHERE id = ?`, thread.ID).Scan(&n))
+		return n
+	}
+	commentPath := fmt.Sprintf("/api/v1/moderation/comments/%d", comment.ID)
+
+	w = adminRequest(router, "POST", commentPath+"/lock", moderator, gin.H{"reason": "spam"})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "comments cannot be locked")
+
+	w = adminRequest(router, "POST", commentPath+"/delete", moderator, gin.H{"reason": "spam"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, commentCount())
+	w = adminRequest(router, "POST", commentPath+"/restore", moderator, gin.H{"reason": "not spam"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, commentCount())
+
+	w = adminRequest(router, "POST", fmt.Sprintf("/api/v1/moderation/threads/%d/delete", thread.ID), moderator, gin.H{"reason": "spam"})
+	require.Equal(t, http.StatusOK, w.Code)
+	w = adminRequest(router, "GET", threadPath, "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = adminRequest(router, "POST", fmt.Sprintf("/api/v1/moderation/threads/%d/restore", thread.ID), moderator, gin.H{"reason": "appeal"})
+	require.Equal(t, http.StatusOK, w.Code)
+	w = adminRequest(router, "GET", threadPath, "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = adminRequest(router, "POST", "/api/v1/moderation/threads/9999/pin", moderator, gin.H{"reason": "missing"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// A restore only undoes moderator deletions
+	w = adminRequest(router, "DELETE", fmt.Sprintf("/api/v1/discussions/comments/%d", comment.ID), author, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = adminRequest(router, "POST", commentPath+"/restore", moderator, gin.H{"reason": "appeal"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 0, commentCount(), "the author's deletion stands")
+
+	actions := func() int {
+		var n int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM moderation_actions`).Scan(&n))
+		return n
+	}
+
+	// A thread without comments is hidden by its author's deletion for good
+	w = adminRequest(router, "POST", "/api/v1/discussions", author, gin.H{"title": "Second thoughts", "content": "Never mind"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var empty models.Thread
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &empty))
+	w = adminRequest(router, "DELETE", fmt.Sprintf("/api/v1/discussions/%d", empty.ID), author, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	before := actions()
+	w = adminRequest(router, "POST", fmt.Sprintf("/api/v1/moderation/threads/%d/restore", empty.ID), moderator, gin.H{"reason": "appeal"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = adminRequest(router, "GET", fmt.Sprintf("/api/v1/discussions/%d", empty.ID), "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, before, actions(), "nothing is recorded")
+
+	// A thread with comments stays up as a tombstone, which a moderator
+	// deletion can hide and a restore bring back
+	w = adminRequest(router, "POST", threadPath+"/comments", moderator, gin.H{"content": "Still worth reading"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = adminRequest(router, "DELETE", threadPath, author, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = adminRequest(router, "POST", fmt.Sprintf("/api/v1/moderation/threads/%d/delete", thread.ID), moderator, gin.H{"reason": "spam"})
+	require.Equal(t, http.StatusOK, w.Code)
+	w = adminRequest(router, "POST", fmt.Sprintf("/api/v1/moderation/threads/%d/restore", thread.ID), moderator, gin.H{"reason": "appeal"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = adminRequest(router, "GET", threadPath, "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
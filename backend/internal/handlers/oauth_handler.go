@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+// OAuthHandler serves sign-in with OAuth providers (Google, GitHub) and the
+// provider accounts linked to the signed-in user. The provider redirects the
+// browser to the frontend, which posts the code and state to a callback here.
+type OAuthHandler struct {
+	service   services.OAuthService
+	sessions  services.SessionService
+	twoFactor services.TwoFactorService
+}
+
+func NewOAuthHandler(service services.OAuthService, sessions services.SessionService, twoFactor services.TwoFactorService) *OAuthHandler {
+	return &OAuthHandler{service: service, sessions: sessions, twoFactor: twoFactor}
+}
+
+// respondOAuthError maps provider failures to 401 and the rest like
+// respondServiceError
+func respondOAuthError(c *gin.Context, err error, message string) {
+	if errors.Is(err, services.ErrInvalidOAuthLogin) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "The provider did not confirm this login"})
+		return
+	}
+	respondServiceError(c, err, message)
+}
+
+// Providers handles GET /auth/oauth: the names of the configured providers
+func (h *OAuthHandler) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.service.Providers()})
+}
+
+// Start handles POST /auth/oauth/:provider: the provider URL to send the
+// browser to
+func (h *OAuthHandler) Start(c *gin.Context) {
+	var req models.OAuthStartRequest
+	// The body is optional
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+			return
+		}
+	}
+
+	url, err := h.service.StartLogin(c.Request.Context(), c.Param("provider"), req.ReferralCode)
+	if err != nil {
+		respondOAuthError(c, err, "Failed to start login")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"url": url}})
+}
+
+// Callback handles POST /auth/oauth/:provider/callback: signs in the user
+// linked to the provider account, registering one on first use
+func (h *OAuthHandler) Callback(c *gin.Context) {
+	var req models.OAuthCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	user, created, err := h.service.SignIn(c.Request.Context(), c.Param("provider"), req.Code, req.State)
+	if err != nil {
+		respondOAuthError(c, err, "Failed to sign in")
+		return
+	}
+	if !user.IsActive {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is not active. Please contact support."})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	startSession(c, h.sessions, h.twoFactor, user, status)
+}
+
+// ListIdentities handles GET /me/identities; must be behind AuthMiddleware
+func (h *OAuthHandler) ListIdentities(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	identities, err := h.service.Identities(userID.(int64))
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch linked accounts")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": identities})
+}
+
+// StartLink handles POST /me/identities/:provider: the provider URL that
+// starts linking an account; must be behind AuthMiddleware
+func (h *OAuthHandler) StartLink(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	url, err := h.service.StartLink(c.Request.Context(), c.Param("provider"), userID.(int64))
+	if err != nil {
+		respondOAuthError(c, err, "Failed to start linking")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"url": url}})
+}
+
+// LinkCallback handles POST /me/identities/:provider/callback; must be behind
+// AuthMiddleware
+func (h *OAuthHandler) LinkCallback(c *gin.Context) {
+	var req models.OAuthCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	identity, err := h.service.Link(c.Request.Context(), userID.(int64), c.Param("provider"), req.Code, req.State)
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This account is linked to another user, or you already linked one of this provider"})
+			return
+		}
+		respondOAuthError(c, err, "Failed to link account")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": identity})
+}
+
+// Unlink handles DELETE /me/identities/:provider; must be behind AuthMiddleware
+func (h *OAuthHandler) Unlink(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	if err := h.service.Unlink(userID.(int64), c.Param("provider")); err != nil {
+		respondServiceError(c, err, "Failed to unlink account")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlinked"})
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/middleware"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+	"github.com/whatisrealfreedom/freedom-website/internal/services/oidctest"
+)
+
+func TestOAuthHandler(t *testing.T) {
+	_, db := setupAdminRouter(t)
+	users := repository.NewUserRepository(db)
+	sessions := newSessionService(db)
+	issuer := oidctest.NewIssuer(t)
+	google := services.NewOIDCProvider(models.ProviderGoogle, issuer.URL, services.OAuthClientConfig{
+		ClientID:     issuer.ClientID,
+		ClientSecret: issuer.ClientSecret,
+		RedirectURL:  "http://localhost:8098/oauth/callback/google",
+	})
+	handler := NewOAuthHandler(services.NewOAuthService(repository.NewIdentityRepository(db), users, newReferralService(db), google), sessions, newTwoFactorService(db))
+
+	router := gin.New()
+	router.GET("/api/v1/auth/oauth", handler.Providers)
+	router.POST("/api/v1/auth/oauth/:provider", handler.Start)
+	router.POST("/api/v1/auth/oauth/:provider/callback", handler.Callback)
+	me := router.Group("/api/v1/me", middleware.AuthMiddleware(sessions))
+	me.GET("/identities", handler.ListIdentities)
+	me.POST("/identities/:provider", handler.StartLink)
+	me.POST("/identities/:provider/callback", handler.LinkCallback)
+	me.DELETE("/identities/:provider", handler.Unlink)
+
+	startURL := func(path, token string) string {
+		w := adminRequest(router, "POST", path, token, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct {
+			Data struct {
+				URL string `json:"url"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data.URL
+	}
+	login := func(user oidctest.User) *httptest.ResponseRecorder {
+		code, state := issuer.Authorize(t, startURL("/api/v1/auth/oauth/google", ""), user)
+		return adminRequest(router, "POST", "/api/v1/auth/oauth/google/callback", "", gin.H{"code": code, "state": state})
+	}
+	alice := oidctest.User{Subject: "alice-sub", Email: "alice@gmail.com", EmailVerified: true, Name: "Alice"}
+
+	w := adminRequest(router, "GET", "/api/v1/auth/oauth", "", nil)
+	assert.JSONEq(t, `{"data":["google"]}`, w.Body.String())
+	w = adminRequest(router, "POST", "/api/v1/auth/oauth/github", "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code, "unconfigured providers are unknown")
+
+	var aliceToken string
+	t.Run("registers on first sign-in, then signs in", func(t *testing.T) {
+		w := login(alice)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var first models.AuthResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &first))
+		assert.NotEmpty(t, first.Token)
+		assert.NotEmpty(t, first.RefreshToken)
+		assert.Equal(t, "alice@gmail.com", first.User.Email)
+		assert.Equal(t, models.ProviderGoogle, *first.User.RegistrationSrc)
+
+		w = login(alice)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var second models.AuthResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &second))
+		assert.Equal(t, first.User.ID, second.User.ID)
+		aliceToken = second.Token
+
+		w = adminRequest(router, "GET", "/api/v1/me/identities", aliceToken, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"subject":"alice-sub"`)
+	})
+
+	t.Run("rejects bad callbacks", func(t *testing.T) {
+		w := adminRequest(router, "POST", "/api/v1/auth/oauth/google/callback", "", gin.H{"code": "x", "state": "forged"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		issuer.Claims = func(c jwt.MapClaims) { c["nonce"] = "replayed" }
+		defer func() { issuer.Claims = nil }()
+		w = login(alice)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, w.Body.String())
+	})
+
+	t.Run("refuses to take over an account by email", func(t *testing.T) {
+		createTestUser(t, db, "bob@example.com", models.RoleReader)
+		w := login(oidctest.User{Subject: "bob-sub", Email: "bob@example.com", EmailVerified: true})
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	})
+
+	t.Run("links and unlinks", func(t *testing.T) {
+		_, token := createTestUser(t, db, "carol@example.com", models.RoleReader)
+
+		code, state := issuer.Authorize(t, startURL("/api/v1/me/identities/google", token), alice)
+		w := adminRequest(router, "POST", "/api/v1/me/identities/google/callback", token, gin.H{"code": code, "state": state})
+		assert.Equal(t, http.StatusConflict, w.Code, "Alice's account belongs to another user")
+
+		carol := oidctest.User{Subject: "carol-sub", Email: "carol@gmail.com", EmailVerified: true}
+		code, state = issuer.Authorize(t, startURL("/api/v1/me/identities/google", token), carol)
+		w = adminRequest(router, "POST", "/api/v1/me/identities/google/callback", token, gin.H{"code": code, "state": state})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"subject":"carol-sub"`)
+
+		w = adminRequest(router, "DELETE", "/api/v1/me/identities/google", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = adminRequest(router, "DELETE", "/api/v1/me/identities/google", token, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+// ProfileHandler serves profile editing and public profiles
+type ProfileHandler struct {
+	service services.ProfileService
+}
+
+func NewProfileHandler(service services.ProfileService) *ProfileHandler {
+	return &ProfileHandler{service: service}
+}
+
+// UpdateMe handles PATCH /me; must be behind AuthMiddleware
+func (h *ProfileHandler) UpdateMe(c *gin.Context) {
+	var input models.ProfileInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	user, err := h.service.UpdateProfile(userID.(int64), &input)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update profile")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
+
+// GetPublic handles GET /users/:id. It never exposes the email or other
+// private fields.
+func (h *ProfileHandler) GetPublic(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	profile, err := h.service.PublicProfile(userID)
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch profile")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": profile})
+}
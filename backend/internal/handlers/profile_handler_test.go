@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/middleware"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+func TestProfileHandler(t *testing.T) {
+	_, db := setupAdminRouter(t)
+	users := repository.NewUserRepository(db)
+	handler := NewProfileHandler(services.NewProfileService(users))
+
+	router := gin.New()
+	router.GET("/api/v1/users/:id", handler.GetPublic)
+	protected := router.Group("/api/v1", middleware.AuthMiddleware(newSessionService(db)))
+	protected.PATCH("/me", handler.UpdateMe)
+
+	userID, token := createTestUser(t, db, "reader@example.com", models.RoleReader)
+	var english int64
+	require.NoError(t, db.QueryRow("SELECT id FROM languages WHERE code = 'en'").Scan(&english))
+
+	t.Run("updates only the fields sent", func(t *testing.T) {
+		w := adminRequest(router, "PATCH", "/api/v1/me", token, gin.H{
+			"name": "Reader", "bio": "Reading about freedom", "preferred_timezone": "Asia/Tehran",
+			"preferred_date_format": "YYYY/MM/DD", "language_id": english,
+		})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = adminRequest(router, "PATCH", "/api/v1/me", token, gin.H{"city": "Shiraz", "bio": ""})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		user, err := users.GetByID(userID)
+		require.NoError(t, err)
+		assert.Equal(t, "Reader", *user.Name)
+		assert.Equal(t, "Shiraz", *user.City)
+		assert.Nil(t, user.Bio, "an empty string clears the field")
+		assert.Equal(t, "Asia/Tehran", *user.PreferredTimezone)
+		assert.Equal(t, english, *user.LanguageID)
+	})
+
+	t.Run("validates fields", func(t *testing.T) {
+		for _, body := range []gin.H{
+			{"preferred_timezone": "Mars/Olympus"},
+			{"preferred_timezone": "Local"},
+			{"preferred_date_format": "someday"},
+			{"language_id": 999},
+			{"country_id": 999},
+			{"currency_id": 999},
+			{"photo_url": "javascript:alert(1)"},
+			{"name": strings.Repeat("ن", 101)},
+		} {
+			w := adminRequest(router, "PATCH", "/api/v1/me", token, body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, "%v: %s", body, w.Body.String())
+		}
+
+		user, err := users.GetByID(userID)
+		require.NoError(t, err)
+		assert.Equal(t, "Asia/Tehran", *user.PreferredTimezone, "a rejected update changes nothing")
+	})
+
+	t.Run("requires authentication", func(t *testing.T) {
+		w := adminRequest(router, "PATCH", "/api/v1/me", "", gin.H{"name": "X"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("public profile hides private fields", func(t *testing.T) {
+		path := fmt.Sprintf("/api/v1/users/%d", userID)
+		w := adminRequest(router, "GET", path, "", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, "unverified accounts are not public")
+
+		require.NoError(t, users.VerifyEmail(userID))
+		w = adminRequest(router, "GET", path, "", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "Reader", resp.Data["name"])
+		assert.Equal(t, "Shiraz", resp.Data["city"])
+		for _, private := range []string{"email", "preferred_timezone", "language_id", "referral_code", "points", "mobile"} {
+			assert.NotContains(t, resp.Data, private)
+		}
+		assert.NotContains(t, w.Body.String(), "reader@example.com")
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		w := adminRequest(router, "GET", "/api/v1/users/9999", "", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = adminRequest(router, "GET", "/api/v1/users/abc", "", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+// ReferralHandler serves the signed-in user's referrals
+type ReferralHandler struct {
+	service services.ReferralService
+}
+
+func NewReferralHandler(service services.ReferralService) *ReferralHandler {
+	return &ReferralHandler{service: service}
+}
+
+// Get handles GET /me/referrals: the user's referral code, points, the tree
+// of users they invited and their points history. Must be behind AuthMiddleware.
+func (h *ReferralHandler) Get(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	overview, err := h.service.Overview(userID.(int64))
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch referrals")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": overview})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/middleware"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+func TestReferralFlow(t *testing.T) {
+	_, db := setupAdminRouter(t)
+	users := repository.NewUserRepository(db)
+	referrals := newReferralService(db)
+	auth := NewAuthHandler(users, newEmailService(t, db), newSessionService(db), newAttemptGuard(db), referrals, newTwoFactorService(db), newReauthService(t, db))
+
+	router := gin.New()
+	router.POST("/api/v1/auth/register", auth.Register)
+	router.POST("/api/v1/auth/verify-email", auth.VerifyEmail)
+	router.GET("/api/v1/me/referrals", middleware.AuthMiddleware(newSessionService(db)), NewReferralHandler(referrals).Get)
+
+	inviterID, token := createTestUser(t, db, "inviter@example.com", models.RoleReader)
+	inviter, err := users.GetByID(inviterID)
+	require.NoError(t, err)
+
+	w := adminRequest(router, "POST", "/api/v1/auth/register", "", gin.H{"email": "nobody@example.com", "password": "password", "referral_code": "RFNOSUCHCODE"})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "an unknown code is rejected")
+
+	w = adminRequest(router, "POST", "/api/v1/auth/register", "", gin.H{"email": "invitee@example.com", "password": "password", "name": "Invitee", "referral_code": inviter.ReferralCode})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	invitee, err := users.GetByEmail("invitee@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, invitee.InvitedBy)
+	assert.Equal(t, inviterID, *invitee.InvitedBy)
+
+	overview := func() models.ReferralOverview {
+		w := adminRequest(router, "GET", "/api/v1/me/referrals", token, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.NotContains(t, w.Body.String(), "@example.com", "invitees are shown without their email")
+		var resp struct {
+			Data models.ReferralOverview `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
+
+	// No points until the invitee verifies
+	before := overview()
+	assert.Equal(t, inviter.ReferralCode, before.ReferralCode)
+	assert.Zero(t, before.Points)
+	require.Len(t, before.Invitees, 1)
+	assert.False(t, before.Invitees[0].Verified)
+
+	var code string
+	require.NoError(t, db.QueryRow("SELECT code FROM email_verification_codes WHERE user_id = ?", invitee.ID).Scan(&code))
+	w = adminRequest(router, "POST", "/api/v1/auth/verify-email", "", gin.H{"email": "invitee@example.com", "code": code})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	after := overview()
+	assert.Equal(t, models.ReferralPoints, after.Points)
+	require.Len(t, after.Invitees, 1)
+	assert.True(t, after.Invitees[0].Verified)
+	assert.Equal(t, "Invitee", *after.Invitees[0].Name)
+	require.Len(t, after.History, 1)
+	assert.Equal(t, models.PointsReasonReferral, after.History[0].Reason)
+	assert.Equal(t, invitee.ID, *after.History[0].SourceUserID)
+}
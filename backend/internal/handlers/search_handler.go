@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+type SearchHandler struct {
+	service services.SearchService
+}
+
+func NewSearchHandler(service services.SearchService) *SearchHandler {
+	return &SearchHandler{service: service}
+}
+
+// Search handles GET /api/v1/search?q=&locale=&limit=
+func (h *SearchHandler) Search(c *gin.Context) {
+	query := c.Query("q")
+	locale := c.Query("locale")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	results, err := h.service.Search(query, locale, limit)
+	if errors.Is(err, services.ErrSearchQueryTooShort) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to search",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   results,
+		"count":  len(results),
+		"query":  query,
+		"locale": locale,
+	})
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+// TelegramHandler serves Telegram sign-in and account linking
+type TelegramHandler struct {
+	auth      *services.TelegramAuth
+	service   services.TelegramService
+	sessions  services.SessionService
+	twoFactor services.TwoFactorService
+}
+
+func NewTelegramHandler(auth *services.TelegramAuth, service services.TelegramService, sessions services.SessionService, twoFactor services.TwoFactorService) *TelegramHandler {
+	return &TelegramHandler{auth: auth, service: service, sessions: sessions, twoFactor: twoFactor}
+}
+
+// verify reads the Login Widget payload from the body, as the widget passed
+// it to the page, plus an optional "referral_code" of ours
+func (h *TelegramHandler) verify(c *gin.Context) (*models.TelegramUser, string, bool) {
+	var raw models.TelegramLogin
+	if err := c.ShouldBindJSON(&raw); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return nil, "", false
+	}
+
+	var referralCode string
+	if code, ok := raw["referral_code"]; ok {
+		json.Unmarshal(code, &referralCode)
+		delete(raw, "referral_code")
+	}
+
+	tg, err := h.auth.Verify(raw.Fields())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired Telegram login"})
+		return nil, "", false
+	}
+	return tg, referralCode, true
+}
+
+// Login handles POST /auth/telegram: signs in the user linked to the Telegram
+// account, registering one on first use
+func (h *TelegramHandler) Login(c *gin.Context) {
+	tg, referralCode, ok := h.verify(c)
+	if !ok {
+		return
+	}
+
+	user, created, err := h.service.SignIn(tg, referralCode)
+	if err != nil {
+		respondServiceError(c, err, "Failed to sign in with Telegram")
+		return
+	}
+	if !user.IsActive {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is not active. Please contact support."})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	startSession(c, h.sessions, h.twoFactor, user, status)
+}
+
+// Link handles POST /me/telegram with a Login Widget payload; must be behind
+// AuthMiddleware
+func (h *TelegramHandler) Link(c *gin.Context) {
+	tg, _, ok := h.verify(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if err := h.service.Link(userID.(int64), tg); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This Telegram account is linked to another user"})
+			return
+		}
+		respondServiceError(c, err, "Failed to link Telegram account")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Telegram account linked", "telegram_id": tg.ID})
+}
+
+// Unlink handles DELETE /me/telegram; must be behind AuthMiddleware
+func (h *TelegramHandler) Unlink(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	if err := h.service.Unlink(userID.(int64)); err != nil {
+		respondServiceError(c, err, "Failed to unlink Telegram account")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Telegram account unlinked"})
+}
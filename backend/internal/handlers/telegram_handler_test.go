@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/middleware"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+// Payloads signed by Telegram for bot token "123456:TEST-BOT-TOKEN" at
+// auth_date 1760000000; the hashes were computed outside Go
+var (
+	telegramAli = gin.H{
+		"id": 987654321, "first_name": "علی", "last_name": "Rezaei", "username": "alirezaei",
+		"photo_url": "https://t.me/i/userpic/320/ali.jpg", "auth_date": 1760000000,
+		"hash": "4b19582c7d907cbf56e670a4ff0324007e1cc31a2d3dc98d13171d1cca31b043",
+	}
+	telegramSara = gin.H{
+		"id": 111222333, "first_name": "Sara", "auth_date": 1760000000,
+		"hash": "6166f66955d6540dfda148990b2b51f7fb135c4e5bae4e05327b593fc05ed6f9",
+	}
+)
+
+func TestTelegramHandler(t *testing.T) {
+	_, db := setupAdminRouter(t)
+	users := repository.NewUserRepository(db)
+	sessions := newSessionService(db)
+	auth := services.NewTelegramAuth("123456:TEST-BOT-TOKEN")
+	auth.Now = func() time.Time { return time.Unix(1760000000, 0).Add(time.Minute) }
+	handler := NewTelegramHandler(auth, services.NewTelegramService(users, repository.NewIdentityRepository(db), newReferralService(db)), sessions, newTwoFactorService(db))
+
+	router := gin.New()
+	router.POST("/api/v1/auth/telegram", handler.Login)
+	me := router.Group("/api/v1/me", middleware.AuthMiddleware(sessions))
+	me.POST("/telegram", handler.Link)
+	me.DELETE("/telegram", handler.Unlink)
+
+	t.Run("registers on first sign-in, then signs in", func(t *testing.T) {
+		w := adminRequest(router, "POST", "/api/v1/auth/telegram", "", telegramAli)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var first models.AuthResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &first))
+		assert.NotEmpty(t, first.Token)
+		assert.NotEmpty(t, first.RefreshToken)
+		require.NotNil(t, first.User.TelegramID)
+		assert.Equal(t, int64(987654321), *first.User.TelegramID)
+		assert.Equal(t, "علی Rezaei", *first.User.Name)
+		assert.True(t, models.IsPlaceholderEmail(first.User.Email))
+
+		w = adminRequest(router, "POST", "/api/v1/auth/telegram", "", telegramAli)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var second models.AuthResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &second))
+		assert.Equal(t, first.User.ID, second.User.ID)
+
+		// With no email or password, unlinking would lock the user out
+		w = adminRequest(router, "DELETE", "/api/v1/me/telegram", second.Token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("rejects forged payloads", func(t *testing.T) {
+		forged := gin.H{}
+		for k, v := range telegramSara {
+			forged[k] = v
+		}
+		forged["id"] = 987654321
+		w := adminRequest(router, "POST", "/api/v1/auth/telegram", "", forged)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("links and unlinks", func(t *testing.T) {
+		userID, token := createTestUser(t, db, "reader@example.com", models.RoleReader)
+
+		w := adminRequest(router, "POST", "/api/v1/me/telegram", token, telegramAli)
+		assert.Equal(t, http.StatusConflict, w.Code, "Ali's account belongs to another user")
+
+		w = adminRequest(router, "POST", "/api/v1/me/telegram", token, telegramSara)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		user, err := users.GetByID(userID)
+		require.NoError(t, err)
+		require.NotNil(t, user.TelegramID)
+		assert.Equal(t, int64(111222333), *user.TelegramID)
+
+		w = adminRequest(router, "DELETE", "/api/v1/me/telegram", token, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		user, err = users.GetByID(userID)
+		require.NoError(t, err)
+		assert.Nil(t, user.TelegramID)
+	})
+	t.Run("accounts with a linked provider can unlink Telegram", func(t *testing.T) {
+		w := adminRequest(router, "POST", "/api/v1/auth/telegram", "", telegramAli)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var ali models.AuthResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ali))
+
+		identities := repository.NewIdentityRepository(db)
+		require.NoError(t, identities.Create(&models.Identity{UserID: ali.User.ID, Provider: models.ProviderGoogle, Subject: "ali", Email: "ali@example.com"}))
+
+		w = adminRequest(router, "DELETE", "/api/v1/me/telegram", ali.Token, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		user, err := users.GetByID(ali.User.ID)
+		require.NoError(t, err)
+		assert.Nil(t, user.TelegramID, "Google still signs them in")
+	})
+}
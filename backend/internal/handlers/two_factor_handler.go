@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+// startSession answers a successful first factor: a token pair with status,
+// or 202 with a TwoFactorChallenge when the user has two-factor
+// authentication on. Every way of signing in goes through here.
+func startSession(c *gin.Context, sessions services.SessionService, twoFactor services.TwoFactorService, user *models.User, status int) {
+	challenge, err := twoFactor.Challenge(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor login", "details": err.Error()})
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusAccepted, challenge)
+		return
+	}
+
+	tokens, err := sessions.Start(user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token", "details": err.Error()})
+		return
+	}
+
+	// Clear password from response
+	user.Password = ""
+
+	c.JSON(status, models.AuthResponse{
+		TokenPair: *tokens,
+		User:      user,
+	})
+}
+
+// CompleteTwoFactor handles POST /auth/2fa: finishes a login that answered a
+// challenge, given a TOTP or recovery code
+func (h *AuthHandler) CompleteTwoFactor(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	userID, err := h.twoFactor.ChallengeUser(req.ChallengeToken)
+	if err != nil {
+		respondChallengeError(c, err)
+		return
+	}
+	user, err := h.userRepo.GetByID(userID)
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch user")
+		return
+	}
+
+	// Each challenge allows a few guesses; this stops cycling through
+	// challenges with a known password
+	if h.lockedOut(c, services.ScopeTwoFactor, user.Email) {
+		return
+	}
+	if _, err := h.twoFactor.Complete(req.ChallengeToken, req.Code); err != nil {
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			h.guard.Fail(services.ScopeTwoFactor, user.Email, c.ClientIP())
+		}
+		respondChallengeError(c, err)
+		return
+	}
+	h.guard.Succeed(services.ScopeTwoFactor, user.Email)
+
+	if !user.IsActive {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is not active. Please contact support."})
+		return
+	}
+
+	tokens, err := h.sessions.Start(user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token", "details": err.Error()})
+		return
+	}
+
+	user.Password = ""
+	c.JSON(http.StatusOK, models.AuthResponse{
+		TokenPair: *tokens,
+		User:      user,
+	})
+}
+
+func respondChallengeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidChallenge):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired. Please sign in again."})
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor code", "details": err.Error()})
+	}
+}
+
+// TwoFactorHandler serves enrollment in two-factor authentication; every
+// route must be behind AuthMiddleware
+type TwoFactorHandler struct {
+	service services.TwoFactorService
+	users   repository.UserRepository
+	guard   *services.AttemptGuard
+}
+
+// NewTwoFactorHandler creates the handler; guard counts wrong codes and
+// passwords together with the second step of logins
+func NewTwoFactorHandler(service services.TwoFactorService, users repository.UserRepository, guard *services.AttemptGuard) *TwoFactorHandler {
+	return &TwoFactorHandler{service: service, users: users, guard: guard}
+}
+
+// Status handles GET /me/2fa
+func (h *TwoFactorHandler) Status(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	status, err := h.service.Status(userID.(int64))
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch two-factor status")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": status})
+}
+
+// Setup handles POST /me/2fa/setup: a new secret with its otpauth URL and QR
+// code. It takes effect once confirmed with Enable.
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	user, err := h.users.GetByID(userID.(int64))
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch user")
+		return
+	}
+
+	setup, err := h.service.Setup(user)
+	if err != nil {
+		respondServiceError(c, err, "Failed to set up two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": setup})
+}
+
+// Enable handles POST /me/2fa/enable with a code from the authenticator and
+// answers the recovery codes, which are shown only this once
+func (h *TwoFactorHandler) Enable(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	codes, err := h.service.Enable(userID.(int64), req.Code)
+	if err != nil {
+		respondServiceError(c, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"recovery_codes": codes}})
+}
+
+// Disable handles POST /me/2fa/disable with a TOTP or recovery code and, for
+// accounts with a password of their own, the password
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	var req models.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	user := h.guardedUser(c)
+	if user == nil {
+		return
+	}
+	// Accounts without a password of their own have only the code as proof
+	if user.HasPassword() && !repository.CheckPassword(req.Password, user.Password) {
+		h.guard.Fail(services.ScopeTwoFactor, user.Email, c.ClientIP())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The password is not correct"})
+		return
+	}
+
+	err := h.service.Disable(user.ID, req.Code)
+	if !h.checked(c, user, err) {
+		respondServiceError(c, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes handles POST /me/2fa/recovery-codes with a TOTP or
+// recovery code; the old recovery codes stop working
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	user := h.guardedUser(c)
+	if user == nil {
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(user.ID, req.Code)
+	if !h.checked(c, user, err) {
+		respondServiceError(c, err, "Failed to create recovery codes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"recovery_codes": codes}})
+}
+
+// guardedUser loads the signed-in user, or answers and returns nil when the
+// user is missing or locked out of ScopeTwoFactor, like the login step
+func (h *TwoFactorHandler) guardedUser(c *gin.Context) *models.User {
+	userID, _ := c.Get("user_id")
+	user, err := h.users.GetByID(userID.(int64))
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch user")
+		return nil
+	}
+	if lockedOut(c, h.guard, services.ScopeTwoFactor, user.Email) {
+		return nil
+	}
+	return user
+}
+
+// checked counts a wrong code against the user and clears the count on
+// success; it reports whether err is nil
+func (h *TwoFactorHandler) checked(c *gin.Context, user *models.User, err error) bool {
+	if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+		h.guard.Fail(services.ScopeTwoFactor, user.Email, c.ClientIP())
+	}
+	if err != nil {
+		return false
+	}
+	h.guard.Succeed(services.ScopeTwoFactor, user.Email)
+	return true
+}
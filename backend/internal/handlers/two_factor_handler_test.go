@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/middleware"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"github.com/whatisrealfreedom/freedom-website/internal/services"
+)
+
+// currentTOTP is what an authenticator app shows for secret now
+func currentTOTP(t *testing.T, secret string) string {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	require.NoError(t, err)
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1_000_000)
+}
+
+func TestTwoFactorHandler(t *testing.T) {
+	_, db := setupAdminRouter(t)
+	users := repository.NewUserRepository(db)
+	sessions := newSessionService(db)
+	twoFactor := newTwoFactorService(db)
+	guard := newAttemptGuard(db)
+	auth := NewAuthHandler(users, newEmailService(t, db), sessions, guard, newReferralService(db), twoFactor, newReauthService(t, db))
+	handler := NewTwoFactorHandler(twoFactor, users, guard)
+
+	router := gin.New()
+	router.POST("/api/v1/auth/login", auth.Login)
+	router.POST("/api/v1/auth/2fa", auth.CompleteTwoFactor)
+	me := router.Group("/api/v1/me", middleware.AuthMiddleware(sessions))
+	me.GET("/2fa", handler.Status)
+	me.POST("/2fa/setup", handler.Setup)
+	me.POST("/2fa/enable", handler.Enable)
+	me.POST("/2fa/disable", handler.Disable)
+	staff := router.Group("/api/v1/admin", middleware.AuthMiddleware(sessions), middleware.RequireTwoFactor(twoFactor))
+	staff.GET("/ping", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	user := &models.User{Email: "moderator@example.com", Password: "password", IsActive: true}
+	require.NoError(t, users.Create(user))
+	require.NoError(t, users.VerifyEmail(user.ID))
+	login := gin.H{"email": user.Email, "password": "password"}
+
+	w := adminRequest(router, "POST", "/api/v1/auth/login", "", login)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var session models.AuthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &session))
+
+	w = adminRequest(router, "GET", "/api/v1/admin/ping", session.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code, "staff routes want two-factor authentication")
+
+	w = adminRequest(router, "POST", "/api/v1/me/2fa/setup", session.Token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var setup struct{ Data models.TwoFactorSetup }
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &setup))
+
+	w = adminRequest(router, "POST", "/api/v1/me/2fa/enable", session.Token, gin.H{"code": "000000"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = adminRequest(router, "POST", "/api/v1/me/2fa/enable", session.Token, gin.H{"code": currentTOTP(t, setup.Data.Secret)})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var enabled struct {
+		Data struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		}
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &enabled))
+	require.Len(t, enabled.Data.RecoveryCodes, models.RecoveryCodeCount)
+
+	w = adminRequest(router, "GET", "/api/v1/admin/ping", session.Token, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	challenge := func() string {
+		w := adminRequest(router, "POST", "/api/v1/auth/login", "", login)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		var body models.TwoFactorChallenge
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.True(t, body.TwoFactorRequired)
+		assert.NotContains(t, w.Body.String(), `"token"`, "no session before the second factor")
+		return body.ChallengeToken
+	}
+
+	t.Run("wrong code", func(t *testing.T) {
+		w := adminRequest(router, "POST", "/api/v1/auth/2fa", "", gin.H{"challenge_token": challenge(), "code": "000000"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = adminRequest(router, "POST", "/api/v1/auth/2fa", "", gin.H{"challenge_token": "unknown", "code": "000000"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("recovery code signs in once", func(t *testing.T) {
+		code := enabled.Data.RecoveryCodes[0]
+		w := adminRequest(router, "POST", "/api/v1/auth/2fa", "", gin.H{"challenge_token": challenge(), "code": code})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var body models.AuthResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.NotEmpty(t, body.Token)
+		assert.Equal(t, user.ID, body.User.ID)
+
+		w = adminRequest(router, "POST", "/api/v1/auth/2fa", "", gin.H{"challenge_token": challenge(), "code": code})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		w := adminRequest(router, "POST", "/api/v1/me/2fa/disable", session.Token, gin.H{"code": enabled.Data.RecoveryCodes[1]})
+		assert.Equal(t, http.StatusBadRequest, w.Code, "the password is required")
+		w = adminRequest(router, "POST", "/api/v1/me/2fa/disable", session.Token,
+			gin.H{"code": enabled.Data.RecoveryCodes[1], "password": "password"})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = adminRequest(router, "GET", "/api/v1/me/2fa", session.Token, nil)
+		assert.JSONEq(t, `{"data":{"enabled":false,"recovery_codes_left":0}}`, w.Body.String())
+
+		w = adminRequest(router, "POST", "/api/v1/auth/login", "", login)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestTwoFactorHandler_LocksOutCodeGuessing(t *testing.T) {
+	_, db := setupAdminRouter(t)
+	users := repository.NewUserRepository(db)
+	twoFactor := newTwoFactorService(db)
+	handler := NewTwoFactorHandler(twoFactor, users, newAttemptGuard(db))
+
+	router := gin.New()
+	me := router.Group("/api/v1/me", middleware.AuthMiddleware(newSessionService(db)))
+	me.POST("/2fa/disable", handler.Disable)
+	me.POST("/2fa/recovery-codes", handler.RegenerateRecoveryCodes)
+
+	userID, token := createTestUser(t, db, "reader@example.com", models.RoleReader)
+	user, err := users.GetByID(userID)
+	require.NoError(t, err)
+	setup, err := twoFactor.Setup(user)
+	require.NoError(t, err)
+	_, err = twoFactor.Enable(userID, currentTOTP(t, setup.Secret))
+	require.NoError(t, err)
+
+	// A stolen access token gets as few guesses as a login
+	for i := 0; i < services.DefaultAccountPolicy.MaxFailures; i++ {
+		w := adminRequest(router, "POST", "/api/v1/me/2fa/recovery-codes", token, gin.H{"code": fmt.Sprintf("%06d", i)})
+		require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	w := adminRequest(router, "POST", "/api/v1/me/2fa/disable", token,
+		gin.H{"code": currentTOTP(t, setup.Secret), "password": "x"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "even the right code waits out the lockout")
+	enabled, err := twoFactor.Enabled(userID)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+}
+
+func TestTwoFactorHandler_ProviderAccounts(t *testing.T) {
+	_, db := setupAdminRouter(t)
+	users := repository.NewUserRepository(db)
+	sessions := newSessionService(db)
+	twoFactor := newTwoFactorService(db)
+	handler := NewTwoFactorHandler(twoFactor, users, newAttemptGuard(db))
+
+	router := gin.New()
+	me := router.Group("/api/v1/me", middleware.AuthMiddleware(sessions))
+	me.POST("/2fa/disable", handler.Disable)
+
+	signUp := func(email string) (*models.User, string, []string) {
+		source := models.ProviderGoogle
+		user := &models.User{Email: email, Password: "random", RegistrationSrc: &source, IsActive: true}
+		require.NoError(t, users.Create(user))
+		setup, err := twoFactor.Setup(user)
+		require.NoError(t, err)
+		codes, err := twoFactor.Enable(user.ID, currentTOTP(t, setup.Secret))
+		require.NoError(t, err)
+		pair, err := sessions.Start(user, "test", "127.0.0.1")
+		require.NoError(t, err)
+		return user, pair.Token, codes
+	}
+
+	// Nobody knows the random password of a Google sign-up
+	_, token, codes := signUp("google@example.com")
+	w := adminRequest(router, "POST", "/api/v1/me/2fa/disable", token, gin.H{"code": codes[0]})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Once they set one with a password reset, it is asked for
+	user, _, codes := signUp("reset@example.com")
+	require.NoError(t, users.ResetPassword(user.ID, "new password"))
+	user, err := users.GetByID(user.ID)
+	require.NoError(t, err)
+	pair, err := sessions.Start(user, "test", "127.0.0.1")
+	require.NoError(t, err)
+	w = adminRequest(router, "POST", "/api/v1/me/2fa/disable", pair.Token, gin.H{"code": codes[0]})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "the password is required")
+	w = adminRequest(router, "POST", "/api/v1/me/2fa/disable", pair.Token, gin.H{"code": codes[0], "password": "new password"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
@@ -8,8 +8,14 @@ import (
 	"github.com/whatisrealfreedom/freedom-website/internal/utils"
 )
 
+// TokenChecker decides whether a validly signed token is still honoured, e.g.
+// that its session has not been revoked
+type TokenChecker interface {
+	CheckToken(claims *utils.Claims) error
+}
+
 // AuthMiddleware validates JWT tokens
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(checker TokenChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -27,7 +33,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		token := parts[1]
-		claims, err := utils.ValidateToken(token)
+		claims, err := validateToken(checker, token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
@@ -37,6 +43,144 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Set user information in context
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
+		c.Set("user_role", claims.Role)
+		c.Set("session_id", claims.SessionID)
+
+		c.Next()
+	}
+}
+
+// OptionalAuthMiddleware sets the user information like AuthMiddleware when a
+// valid token is present, but lets anonymous requests through
+func OptionalAuthMiddleware(checker TokenChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if claims, err := validateToken(checker, parts[1]); err == nil {
+				c.Set("user_id", claims.UserID)
+				c.Set("user_email", claims.Email)
+				c.Set("user_role", claims.Role)
+				c.Set("session_id", claims.SessionID)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// validateToken checks the signature and expiry, then asks checker whether
+// the token has been revoked
+func validateToken(checker TokenChecker, token string) (*utils.Claims, error) {
+	claims, err := utils.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checker.CheckToken(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// RoleSource looks up a user's current role
+type RoleSource interface {
+	GetRole(userID int64) (string, error)
+}
+
+// RequireRole must run after AuthMiddleware. It allows the request only if the
+// user currently has one of roles. The role is read from roles rather than the
+// token, so a demotion takes effect immediately.
+func RequireRole(roles RoleSource, allowed ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		role, err := roles.GetRole(userID.(int64))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+
+		for _, r := range allowed {
+			if role == r {
+				c.Set("user_role", role)
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.Abort()
+	}
+}
+
+// PermissionSource checks a user's current role against role_permissions
+type PermissionSource interface {
+	UserHasPermission(userID int64, permission string) (bool, error)
+}
+
+// RequirePermission must run after AuthMiddleware. Like RequireRole it reads
+// the database on every request; prefer it for new routes so that what a role
+// may do lives in role_permissions.
+func RequirePermission(perms PermissionSource, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		allowed, err := perms.UserHasPermission(userID.(int64), permission)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions", "details": err.Error()})
+			c.Abort()
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions", "permission": permission})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// TwoFactorSource reports whether a user has two-factor authentication on
+type TwoFactorSource interface {
+	Enabled(userID int64) (bool, error)
+}
+
+// RequireTwoFactor must run after AuthMiddleware. It turns away users who
+// have not turned on two-factor authentication, for routes such as the admin
+// and moderation ones where a stolen password would do the most harm.
+func RequireTwoFactor(source TwoFactorSource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		enabled, err := source.Enabled(userID.(int64))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor authentication", "details": err.Error()})
+			c.Abort()
+			return
+		}
+		if !enabled {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Turn on two-factor authentication to use this", "two_factor_setup_required": true})
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter is a token bucket per client: each starts with Requests tokens,
+// a request takes one, and tokens come back at Requests per Period
+type RateLimiter struct {
+	requests int
+	period   time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func NewRateLimiter(requests int, period time.Duration) *RateLimiter {
+	return &RateLimiter{
+		requests: requests,
+		period:   period,
+		now:      time.Now,
+		buckets:  map[string]*bucket{},
+	}
+}
+
+// take spends a token of key. It returns the tokens left and, when none was
+// available, how long until one is.
+func (l *RateLimiter) take(key string) (allowed bool, remaining int, retryAfter, reset time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	capacity := float64(l.requests)
+	perToken := l.period / time.Duration(l.requests)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		l.sweep(now)
+		b = &bucket{tokens: capacity, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.updated))/float64(perToken))
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		allowed = true
+	} else {
+		retryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	reset = time.Duration((capacity - b.tokens) * float64(perToken))
+
+	return allowed, int(b.tokens), retryAfter, reset
+}
+
+// sweep forgets buckets that have refilled completely, which behave exactly
+// like new ones, once there are many of them
+func (l *RateLimiter) sweep(now time.Time) {
+	if len(l.buckets) < 10000 {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= l.period {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimit throttles requests with limiter, per user when it runs after
+// AuthMiddleware and per client IP otherwise. Every response carries the
+// RateLimit-* headers; rejected ones get 429 and Retry-After. A nil limiter
+// lets everything through.
+func RateLimit(limiter *RateLimiter) gin.HandlerFunc {
+	if limiter == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	policy := fmt.Sprintf("%d;w=%d", limiter.requests, int(limiter.period.Seconds()))
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if userID, ok := c.Get("user_id"); ok {
+			key = fmt.Sprintf("user:%v", userID)
+		}
+
+		allowed, remaining, retryAfter, reset := limiter.take(key)
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(limiter.requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests. Please slow down."})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
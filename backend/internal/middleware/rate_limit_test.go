@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/config"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(3, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	router := gin.New()
+	router.GET("/anon", RateLimit(limiter), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/user/:id", func(c *gin.Context) {
+		c.Set("user_id", int64(len(c.Param("id"))))
+		c.Next()
+	}, RateLimit(limiter), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func(path, ip string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i, remaining := range []string{"2", "1", "0"} {
+		w := get("/anon", "10.0.0.1")
+		assert.Equal(t, http.StatusOK, w.Code, "request %d", i)
+		assert.Equal(t, "3", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, remaining, w.Header().Get("RateLimit-Remaining"))
+	}
+	assert.Equal(t, "3;w=60", get("/anon", "10.0.0.2").Header().Get("RateLimit-Policy"))
+
+	w := get("/anon", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "20", w.Header().Get("Retry-After"), "one token comes back every 20s")
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+
+	// Signed-in users have their own bucket wherever they come from
+	assert.Equal(t, http.StatusOK, get("/user/x", "10.0.0.1").Code)
+
+	now = now.Add(20 * time.Second)
+	assert.Equal(t, http.StatusOK, get("/anon", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, get("/anon", "10.0.0.1").Code)
+
+	now = now.Add(time.Hour)
+	w = get("/anon", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Remaining"), "buckets never hold more than the limit")
+}
+
+func TestRateLimit_ForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(cfg *config.Config) *gin.Engine {
+		router := gin.New()
+		require.NoError(t, router.SetTrustedProxies(cfg.TrustedProxyList()))
+		router.GET("/anon", RateLimit(NewRateLimiter(2, time.Minute)), func(c *gin.Context) { c.Status(http.StatusOK) })
+		return router
+	}
+	get := func(router *gin.Engine, remote, forwardedFor string) int {
+		req, _ := http.NewRequest("GET", "/anon", nil)
+		req.RemoteAddr = remote + ":1234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("without a proxy the header is ignored", func(t *testing.T) {
+		router := newRouter(&config.Config{})
+		for i, spoofed := range []string{"198.51.100.1", "198.51.100.2"} {
+			assert.Equal(t, http.StatusOK, get(router, "203.0.113.7", spoofed), "request %d", i)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, get(router, "203.0.113.7", "198.51.100.3"))
+	})
+
+	t.Run("behind a trusted proxy clients are told apart", func(t *testing.T) {
+		router := newRouter(&config.Config{TrustedProxies: "10.0.0.0/8"})
+		// The proxy appends the address it saw; whatever the client put
+		// before it does not count
+		for i, spoofed := range []string{"198.51.100.1", "198.51.100.2"} {
+			assert.Equal(t, http.StatusOK, get(router, "10.0.0.2", spoofed+", 203.0.113.7"), "request %d", i)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, get(router, "10.0.0.2", "198.51.100.3, 203.0.113.7"))
+		assert.Equal(t, http.StatusOK, get(router, "10.0.0.2", "203.0.113.8"), "another client has its own bucket")
+
+		// Only the proxy may set the header
+		assert.Equal(t, http.StatusOK, get(router, "203.0.113.9", "203.0.113.7"))
+	})
+}
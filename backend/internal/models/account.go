@@ -0,0 +1,44 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// AccountDeletionGrace is how long a deletion requested with DELETE /me can
+// still be cancelled before the account is anonymized
+const AccountDeletionGrace = 30 * 24 * time.Hour
+
+// deletedEmailDomain is reserved (RFC 2606), like placeholderEmailDomain
+const deletedEmailDomain = "@deleted.invalid"
+
+// DeletedUserEmail replaces the email of a deleted user; users.email may not
+// be empty and must stay unique
+func DeletedUserEmail(userID int64) string {
+	return fmt.Sprintf("deleted-%d%s", userID, deletedEmailDomain)
+}
+
+// DeleteAccountRequest is the payload of DELETE /me; see ReauthMethods for
+// what it must carry
+type DeleteAccountRequest struct {
+	ReauthRequest
+}
+
+// AccountDeletion answers DELETE /me
+type AccountDeletion struct {
+	RequestedAt time.Time `json:"requested_at"`
+	// DeleteAfter is when the account is anonymized unless the request is
+	// cancelled
+	DeleteAfter time.Time `json:"delete_after"`
+}
+
+// AccountExport is everything GET /me/export hands a user about themselves.
+// Each field becomes a JSON file of the ZIP.
+type AccountExport struct {
+	Profile   *User          `json:"profile"`
+	Threads   []*Thread      `json:"threads"`
+	Comments  []*Comment     `json:"comments"`
+	Votes     []*Vote        `json:"votes"`
+	Reactions []*Reaction    `json:"reactions"`
+	Drafts    []*ThreadDraft `json:"drafts"`
+}
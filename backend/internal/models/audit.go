@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Audit log events
+const (
+	AuditLockout        = "auth.lockout"
+	AuditAccountDeleted = "account.deleted"
+)
+
+// AuditEntry is one row of audit_log
+type AuditEntry struct {
+	ID        int64     `json:"id" db:"id"`
+	Event     string    `json:"event" db:"event"`
+	UserID    *int64    `json:"user_id,omitempty" db:"user_id"`
+	Subject   string    `json:"subject" db:"subject"`
+	IPAddress string    `json:"ip_address" db:"ip_address"`
+	Details   string    `json:"details" db:"details"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -8,7 +8,7 @@ type Chapter struct {
 	Slug        string `json:"slug" db:"slug"`
 	Description string `json:"description" db:"description"`
 	Content     string `json:"content" db:"content"`
-	ContentEn   string `json:"content_en" db:"content_en"`
+	ContentEn   string `json:"content_en,omitempty" db:"content_en"` // superseded by chapter_translations
 	Icon        string `json:"icon" db:"icon"`
 	Pages       int    `json:"pages" db:"pages"`
 	ReadTime    int    `json:"read_time" db:"read_time"` // in minutes
@@ -16,6 +16,12 @@ type Chapter struct {
 	Order       int    `json:"order" db:"order"`
 	CreatedAt   string `json:"created_at" db:"created_at"`
 	UpdatedAt   string `json:"updated_at" db:"updated_at"`
+
+	// Locale is the translation actually served; AvailableLocales lists all
+	// translations and Slugs maps each of them to its slug
+	Locale           string            `json:"locale"`
+	AvailableLocales []string          `json:"available_locales"`
+	Slugs            map[string]string `json:"slugs"`
 }
 
 // ChapterSummary is a lightweight version for listing
@@ -29,4 +35,74 @@ type ChapterSummary struct {
 	Pages       int    `json:"pages"`
 	ReadTime    int    `json:"read_time"`
 	Featured    bool   `json:"featured"`
+	Locale      string `json:"locale"`
+}
+
+// ChapterTranslation holds the localized fields of a chapter (chapter_translations)
+type ChapterTranslation struct {
+	ID          int64  `json:"id" db:"id"`
+	ChapterID   int    `json:"chapter_id" db:"chapter_id"`
+	Locale      string `json:"locale" db:"locale"`
+	Title       string `json:"title" db:"title"`
+	Slug        string `json:"slug" db:"slug"`
+	Description string `json:"description" db:"description"`
+	Content     string `json:"content" db:"content"`
+}
+
+// ChapterInput is the admin payload for creating or updating a chapter.
+// On update, fields left out of the JSON keep their current value.
+type ChapterInput struct {
+	Number      *int    `json:"number"`
+	Title       *string `json:"title"`
+	Slug        *string `json:"slug"`
+	Description *string `json:"description"`
+	Icon        *string `json:"icon"`
+	Pages       *int    `json:"pages"`
+	ReadTime    *int    `json:"read_time"`
+	Featured    *bool   `json:"featured"`
+	Order       *int    `json:"order"`
+}
+
+// ApplyTo copies the fields that are set onto c
+func (in *ChapterInput) ApplyTo(c *Chapter) {
+	if in.Number != nil {
+		c.Number = *in.Number
+	}
+	if in.Title != nil {
+		c.Title = *in.Title
+	}
+	if in.Slug != nil {
+		c.Slug = *in.Slug
+	}
+	if in.Description != nil {
+		c.Description = *in.Description
+	}
+	if in.Icon != nil {
+		c.Icon = *in.Icon
+	}
+	if in.Pages != nil {
+		c.Pages = *in.Pages
+	}
+	if in.ReadTime != nil {
+		c.ReadTime = *in.ReadTime
+	}
+	if in.Featured != nil {
+		c.Featured = *in.Featured
+	}
+	if in.Order != nil {
+		c.Order = *in.Order
+	}
+}
+
+// TranslationInput is the admin payload for saving a chapter translation
+type TranslationInput struct {
+	Title       string `json:"title" binding:"required"`
+	Slug        string `json:"slug" binding:"required"`
+	Description string `json:"description" binding:"required"`
+	Content     string `json:"content"`
+}
+
+// ReorderChaptersRequest lists chapter IDs in their new display order
+type ReorderChaptersRequest struct {
+	IDs []int `json:"ids" binding:"required"`
 }
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// OAuth providers; the names are also stored in users.registration_src
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+)
+
+// OAuthStateTTL is how long a user has to come back from the provider
+const OAuthStateTTL = 10 * time.Minute
+
+// Identity is an account at an OAuth provider linked to a user (identities)
+type Identity struct {
+	ID          int64      `json:"-" db:"id"`
+	UserID      int64      `json:"-" db:"user_id"`
+	Provider    string     `json:"provider" db:"provider"`
+	Subject     string     `json:"subject" db:"subject"`
+	Email       string     `json:"email" db:"email"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at" db:"last_login_at"`
+}
+
+// ExternalUser is what a provider tells about the user who signed in
+type ExternalUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	PictureURL    string
+}
+
+// OAuthState is a login in flight between the redirect to a provider and its
+// callback (oauth_states)
+type OAuthState struct {
+	Provider     string    `db:"provider"`
+	CodeVerifier string    `db:"code_verifier"` // PKCE
+	Nonce        string    `db:"nonce"`
+	UserID       *int64    `db:"user_id"` // set when linking to a signed-in user
+	ReferralCode string    `db:"referral_code"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+// OAuthCallbackRequest carries what the provider passed back to the frontend
+type OAuthCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// OAuthStartRequest optionally carries a referral code for new accounts
+type OAuthStartRequest struct {
+	ReferralCode string `json:"referral_code"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Moderation actions; threads accept all of them, comments only delete and restore
+const (
+	ModerationPin     = "pin"
+	ModerationUnpin   = "unpin"
+	ModerationLock    = "lock"
+	ModerationUnlock  = "unlock"
+	ModerationDelete  = "delete"
+	ModerationRestore = "restore"
+)
+
+// ModerationAction is one entry of the moderation log (moderation_actions)
+type ModerationAction struct {
+	ID          int64     `json:"id" db:"id"`
+	ModeratorID *int64    `json:"moderator_id" db:"moderator_id"`
+	ThreadID    *int64    `json:"thread_id,omitempty" db:"thread_id"`
+	CommentID   *int64    `json:"comment_id,omitempty" db:"comment_id"`
+	Action      string    `json:"action" db:"action"`
+	Reason      string    `json:"reason" db:"reason"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// ModerationRequest carries the reason recorded with a moderation action
+type ModerationRequest struct {
+	Reason string `json:"reason" binding:"required,min=3,max=500"`
+}
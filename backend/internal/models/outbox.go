@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Statuses of email_outbox rows
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	// OutboxFailed rows ran out of attempts and are not retried
+	OutboxFailed = "failed"
+)
+
+// OutboxEmail is a queued email (email_outbox)
+type OutboxEmail struct {
+	ID            int64      `json:"id" db:"id"`
+	Recipient     string     `json:"recipient" db:"recipient"`
+	Subject       string     `json:"subject" db:"subject"`
+	HTMLBody      string     `json:"html_body" db:"html_body"`
+	TextBody      string     `json:"text_body" db:"text_body"`
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     string     `json:"last_error" db:"last_error"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+}
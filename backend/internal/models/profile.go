@@ -0,0 +1,97 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// AvatarURLPrefix is where uploaded avatars are served; photo_url points
+// below it after an upload
+const AvatarURLPrefix = "/api/v1/avatars/"
+
+// Avatar lists the URLs of an uploaded avatar by size in pixels
+type Avatar struct {
+	PhotoURL string         `json:"photo_url"`
+	Sizes    map[int]string `json:"sizes"`
+}
+
+// DateFormats are the accepted values of User.PreferredDateFormat
+var DateFormats = []string{"YYYY-MM-DD", "YYYY/MM/DD", "DD/MM/YYYY", "MM/DD/YYYY"}
+
+// ProfileInput is the payload of PATCH /me. Fields left out of the JSON keep
+// their current value; an empty string or a zero ID clears the field.
+type ProfileInput struct {
+	Name                *string `json:"name"`
+	Bio                 *string `json:"bio"`
+	City                *string `json:"city"`
+	JobTitle            *string `json:"job_title"`
+	PreferredTimezone   *string `json:"preferred_timezone"`
+	PreferredDateFormat *string `json:"preferred_date_format"`
+	PhotoURL            *string `json:"photo_url"`
+	LanguageID          *int64  `json:"language_id"`
+	CurrencyID          *int64  `json:"currency_id"`
+	CountryID           *int64  `json:"country_id"`
+}
+
+// ApplyTo copies the fields that are set onto u
+func (in *ProfileInput) ApplyTo(u *User) {
+	setString := func(dst **string, v *string) {
+		if v == nil {
+			return
+		}
+		if s := strings.TrimSpace(*v); s != "" {
+			*dst = &s
+		} else {
+			*dst = nil
+		}
+	}
+	setID := func(dst **int64, v *int64) {
+		if v == nil {
+			return
+		}
+		if *v != 0 {
+			id := *v
+			*dst = &id
+		} else {
+			*dst = nil
+		}
+	}
+
+	setString(&u.Name, in.Name)
+	setString(&u.Bio, in.Bio)
+	setString(&u.City, in.City)
+	setString(&u.JobTitle, in.JobTitle)
+	setString(&u.PreferredTimezone, in.PreferredTimezone)
+	setString(&u.PreferredDateFormat, in.PreferredDateFormat)
+	setString(&u.PhotoURL, in.PhotoURL)
+	setID(&u.LanguageID, in.LanguageID)
+	setID(&u.CurrencyID, in.CurrencyID)
+	setID(&u.CountryID, in.CountryID)
+}
+
+// PublicProfile is what anyone may see of a user. Add fields here with care:
+// it is served without authentication.
+type PublicProfile struct {
+	ID        int64     `json:"id"`
+	Name      *string   `json:"name"`
+	Bio       *string   `json:"bio"`
+	City      *string   `json:"city"`
+	JobTitle  *string   `json:"job_title"`
+	PhotoURL  *string   `json:"photo_url"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PublicProfile returns the whitelisted fields of u
+func (u *User) PublicProfile() *PublicProfile {
+	return &PublicProfile{
+		ID:        u.ID,
+		Name:      u.Name,
+		Bio:       u.Bio,
+		City:      u.City,
+		JobTitle:  u.JobTitle,
+		PhotoURL:  u.PhotoURL,
+		Role:      u.Role,
+		CreatedAt: u.CreatedAt,
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+// Ways to confirm it is the account owner, not just someone holding an
+// access token, before a change that could take the account over
+const (
+	// ReauthPassword applies to accounts that signed up with a password
+	ReauthPassword = "password"
+	// ReauthTwoFactor is a TOTP or recovery code, when two-factor
+	// authentication is on
+	ReauthTwoFactor = "two_factor"
+	// ReauthEmailCode is a code from POST /me/reauth/code, for accounts with
+	// neither of the above
+	ReauthEmailCode = "email_code"
+	// ReauthTelegram is a fresh Telegram login, for Telegram accounts with
+	// none of the above
+	ReauthTelegram = "telegram"
+)
+
+// ReauthRequest is embedded in the payloads of sensitive requests. Code is
+// the two-factor code when two-factor authentication is on, otherwise the
+// emailed code.
+type ReauthRequest struct {
+	Password string        `json:"password"`
+	Code     string        `json:"code"`
+	Telegram TelegramLogin `json:"telegram"`
+}
+
+// ReauthMethods answers GET /me/reauth: every method listed is required
+type ReauthMethods struct {
+	Methods []string `json:"methods"`
+}
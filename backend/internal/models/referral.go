@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Reasons for points_ledger entries
+const (
+	PointsReasonReferral = "referral"
+)
+
+// ReferralPoints is what an inviter earns when an invitee verifies their email
+const ReferralPoints = 100
+
+// MaxReferralDepth is how many levels of the invite tree are shown
+const MaxReferralDepth = 3
+
+// PointsEntry is one row of points_ledger
+type PointsEntry struct {
+	ID           int64     `json:"id" db:"id"`
+	UserID       int64     `json:"-" db:"user_id"`
+	Amount       int       `json:"amount" db:"amount"`
+	Reason       string    `json:"reason" db:"reason"`
+	SourceUserID *int64    `json:"source_user_id,omitempty" db:"source_user_id"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// Referral is a user someone invited, with the users they invited in turn.
+// Invitees are identified by ID and name only, never by email.
+type Referral struct {
+	UserID    int64       `json:"id"`
+	Name      *string     `json:"name"`
+	JoinedAt  time.Time   `json:"joined_at"`
+	Verified  bool        `json:"verified"`
+	InvitedBy int64       `json:"-"`
+	Depth     int         `json:"-"`
+	Invitees  []*Referral `json:"invitees"`
+}
+
+// ReferralOverview is the response of GET /me/referrals
+type ReferralOverview struct {
+	ReferralCode string        `json:"referral_code"`
+	Points       int           `json:"points"`
+	Invitees     []*Referral   `json:"invitees"`
+	History      []PointsEntry `json:"history"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// SearchResult is a chapter translation matching a search query
+type SearchResult struct {
+	ChapterID int     `json:"chapter_id"`
+	Number    int     `json:"number"`
+	Locale    string  `json:"locale"`
+	Title     string  `json:"title"`
+	Slug      string  `json:"slug"`
+	Snippet   string  `json:"snippet"` // HTML-escaped, matches wrapped in <mark>
+	Score     float64 `json:"score"`
+
+	Description string `json:"-"`
+	Content     string `json:"-"` // plain text, used to build the snippet
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RegistrationSrcTelegram marks users who signed up with Telegram
+const RegistrationSrcTelegram = "telegram"
+
+// placeholderEmailDomain is reserved (RFC 2606), so mail to it never leaves
+const placeholderEmailDomain = "@telegram.invalid"
+
+// TelegramUser is a verified Telegram Login Widget payload
+type TelegramUser struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Username  string `json:"username"`
+	PhotoURL  string `json:"photo_url"`
+	AuthDate  int64  `json:"auth_date"`
+}
+
+// TelegramLogin is a Login Widget payload as the widget passed it to the
+// page, before it is verified
+type TelegramLogin map[string]json.RawMessage
+
+// Fields are the values the hash covers, as Telegram wrote them: numbers keep
+// their digits, strings are unquoted
+func (l TelegramLogin) Fields() map[string]string {
+	fields := make(map[string]string, len(l))
+	for k, v := range l {
+		var s string
+		if err := json.Unmarshal(v, &s); err == nil {
+			fields[k] = s
+		} else {
+			fields[k] = strings.TrimSpace(string(v))
+		}
+	}
+	return fields
+}
+
+// DisplayName is the Telegram user's full name, or their username
+func (u *TelegramUser) DisplayName() string {
+	if name := strings.TrimSpace(u.FirstName + " " + u.LastName); name != "" {
+		return name
+	}
+	return u.Username
+}
+
+// TelegramPlaceholderEmail is the email of a user who signed up with Telegram,
+// which shares no email; users.email may not be empty
+func TelegramPlaceholderEmail(telegramID int64) string {
+	return fmt.Sprintf("telegram-%d%s", telegramID, placeholderEmailDomain)
+}
+
+// IsPlaceholderEmail reports whether email is a TelegramPlaceholderEmail or
+// a DeletedUserEmail, neither of which is ever mailed
+func IsPlaceholderEmail(email string) bool {
+	return strings.HasSuffix(email, placeholderEmailDomain) || strings.HasSuffix(email, deletedEmailDomain)
+}
@@ -13,6 +13,7 @@ type Thread struct {
 	ViewCount    int       `json:"view_count" db:"view_count"`
 	IsPinned     bool      `json:"is_pinned" db:"is_pinned"`
 	IsLocked     bool      `json:"is_locked" db:"is_locked"`
+	IsDeleted    bool      `json:"is_deleted" db:"author_deleted"` // deleted by its author; shown as a tombstone
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 	EditedAt     *time.Time `json:"edited_at,omitempty" db:"edited_at"`
@@ -23,6 +24,20 @@ type Thread struct {
 	UserReactions []string `json:"user_reactions,omitempty"` // List of reaction types user has given
 }
 
+// DeletedPlaceholder replaces the text and author of deleted threads and comments
+const DeletedPlaceholder = "[deleted]"
+
+// Redact turns a thread deleted by its author into a tombstone
+func (t *Thread) Redact() {
+	if !t.IsDeleted {
+		return
+	}
+	t.UserID = 0
+	t.Title = DeletedPlaceholder
+	t.Content = DeletedPlaceholder
+	t.Author = nil
+}
+
 // Comment represents a comment in a thread (can be nested)
 type Comment struct {
 	ID        int64     `json:"id" db:"id"`
@@ -44,6 +59,18 @@ type Comment struct {
 	Replies      []*Comment `json:"replies,omitempty"` // Nested replies
 }
 
+// Redact turns a deleted comment into a tombstone
+func (c *Comment) Redact() {
+	if !c.IsDeleted {
+		return
+	}
+	c.UserID = 0
+	c.Content = DeletedPlaceholder
+	c.Author = nil
+	c.UserVote = nil
+	c.UserReactions = nil
+}
+
 // Vote represents a vote (upvote/downvote) on a thread or comment
 type Vote struct {
 	ID        int64     `json:"id" db:"id"`
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// TwoFactorChallengeTTL is how long a login waits for its second factor
+const TwoFactorChallengeTTL = 5 * time.Minute
+
+// RecoveryCodeCount is how many recovery codes a user gets at a time
+const RecoveryCodeCount = 10
+
+// TOTP is a user's authenticator secret (user_totp)
+type TOTP struct {
+	UserID    int64      `db:"user_id"`
+	Secret    string     `db:"secret"` // base32, as shown to authenticator apps
+	LastStep  int64      `db:"last_step"`
+	CreatedAt time.Time  `db:"created_at"`
+	EnabledAt *time.Time `db:"enabled_at"`
+}
+
+// TwoFactorStatus answers GET /me/2fa
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// TwoFactorSetup is a new authenticator secret for the user to scan
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	// QRCodePNG is the otpauth URL as a base64 PNG data URL
+	QRCodePNG string `json:"qr_code_png"`
+}
+
+// TwoFactorChallenge is the answer to a correct password when the user has
+// two-factor authentication: the login finishes at /auth/2fa with a code
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// TwoFactorLoginRequest finishes a login with a TOTP or recovery code
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// TwoFactorCodeRequest carries a TOTP or recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest turns two-factor authentication off. Accounts that
+// signed up with a password must confirm it as well.
+type DisableTwoFactorRequest struct {
+	Code     string `json:"code" binding:"required"`
+	Password string `json:"password"`
+}
@@ -34,6 +34,46 @@ type User struct {
 	InvitedBy                 *int64     `json:"invited_by" db:"invited_by"`
 	Points                    int        `json:"points" db:"points"`
 	RegistrationSrc           *string    `json:"registration_src" db:"registration_src"`
+	Role                      string     `json:"role" db:"role"`
+	TokenVersion              int        `json:"-" db:"token_version"` // bumped to revoke issued tokens
+	// PasswordSet is false for Telegram, Google and GitHub sign-ups, whose
+	// random password nobody knows, until they reset it
+	PasswordSet bool `json:"-" db:"password_set"`
+	// DeletionRequestedAt is set while the account waits to be deleted (see
+	// AccountDeletionGrace)
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at,omitempty" db:"deletion_requested_at"`
+}
+
+// HasPassword reports whether the user can confirm changes with their
+// password
+func (u *User) HasPassword() bool {
+	return u.PasswordSet
+}
+
+// User roles, from least to most privileged
+const (
+	RoleReader    = "reader"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Permissions granted to roles through role_permissions
+const (
+	PermissionManageChapters      = "chapters.manage"
+	PermissionModerateDiscussions = "discussions.moderate"
+	PermissionManageUsers         = "users.manage"
+)
+
+// Role is a row of roles with the permissions it grants
+type Role struct {
+	Name        string   `json:"name" db:"name"`
+	Description string   `json:"description" db:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// SetRoleRequest is the admin payload for changing a user's role
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required"`
 }
 
 // EmailVerificationCode represents an email verification code
@@ -44,14 +84,34 @@ type EmailVerificationCode struct {
 	Email     string    `json:"email" db:"email"`
 	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
 	Used      bool      `json:"used" db:"used"`
+	Purpose   string    `json:"purpose" db:"purpose"`
+	Attempts  int       `json:"attempts" db:"attempts"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
+// Purposes of email_verification_codes; a code only works for its own purpose
+const (
+	CodePurposeVerifyEmail   = "verify_email"
+	CodePurposePasswordReset = "password_reset"
+	// CodePurposeChangeEmail codes are sent to the new address of an email change
+	CodePurposeChangeEmail = "change_email"
+	// CodePurposeReauth codes confirm a sensitive change (ReauthEmailCode)
+	CodePurposeReauth = "reauth"
+)
+
+// MaxCodeAttempts is how many wrong guesses invalidate a user's active codes
+const MaxCodeAttempts = 5
+
+// CodeTTL is how long an emailed code stays valid
+const CodeTTL = 15 * time.Minute
+
 // RegisterRequest represents a registration request
 type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
 	Name     string `json:"name"`
+	// ReferralCode is the inviting user's code, optional
+	ReferralCode string `json:"referral_code"`
 }
 
 // LoginRequest represents a login request
@@ -66,8 +126,34 @@ type VerifyEmailRequest struct {
 	Code  string `json:"code" binding:"required,len=5"`
 }
 
+// ForgotPasswordRequest starts a password reset
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password using an emailed reset code
+type ResetPasswordRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Code     string `json:"code" binding:"required,len=5"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// ChangeEmailRequest starts an email change; see ReauthMethods for what it
+// must carry besides the email
+type ChangeEmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	ReauthRequest
+}
+
+// ConfirmEmailChangeRequest finishes an email change with the code sent to
+// the new address
+type ConfirmEmailChangeRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Code  string `json:"code" binding:"required,len=5"`
+}
+
 // AuthResponse represents an authentication response
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  *User  `json:"user"`
+	TokenPair
+	User *User `json:"user"`
 }
@@ -0,0 +1,318 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+)
+
+// AccountRepository gathers a user's data for export and carries out
+// account deletion
+type AccountRepository interface {
+	// Export collects everything a user wrote or saved; the profile is
+	// filled in by the caller
+	Export(userID int64) (*models.AccountExport, error)
+	// RequestDeletion marks an account for deletion at requestedAt; a
+	// pending request keeps its original time
+	RequestDeletion(userID int64, requestedAt time.Time) error
+	// CancelDeletion drops a pending request; ErrNotFound when there is none
+	CancelDeletion(userID int64) error
+	// DueForDeletion lists the users whose deletion was requested before
+	// cutoff and who are not anonymized yet
+	DueForDeletion(cutoff time.Time) ([]int64, error)
+	// Anonymize erases a user's personal data, including the emails queued or
+	// sent to them, and signs them out for good. The row stays, so their
+	// threads and comments survive under a placeholder author.
+	Anonymize(userID int64) error
+}
+
+type accountRepository struct {
+	db *dialectDB
+}
+
+func NewAccountRepository(db *sql.DB) AccountRepository {
+	return &accountRepository{db: newDialectDB(db)}
+}
+
+func (r *accountRepository) Export(userID int64) (*models.AccountExport, error) {
+	export := &models.AccountExport{}
+	var err error
+	if export.Threads, err = r.exportThreads(userID); err != nil {
+		return nil, err
+	}
+	if export.Comments, err = r.exportComments(userID); err != nil {
+		return nil, err
+	}
+	if export.Votes, err = r.exportVotes(userID); err != nil {
+		return nil, err
+	}
+	if export.Reactions, err = r.exportReactions(userID); err != nil {
+		return nil, err
+	}
+	if export.Drafts, err = r.exportDrafts(userID); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (r *accountRepository) exportThreads(userID int64) ([]*models.Thread, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, title, content, score, comment_count, view_count, is_pinned, is_locked,
+		       author_deleted, created_at, updated_at, edited_at
+		FROM threads WHERE user_id = ? ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export threads: %w", err)
+	}
+	defer rows.Close()
+
+	threads := []*models.Thread{}
+	for rows.Next() {
+		var t models.Thread
+		var editedAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Title, &t.Content, &t.Score, &t.CommentCount, &t.ViewCount,
+			&t.IsPinned, &t.IsLocked, &t.IsDeleted, &t.CreatedAt, &t.UpdatedAt, &editedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan thread: %w", err)
+		}
+		if editedAt.Valid {
+			t.EditedAt = &editedAt.Time
+		}
+		threads = append(threads, &t)
+	}
+	return threads, rows.Err()
+}
+
+func (r *accountRepository) exportComments(userID int64) ([]*models.Comment, error) {
+	rows, err := r.db.Query(`
+		SELECT id, thread_id, user_id, parent_id, content, score, depth, is_deleted, created_at, updated_at, edited_at
+		FROM comments WHERE user_id = ? ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []*models.Comment{}
+	for rows.Next() {
+		var c models.Comment
+		var parentID sql.NullInt64
+		var editedAt sql.NullTime
+		if err := rows.Scan(&c.ID, &c.ThreadID, &c.UserID, &parentID, &c.Content, &c.Score, &c.Depth,
+			&c.IsDeleted, &c.CreatedAt, &c.UpdatedAt, &editedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		c.ParentID = nullInt64Ptr(parentID)
+		if editedAt.Valid {
+			c.EditedAt = &editedAt.Time
+		}
+		comments = append(comments, &c)
+	}
+	return comments, rows.Err()
+}
+
+func (r *accountRepository) exportVotes(userID int64) ([]*models.Vote, error) {
+	rows, err := r.db.Query(
+		"SELECT id, user_id, thread_id, comment_id, vote_type, created_at FROM votes WHERE user_id = ? ORDER BY id", userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export votes: %w", err)
+	}
+	defer rows.Close()
+
+	votes := []*models.Vote{}
+	for rows.Next() {
+		var v models.Vote
+		var threadID, commentID sql.NullInt64
+		if err := rows.Scan(&v.ID, &v.UserID, &threadID, &commentID, &v.VoteType, &v.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan vote: %w", err)
+		}
+		v.ThreadID, v.CommentID = nullInt64Ptr(threadID), nullInt64Ptr(commentID)
+		votes = append(votes, &v)
+	}
+	return votes, rows.Err()
+}
+
+func (r *accountRepository) exportReactions(userID int64) ([]*models.Reaction, error) {
+	rows, err := r.db.Query(
+		"SELECT id, user_id, thread_id, comment_id, reaction_type, created_at FROM reactions WHERE user_id = ? ORDER BY id", userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export reactions: %w", err)
+	}
+	defer rows.Close()
+
+	reactions := []*models.Reaction{}
+	for rows.Next() {
+		var re models.Reaction
+		var threadID, commentID sql.NullInt64
+		if err := rows.Scan(&re.ID, &re.UserID, &threadID, &commentID, &re.ReactionType, &re.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction: %w", err)
+		}
+		re.ThreadID, re.CommentID = nullInt64Ptr(threadID), nullInt64Ptr(commentID)
+		reactions = append(reactions, &re)
+	}
+	return reactions, rows.Err()
+}
+
+func (r *accountRepository) exportDrafts(userID int64) ([]*models.ThreadDraft, error) {
+	rows, err := r.db.Query(
+		"SELECT id, user_id, title, content, updated_at FROM thread_drafts WHERE user_id = ? ORDER BY id", userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export drafts: %w", err)
+	}
+	defer rows.Close()
+
+	drafts := []*models.ThreadDraft{}
+	for rows.Next() {
+		var d models.ThreadDraft
+		var title, content sql.NullString
+		if err := rows.Scan(&d.ID, &d.UserID, &title, &content, &d.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan draft: %w", err)
+		}
+		if title.Valid {
+			d.Title = &title.String
+		}
+		if content.Valid {
+			d.Content = &content.String
+		}
+		drafts = append(drafts, &d)
+	}
+	return drafts, rows.Err()
+}
+
+func nullInt64Ptr(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int64
+}
+
+func (r *accountRepository) RequestDeletion(userID int64, requestedAt time.Time) error {
+	result, err := r.db.Exec(`
+		UPDATE users SET deletion_requested_at = COALESCE(deletion_requested_at, ?)
+		WHERE id = ? AND deleted_at IS NULL
+	`, requestedAt.UTC(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to request account deletion: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("user %w", ErrNotFound)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *accountRepository) CancelDeletion(userID int64) error {
+	result, err := r.db.Exec(`
+		UPDATE users SET deletion_requested_at = NULL
+		WHERE id = ? AND deletion_requested_at IS NOT NULL AND deleted_at IS NULL
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel account deletion: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("deletion request %w", ErrNotFound)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *accountRepository) DueForDeletion(cutoff time.Time) ([]int64, error) {
+	rows, err := r.db.Query(`
+		SELECT id FROM users
+		WHERE deletion_requested_at IS NOT NULL AND deletion_requested_at <= ? AND deleted_at IS NULL
+		ORDER BY id
+	`, cutoff.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts due for deletion: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// personalTables hold nothing but a user's own logins and unpublished data;
+// Anonymize empties them
+var personalTables = []string{
+	"thread_drafts",
+	"sessions", // refresh_tokens cascade
+	"identities",
+	"oauth_states",
+	"user_totp",
+	"recovery_codes",
+	"two_factor_challenges",
+	"email_verification_codes",
+}
+
+func (r *accountRepository) Anonymize(userID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Outgoing email keeps the address and the whole message. Addresses the
+	// user had codes for, such as a pending email change, go too, unless
+	// another account uses them now. Runs before the codes are deleted.
+	if _, err := tx.Exec(r.db.dialect.Rebind(`
+		DELETE FROM email_outbox
+		WHERE recipient IN (
+			SELECT email FROM users WHERE id = ?
+			UNION SELECT email FROM email_verification_codes WHERE user_id = ?
+		) AND recipient NOT IN (SELECT email FROM users WHERE id <> ?)
+	`), userID, userID, userID); err != nil {
+		return fmt.Errorf("failed to delete email_outbox: %w", err)
+	}
+
+	now := time.Now().UTC()
+	// An empty password hash matches no password, and a lowercase referral
+	// code matches no NormalizeReferralCode input. Bumping token_version
+	// rejects every token issued so far.
+	result, err := tx.Exec(r.db.dialect.Rebind(`
+		UPDATE users SET
+			email = ?, password = '', name = ?, telegram_id = NULL,
+			language_id = NULL, currency_id = NULL, country_id = NULL,
+			preferred_date_format = NULL, preferred_timezone = NULL,
+			number_format_preference = NULL, currency_display_preference = NULL,
+			city = NULL, address = NULL, job_title = NULL, bio = NULL, mobile = NULL, phone = NULL,
+			birthdate = NULL, email_verified_at = NULL, mobile_verified_at = NULL, phone_verified_at = NULL,
+			photo_url = NULL, referral_code = ?, registration_src = NULL, is_active = FALSE, role = ?,
+			token_version = token_version + 1, deleted_at = ?, updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`), models.DeletedUserEmail(userID), models.DeletedPlaceholder, fmt.Sprintf("deleted-%d", userID),
+		models.RoleReader, now, now, userID)
+	if err != nil {
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("user %w", ErrNotFound)
+	}
+
+	for _, table := range personalTables {
+		if _, err := tx.Exec(r.db.dialect.Rebind("DELETE FROM "+table+" WHERE user_id = ?"), userID); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit account deletion: %w", err)
+	}
+	r.db.checkpoint()
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+)
+
+type AuditRepository interface {
+	Record(entry *models.AuditEntry) error
+	// List returns the most recent entries first; event narrows it to one event
+	List(event string, limit int) ([]models.AuditEntry, error)
+}
+
+type auditRepository struct {
+	db *dialectDB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: newDialectDB(db)}
+}
+
+func (r *auditRepository) Record(entry *models.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (event, user_id, subject, ip_address, details, created_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		RETURNING id, created_at
+	`
+	var createdAt sql.NullTime
+	err := r.db.QueryRow(query, entry.Event, entry.UserID, entry.Subject, entry.IPAddress, entry.Details).
+		Scan(&entry.ID, &createdAt)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	entry.CreatedAt = createdAt.Time
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *auditRepository) List(event string, limit int) ([]models.AuditEntry, error) {
+	query := "SELECT id, event, user_id, subject, ip_address, details, created_at FROM audit_log"
+	var args []interface{}
+	if event != "" {
+		query += " WHERE event = ?"
+		args = append(args, event)
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var userID sql.NullInt64
+		var createdAt sql.NullTime
+		if err := rows.Scan(&e.ID, &e.Event, &userID, &e.Subject, &e.IPAddress, &e.Details, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if userID.Valid {
+			e.UserID = &userID.Int64
+		}
+		e.CreatedAt = createdAt.Time
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
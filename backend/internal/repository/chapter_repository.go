@@ -11,19 +11,30 @@ type ChapterRepository interface {
 	GetAll() ([]models.ChapterSummary, error)
 	GetByID(id int) (*models.Chapter, error)
 	GetBySlug(slug string) (*models.Chapter, error)
+	GetTranslations(chapterID int) ([]models.ChapterTranslation, error)
+	ListTranslationSummaries() ([]models.ChapterTranslation, error)
+	GetTranslationBySlug(locale, slug string) (*models.ChapterTranslation, error)
+	GetRedirectSlug(locale, oldSlug string) (string, error)
+
+	Create(chapter *models.Chapter) error
+	Update(chapter *models.Chapter) error
+	Delete(id int) error
+	Reorder(ids []int) error
+	UpsertTranslation(translation *models.ChapterTranslation) error
+	DeleteTranslation(chapterID int, locale string) error
 }
 
 type chapterRepository struct {
-	db *sql.DB
+	db *dialectDB
 }
 
 func NewChapterRepository(db *sql.DB) ChapterRepository {
-	return &chapterRepository{db: db}
+	return &chapterRepository{db: newDialectDB(db)}
 }
 
 func (r *chapterRepository) GetAll() ([]models.ChapterSummary, error) {
 	query := `
-		SELECT id, number, title, slug, description, icon, pages, read_time, featured
+		SELECT id, number, title, slug, description, COALESCE(icon, '') as icon, pages, read_time, featured
 		FROM chapters
 		ORDER BY "order" ASC
 	`
@@ -59,7 +70,7 @@ func (r *chapterRepository) GetAll() ([]models.ChapterSummary, error) {
 
 func (r *chapterRepository) GetByID(id int) (*models.Chapter, error) {
 	query := `
-		SELECT id, number, title, slug, description, content, COALESCE(content_en, '') as content_en, icon, pages, read_time, featured, "order", created_at, updated_at
+		SELECT id, number, title, slug, description, COALESCE(content, '') as content, COALESCE(content_en, '') as content_en, COALESCE(icon, '') as icon, pages, read_time, featured, "order", created_at, updated_at
 		FROM chapters
 		WHERE id = ?
 	`
@@ -82,7 +93,7 @@ func (r *chapterRepository) GetByID(id int) (*models.Chapter, error) {
 		&c.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("chapter not found")
+		return nil, fmt.Errorf("chapter %w", ErrNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chapter: %w", err)
@@ -93,7 +104,7 @@ func (r *chapterRepository) GetByID(id int) (*models.Chapter, error) {
 
 func (r *chapterRepository) GetBySlug(slug string) (*models.Chapter, error) {
 	query := `
-		SELECT id, number, title, slug, description, content, COALESCE(content_en, '') as content_en, icon, pages, read_time, featured, "order", created_at, updated_at
+		SELECT id, number, title, slug, description, COALESCE(content, '') as content, COALESCE(content_en, '') as content_en, COALESCE(icon, '') as icon, pages, read_time, featured, "order", created_at, updated_at
 		FROM chapters
 		WHERE slug = ?
 	`
@@ -116,7 +127,7 @@ func (r *chapterRepository) GetBySlug(slug string) (*models.Chapter, error) {
 		&c.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("chapter not found")
+		return nil, fmt.Errorf("chapter %w", ErrNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chapter: %w", err)
@@ -124,3 +135,224 @@ func (r *chapterRepository) GetBySlug(slug string) (*models.Chapter, error) {
 
 	return &c, nil
 }
+
+// GetTranslations returns every translation of a chapter, ordered by locale
+func (r *chapterRepository) GetTranslations(chapterID int) ([]models.ChapterTranslation, error) {
+	query := `
+		SELECT id, chapter_id, locale, title, slug, description, COALESCE(content, '') as content
+		FROM chapter_translations
+		WHERE chapter_id = ?
+		ORDER BY locale ASC
+	`
+
+	rows, err := r.db.Query(query, chapterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chapter translations: %w", err)
+	}
+	defer rows.Close()
+
+	var translations []models.ChapterTranslation
+	for rows.Next() {
+		var t models.ChapterTranslation
+		if err := rows.Scan(&t.ID, &t.ChapterID, &t.Locale, &t.Title, &t.Slug, &t.Description, &t.Content); err != nil {
+			return nil, fmt.Errorf("failed to scan chapter translation: %w", err)
+		}
+		translations = append(translations, t)
+	}
+
+	return translations, nil
+}
+
+// ListTranslationSummaries returns the translations of all chapters without their content
+func (r *chapterRepository) ListTranslationSummaries() ([]models.ChapterTranslation, error) {
+	query := `
+		SELECT id, chapter_id, locale, title, slug, description
+		FROM chapter_translations
+		ORDER BY chapter_id ASC, locale ASC
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chapter translations: %w", err)
+	}
+	defer rows.Close()
+
+	var translations []models.ChapterTranslation
+	for rows.Next() {
+		var t models.ChapterTranslation
+		if err := rows.Scan(&t.ID, &t.ChapterID, &t.Locale, &t.Title, &t.Slug, &t.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan chapter translation: %w", err)
+		}
+		translations = append(translations, t)
+	}
+
+	return translations, nil
+}
+
+func (r *chapterRepository) GetTranslationBySlug(locale, slug string) (*models.ChapterTranslation, error) {
+	query := `
+		SELECT id, chapter_id, locale, title, slug, description, COALESCE(content, '') as content
+		FROM chapter_translations
+		WHERE locale = ? AND slug = ?
+	`
+
+	var t models.ChapterTranslation
+	err := r.db.QueryRow(query, locale, slug).Scan(&t.ID, &t.ChapterID, &t.Locale, &t.Title, &t.Slug, &t.Description, &t.Content)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("chapter translation %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chapter translation: %w", err)
+	}
+
+	return &t, nil
+}
+
+// GetRedirectSlug looks up a former slug in chapter_slug_history and returns the
+// current slug of the same chapter in the same locale
+func (r *chapterRepository) GetRedirectSlug(locale, oldSlug string) (string, error) {
+	query := `
+		SELECT ct.slug
+		FROM chapter_slug_history h
+		JOIN chapter_translations ct ON ct.chapter_id = h.chapter_id AND ct.locale = h.locale
+		WHERE h.locale = ? AND h.slug = ?
+	`
+
+	var slug string
+	err := r.db.QueryRow(query, locale, oldSlug).Scan(&slug)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("chapter slug %w", ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get chapter slug history: %w", err)
+	}
+
+	return slug, nil
+}
+
+func (r *chapterRepository) Create(chapter *models.Chapter) error {
+	query := `
+		INSERT INTO chapters (number, title, slug, description, icon, pages, read_time, featured, "order", created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id
+	`
+
+	err := r.db.QueryRow(query,
+		chapter.Number, chapter.Title, chapter.Slug, chapter.Description, chapter.Icon,
+		chapter.Pages, chapter.ReadTime, chapter.Featured, chapter.Order,
+	).Scan(&chapter.ID)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("chapter number or slug %w", ErrDuplicate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create chapter: %w", err)
+	}
+
+	r.db.checkpoint()
+	return nil
+}
+
+func (r *chapterRepository) Update(chapter *models.Chapter) error {
+	query := `
+		UPDATE chapters
+		SET number = ?, title = ?, slug = ?, description = ?, icon = ?, pages = ?, read_time = ?,
+			featured = ?, "order" = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+
+	result, err := r.db.Exec(query,
+		chapter.Number, chapter.Title, chapter.Slug, chapter.Description, chapter.Icon,
+		chapter.Pages, chapter.ReadTime, chapter.Featured, chapter.Order, chapter.ID,
+	)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("chapter number or slug %w", ErrDuplicate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update chapter: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("chapter %w", ErrNotFound)
+	}
+
+	r.db.checkpoint()
+	return nil
+}
+
+// Delete removes a chapter; its translations and slug history cascade
+func (r *chapterRepository) Delete(id int) error {
+	result, err := r.db.Exec("DELETE FROM chapters WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete chapter: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("chapter %w", ErrNotFound)
+	}
+
+	r.db.checkpoint()
+	return nil
+}
+
+// Reorder sets "order" to each chapter's position in ids (starting at 1)
+func (r *chapterRepository) Reorder(ids []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i, id := range ids {
+		result, err := tx.Exec(r.db.dialect.Rebind(`UPDATE chapters SET "order" = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`), i+1, id)
+		if err != nil {
+			return fmt.Errorf("failed to reorder chapters: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("chapter %d %w", id, ErrNotFound)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to reorder chapters: %w", err)
+	}
+
+	r.db.checkpoint()
+	return nil
+}
+
+// UpsertTranslation creates or replaces the translation for (chapter_id, locale)
+func (r *chapterRepository) UpsertTranslation(t *models.ChapterTranslation) error {
+	query := `
+		INSERT INTO chapter_translations (chapter_id, locale, title, slug, description, content, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (chapter_id, locale) DO UPDATE SET
+			title = EXCLUDED.title,
+			slug = EXCLUDED.slug,
+			description = EXCLUDED.description,
+			content = EXCLUDED.content,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING id
+	`
+
+	err := r.db.QueryRow(query, t.ChapterID, t.Locale, t.Title, t.Slug, t.Description, t.Content).Scan(&t.ID)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("slug %q for locale %s %w", t.Slug, t.Locale, ErrDuplicate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save chapter translation: %w", err)
+	}
+
+	r.db.checkpoint()
+	return nil
+}
+
+func (r *chapterRepository) DeleteTranslation(chapterID int, locale string) error {
+	result, err := r.db.Exec("DELETE FROM chapter_translations WHERE chapter_id = ? AND locale = ?", chapterID, locale)
+	if err != nil {
+		return fmt.Errorf("failed to delete chapter translation: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("chapter translation %w", ErrNotFound)
+	}
+
+	r.db.checkpoint()
+	return nil
+}
@@ -2,7 +2,6 @@ package repository
 
 import (
 	"database/sql"
-	"os"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -24,6 +23,7 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 			slug TEXT NOT NULL UNIQUE,
 			description TEXT NOT NULL,
 			content TEXT,
+			content_en TEXT,
 			icon TEXT,
 			pages INTEGER DEFAULT 0,
 			read_time INTEGER DEFAULT 0,
@@ -98,3 +98,21 @@ func TestChapterRepository_GetBySlug(t *testing.T) {
 	assert.Equal(t, "test-chapter", chapter.Slug)
 }
 
+
+func TestChapterRepository_Translations(t *testing.T) {
+	db := setupMigratedDB(t)
+	repo := NewChapterRepository(db)
+
+	translations, err := repo.GetTranslations(1)
+	require.NoError(t, err)
+	locales := map[string]string{}
+	for _, tr := range translations {
+		locales[tr.Locale] = tr.Content
+	}
+	assert.NotEmpty(t, locales["fa"])
+
+	summaries, err := repo.ListTranslationSummaries()
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(summaries), 10)
+	assert.Empty(t, summaries[0].Content)
+}
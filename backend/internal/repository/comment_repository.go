@@ -12,6 +12,8 @@ type CommentRepository interface {
 	GetByThreadID(threadID int64, userID *int64) ([]*models.Comment, error)
 	GetByID(id int64, userID *int64) (*models.Comment, error)
 	Update(comment *models.Comment) error
+	// Delete marks a comment deleted by its author
+	Delete(id, userID int64) error
 	GetUserVote(commentID, userID int64) (*int, error)
 	GetUserReactions(commentID, userID int64) ([]string, error)
 	GetReactionSummary(commentID int64) ([]*models.ReactionSummary, error)
@@ -19,11 +21,11 @@ type CommentRepository interface {
 }
 
 type commentRepository struct {
-	db *sql.DB
+	db *dialectDB
 }
 
 func NewCommentRepository(db *sql.DB) CommentRepository {
-	return &commentRepository{db: db}
+	return &commentRepository{db: newDialectDB(db)}
 }
 
 func (r *commentRepository) Create(comment *models.Comment) error {
@@ -40,7 +42,7 @@ func (r *commentRepository) Create(comment *models.Comment) error {
 	
 	query := `
 		INSERT INTO comments (thread_id, user_id, parent_id, content, score, depth, is_deleted, created_at, updated_at)
-		VALUES (?, ?, ?, ?, 0, ?, 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		VALUES (?, ?, ?, ?, 0, ?, FALSE, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		RETURNING id, created_at, updated_at
 	`
 	
@@ -79,8 +81,8 @@ func (r *commentRepository) GetByThreadID(threadID int64, userID *int64) ([]*mod
 		       u.id, u.email, u.name, u.email_verified_at, u.photo_url, u.created_at
 		FROM comments c
 		LEFT JOIN users u ON c.user_id = u.id
-		WHERE c.thread_id = ? AND c.is_deleted = 0
-		ORDER BY c.created_at ASC
+		WHERE c.thread_id = ?
+		ORDER BY c.created_at ASC, c.id ASC
 	`
 	
 	rows, err := r.db.Query(query, threadID)
@@ -154,7 +156,32 @@ func (r *commentRepository) GetByThreadID(threadID int64, userID *int64) ([]*mod
 		comments = append(comments, comment)
 	}
 	
-	return comments, nil
+	return visibleComments(comments), nil
+}
+
+// visibleComments drops deleted comments that have no live replies and turns
+// the remaining deleted ones into tombstones, so replies keep their place in
+// the tree. comments must be in creation order (replies after their parent).
+func visibleComments(comments []*models.Comment) []*models.Comment {
+	keep := make(map[int64]bool, len(comments))
+	for i := len(comments) - 1; i >= 0; i-- {
+		comment := comments[i]
+		if !comment.IsDeleted || keep[comment.ID] {
+			keep[comment.ID] = true
+			if comment.ParentID != nil {
+				keep[*comment.ParentID] = true
+			}
+		}
+	}
+	
+	var visible []*models.Comment
+	for _, comment := range comments {
+		if keep[comment.ID] {
+			comment.Redact()
+			visible = append(visible, comment)
+		}
+	}
+	return visible
 }
 
 func (r *commentRepository) GetByID(id int64, userID *int64) (*models.Comment, error) {
@@ -185,7 +212,7 @@ func (r *commentRepository) GetByID(id int64, userID *int64) (*models.Comment, e
 	
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("comment not found")
+			return nil, fmt.Errorf("comment %w", ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get comment: %w", err)
 	}
@@ -235,7 +262,7 @@ func (r *commentRepository) Update(comment *models.Comment) error {
 	query := `
 		UPDATE comments 
 		SET content = ?, edited_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ? AND user_id = ?
+		WHERE id = ? AND user_id = ? AND is_deleted = FALSE
 	`
 	
 	result, err := r.db.Exec(query, comment.Content, comment.ID, comment.UserID)
@@ -255,6 +282,31 @@ func (r *commentRepository) Update(comment *models.Comment) error {
 	return nil
 }
 
+func (r *commentRepository) Delete(id, userID int64) error {
+	// The comment_count triggers recount the thread. author_deleted keeps a
+	// moderator restore from bringing it back.
+	result, err := r.db.Exec(
+		"UPDATE comments SET is_deleted = TRUE, author_deleted = TRUE WHERE id = ? AND user_id = ? AND is_deleted = FALSE",
+		id, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	
+	if rowsAffected == 0 {
+		return fmt.Errorf("comment not found or user not authorized")
+	}
+	
+	r.db.checkpoint()
+	
+	return nil
+}
+
 func (r *commentRepository) GetUserVote(commentID, userID int64) (*int, error) {
 	var voteType sql.NullInt64
 	err := r.db.QueryRow(
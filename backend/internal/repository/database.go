@@ -3,8 +3,11 @@ package repository
 import (
 	"database/sql"
 	"fmt"
-	"strings"
+	"net"
+	"net/url"
+	"time"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/whatisrealfreedom/freedom-website/internal/config"
 )
@@ -13,20 +16,42 @@ import (
 type Database interface {
 	Close() error
 	GetDB() *sql.DB
+	Dialect() Dialect
 }
 
 type database struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect Dialect
 }
 
-// NewDatabase creates a database connection based on config
+// NewDatabase creates a database connection based on config and, unless
+// AUTO_MIGRATE is disabled, applies pending migrations
 func NewDatabase(cfg *config.Config) (Database, error) {
+	d, err := OpenDatabase(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.AutoMigrate {
+		err = RunMigrationsOnDB(d.GetDB(), d.Dialect())
+	} else {
+		err = checkMigrationsOnDB(d.GetDB(), d.Dialect())
+	}
+	if err != nil {
+		d.Close()
+		return nil, fmt.Errorf("failed to run %s migrations: %w", d.Dialect(), err)
+	}
+
+	return d, nil
+}
+
+// OpenDatabase connects to the configured database without touching its schema
+func OpenDatabase(cfg *config.Config) (Database, error) {
 	switch cfg.DBType {
 	case "sqlite":
 		return newSQLiteDB(cfg)
 	case "postgres":
-		// TODO: implement PostgreSQL
-		return nil, fmt.Errorf("postgres not yet implemented")
+		return newPostgresDB(cfg)
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", cfg.DBType)
 	}
@@ -55,22 +80,40 @@ func newSQLiteDB(cfg *config.Config) (Database, error) {
 		return nil, fmt.Errorf("failed to set SQLite pragmas: %w", err)
 	}
 
-	// Run migrations after connection is established
-	// Use a transaction to ensure migrations are atomic where possible
-	if err := RunMigrationsOnDB(db); err != nil {
-		// Log the error but don't fail completely - allow server to start
-		// This prevents 502 errors when migrations have minor issues
-		fmt.Printf("⚠️  Migration warning (server will continue): %v\n", err)
-		// Only fail on critical errors (database connection issues, not migration syntax)
-		if strings.Contains(strings.ToLower(err.Error()), "database") && 
-		   strings.Contains(strings.ToLower(err.Error()), "locked") {
-			return nil, fmt.Errorf("database is locked, please try again: %w", err)
-		}
-		// For other migration errors, log but continue
-		fmt.Printf("ℹ️  Continuing despite migration warning. Some features may not work correctly.\n")
+	return &database{db: db, dialect: DialectSQLite}, nil
+}
+
+// PostgresDSN builds a lib/pq connection URL from the DB_* settings
+func PostgresDSN(cfg *config.Config) string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(cfg.DBUser, cfg.DBPassword),
+		Host:   net.JoinHostPort(cfg.DBHost, cfg.DBPort),
+		Path:   "/" + cfg.DBName,
+	}
+	q := url.Values{}
+	q.Set("sslmode", cfg.DBSSLMode)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func newPostgresDB(cfg *config.Config) (Database, error) {
+	db, err := sql.Open("postgres", PostgresDSN(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres database: %w", err)
 	}
 
-	return &database{db: db}, nil
+	// Unlike SQLite, Postgres handles concurrent writers, so allow a real pool
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(30 * time.Minute)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return &database{db: db, dialect: DialectPostgres}, nil
 }
 
 func (d *database) Close() error {
@@ -80,3 +123,7 @@ func (d *database) Close() error {
 func (d *database) GetDB() *sql.DB {
 	return d.db
 }
+
+func (d *database) Dialect() Dialect {
+	return d.dialect
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	// ErrNotFound is wrapped by lookups that match no row, e.g. "chapter not found"
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is wrapped when a write violates a unique constraint
+	ErrDuplicate = errors.New("already exists")
+)
+
+// isUniqueViolation reports whether err is a unique constraint failure on either dialect
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	return false
+}
+
+// Dialect identifies the SQL flavour spoken by the underlying database
+type Dialect string
+
+const (
+	DialectSQLite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+)
+
+// Rebind converts the ?-style placeholders used throughout the repositories
+// into the bind syntax of the dialect. Question marks inside string literals,
+// quoted identifiers and comments are left untouched.
+func (d Dialect) Rebind(query string) string {
+	if d != DialectPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+
+	n := 0
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"':
+			// Copy the quoted section verbatim (doubled quotes are escapes)
+			end := i + 1
+			for end < len(query) {
+				if query[end] == ch {
+					if end+1 < len(query) && query[end+1] == ch {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			if end >= len(query) {
+				end = len(query) - 1
+			}
+			b.WriteString(query[i : end+1])
+			i = end
+		case ch == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				b.WriteString(query[i:])
+				i = len(query)
+				continue
+			}
+			b.WriteString(query[i : i+end+1])
+			i += end
+		case ch == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+		default:
+			b.WriteByte(ch)
+		}
+	}
+
+	return b.String()
+}
+
+// detectDialect inspects the driver behind a connection pool
+func detectDialect(db *sql.DB) Dialect {
+	if _, ok := db.Driver().(*pq.Driver); ok {
+		return DialectPostgres
+	}
+	return DialectSQLite
+}
+
+// dialectDB wraps *sql.DB so repositories can keep writing ?-placeholders
+// and let the wrapper translate them for the active dialect
+type dialectDB struct {
+	*sql.DB
+	dialect Dialect
+}
+
+func newDialectDB(db *sql.DB) *dialectDB {
+	return &dialectDB{DB: db, dialect: detectDialect(db)}
+}
+
+func (d *dialectDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return d.DB.Exec(d.dialect.Rebind(query), args...)
+}
+
+func (d *dialectDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return d.DB.Query(d.dialect.Rebind(query), args...)
+}
+
+func (d *dialectDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return d.DB.QueryRow(d.dialect.Rebind(query), args...)
+}
+
+// checkpoint forces a WAL checkpoint on SQLite so writes are persisted to the
+// main database file. Other dialects handle durability themselves.
+func (d *dialectDB) checkpoint() {
+	if d.dialect == DialectSQLite {
+		_, _ = d.DB.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	}
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialect_Rebind_SQLiteUnchanged(t *testing.T) {
+	query := "SELECT * FROM users WHERE email = ? AND id = ?"
+	assert.Equal(t, query, DialectSQLite.Rebind(query))
+}
+
+func TestDialect_Rebind_Postgres(t *testing.T) {
+	query := "UPDATE votes SET vote_type = ? WHERE user_id = ? AND (thread_id = ? OR comment_id = ?)"
+	assert.Equal(t,
+		"UPDATE votes SET vote_type = $1 WHERE user_id = $2 AND (thread_id = $3 OR comment_id = $4)",
+		DialectPostgres.Rebind(query),
+	)
+}
+
+func TestDialect_Rebind_PostgresSkipsQuotedAndComments(t *testing.T) {
+	query := `SELECT 'what?', "odd?col" -- really?
+		FROM t WHERE a = ? AND b = 'it''s ?' AND c = ?`
+	assert.Equal(t, `SELECT 'what?', "odd?col" -- really?
+		FROM t WHERE a = $1 AND b = 'it''s ?' AND c = $2`,
+		DialectPostgres.Rebind(query),
+	)
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+)
+
+// IdentityRepository stores the OAuth accounts linked to users and the OAuth
+// logins in flight
+type IdentityRepository interface {
+	// Create links an identity. A provider account linked to any user, or a
+	// second account of the same provider for a user, is ErrDuplicate.
+	Create(identity *models.Identity) error
+	Get(provider, subject string) (*models.Identity, error)
+	// List returns the identities of a user, oldest first
+	List(userID int64) ([]models.Identity, error)
+	// Delete unlinks the identity of provider from a user
+	Delete(userID int64, provider string) error
+	TouchLogin(id int64) error
+
+	// SaveState stores a login in flight under the hash of its state
+	SaveState(stateHash string, state *models.OAuthState) error
+	// TakeState returns and deletes a stored login, so each state works
+	// once. Unknown and expired states wrap ErrNotFound.
+	TakeState(stateHash string) (*models.OAuthState, error)
+	DeleteExpiredStates() error
+}
+
+type identityRepository struct {
+	db *dialectDB
+}
+
+func NewIdentityRepository(db *sql.DB) IdentityRepository {
+	return &identityRepository{db: newDialectDB(db)}
+}
+
+func (r *identityRepository) Create(identity *models.Identity) error {
+	identity.CreatedAt = time.Now().UTC()
+
+	err := r.db.QueryRow(`
+		INSERT INTO identities (user_id, provider, subject, email, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id
+	`, identity.UserID, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt).Scan(&identity.ID)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("identity %w", ErrDuplicate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create identity: %w", err)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *identityRepository) Get(provider, subject string) (*models.Identity, error) {
+	var identity models.Identity
+	err := r.db.QueryRow(`
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM identities
+		WHERE provider = ? AND subject = ?
+	`, provider, subject).Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email,
+		&identity.CreatedAt, &identity.LastLoginAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("identity %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+	return &identity, nil
+}
+
+func (r *identityRepository) List(userID int64) ([]models.Identity, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM identities
+		WHERE user_id = ?
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identities: %w", err)
+	}
+	defer rows.Close()
+
+	identities := []models.Identity{}
+	for rows.Next() {
+		var identity models.Identity
+		if err := rows.Scan(
+			&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email,
+			&identity.CreatedAt, &identity.LastLoginAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan identity: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, rows.Err()
+}
+
+func (r *identityRepository) Delete(userID int64, provider string) error {
+	result, err := r.db.Exec("DELETE FROM identities WHERE user_id = ? AND provider = ?", userID, provider)
+	if err != nil {
+		return fmt.Errorf("failed to delete identity: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("identity %w", ErrNotFound)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *identityRepository) TouchLogin(id int64) error {
+	if _, err := r.db.Exec("UPDATE identities SET last_login_at = ? WHERE id = ?", time.Now().UTC(), id); err != nil {
+		return fmt.Errorf("failed to update identity: %w", err)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *identityRepository) SaveState(stateHash string, state *models.OAuthState) error {
+	_, err := r.db.Exec(`
+		INSERT INTO oauth_states (state_hash, provider, code_verifier, nonce, user_id, referral_code, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, stateHash, state.Provider, state.CodeVerifier, state.Nonce, state.UserID, state.ReferralCode,
+		state.CreatedAt.UTC(), state.ExpiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save oauth state: %w", err)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *identityRepository) TakeState(stateHash string) (*models.OAuthState, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var state models.OAuthState
+	var userID sql.NullInt64
+	err = tx.QueryRow(r.db.dialect.Rebind(`
+		SELECT provider, code_verifier, nonce, user_id, referral_code, created_at, expires_at
+		FROM oauth_states
+		WHERE state_hash = ?
+	`), stateHash).Scan(
+		&state.Provider, &state.CodeVerifier, &state.Nonce, &userID, &state.ReferralCode,
+		&state.CreatedAt, &state.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("oauth state %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oauth state: %w", err)
+	}
+
+	// Deleting first makes concurrent callbacks with the same state race for
+	// one row, and only one wins
+	result, err := tx.Exec(r.db.dialect.Rebind("DELETE FROM oauth_states WHERE state_hash = ?"), stateHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete oauth state: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("oauth state %w", ErrNotFound)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit oauth state: %w", err)
+	}
+	r.db.checkpoint()
+
+	if !state.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("oauth state %w", ErrNotFound)
+	}
+	if userID.Valid {
+		state.UserID = &userID.Int64
+	}
+	return &state, nil
+}
+
+func (r *identityRepository) DeleteExpiredStates() error {
+	if _, err := r.db.Exec("DELETE FROM oauth_states WHERE expires_at <= ?", time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to delete expired oauth states: %w", err)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
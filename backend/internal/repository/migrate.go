@@ -1,108 +1,524 @@
 package repository
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// RunMigrations runs all SQL migration files in order
-func RunMigrations(db *sql.DB, migrationsPath string) error {
-	files, err := os.ReadDir(migrationsPath)
+// ErrChecksumMismatch is returned when an applied migration file was edited afterwards
+var ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+// legacyBaselineVersion is the last migration shipped before the schema_migrations
+// ledger existed. Databases created by the old runner already contain everything
+// up to this version, so those migrations are recorded instead of re-executed.
+const legacyBaselineVersion = 10
+
+// Migration is a numbered SQL file pair (NNN_name.sql and optional NNN_name.down.sql)
+type Migration struct {
+	Version  int
+	Name     string
+	UpPath   string
+	DownPath string
+	UpSQL    string
+	DownSQL  string
+	Checksum string
+}
+
+// MigrationStatus describes a migration file together with its ledger entry
+type MigrationStatus struct {
+	Migration *Migration
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Drifted   bool // file checksum differs from the one recorded when applied
+	Missing   bool // recorded in the ledger but no longer on disk
+}
+
+// Migrator applies and rolls back migrations, recording each applied version
+// and its checksum in the schema_migrations table
+type Migrator struct {
+	db      *sql.DB
+	dialect Dialect
+	dir     string
+	Out     io.Writer
+}
+
+func NewMigrator(db *sql.DB, dialect Dialect, dir string) *Migrator {
+	return &Migrator{db: db, dialect: dialect, dir: dir, Out: os.Stdout}
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt *time.Time // nil for legacy migrations not yet recorded in the ledger
+}
+
+// Load reads the migration files from disk, sorted by version.
+// Files in a subdirectory named after the dialect (e.g. migrations/postgres)
+// replace the shared file with the same name for that dialect only.
+func (m *Migrator) Load() ([]*Migration, error) {
+	paths, err := migrationFiles(m.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	dialectPaths, err := migrationFiles(filepath.Join(m.dir, string(m.dialect)))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s migrations directory: %w", m.dialect, err)
+	}
+	for name, path := range dialectPaths {
+		paths[name] = path
+	}
+
+	byVersion := make(map[int]*Migration)
+	for file, path := range paths {
+		base := strings.TrimSuffix(file, ".sql")
+		isDown := strings.HasSuffix(base, ".down")
+		base = strings.TrimSuffix(base, ".down")
+
+		version, err := parseMigrationVersion(base)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file name %s: %w", file, err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: base}
+			byVersion[version] = mig
+		} else if mig.Name != base {
+			return nil, fmt.Errorf("duplicate migration version %03d: %s and %s", version, mig.Name, base)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+
+		if isDown {
+			mig.DownPath = path
+			mig.DownSQL = string(content)
+		} else {
+			sum := sha256.Sum256(content)
+			mig.UpPath = path
+			mig.UpSQL = string(content)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.UpPath == "" {
+			return nil, fmt.Errorf("migration %s has a down file but no up file", mig.Name)
+		}
+		migrations = append(migrations, mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Status lists every known migration and whether it has been applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	migrations, err := m.Load()
 	if err != nil {
-		return fmt.Errorf("failed to read migrations directory: %w", err)
+		return nil, err
+	}
+	applied, err := m.applied(migrations)
+	if err != nil {
+		return nil, err
 	}
 
-	// Filter and sort migration files
-	var migrationFiles []string
-	for _, file := range files {
-		if strings.HasSuffix(file.Name(), ".sql") {
-			migrationFiles = append(migrationFiles, file.Name())
+	var statuses []MigrationStatus
+	seen := make(map[int]bool)
+	for _, mig := range migrations {
+		s := MigrationStatus{Migration: mig, Version: mig.Version, Name: mig.Name}
+		if a, ok := applied[mig.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = a.appliedAt
+			s.Drifted = a.checksum != mig.Checksum
+		}
+		seen[mig.Version] = true
+		statuses = append(statuses, s)
+	}
+	for version, a := range applied {
+		if !seen[version] {
+			statuses = append(statuses, MigrationStatus{
+				Version: version, Name: a.name, Applied: true, AppliedAt: a.appliedAt, Missing: true,
+			})
 		}
 	}
-	sort.Strings(migrationFiles)
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
 
-	// List of deprecated migrations that should be skipped
-	deprecatedMigrations := map[string]string{
-		"008_add_iran_cradle_freedom_chapter.sql": "This migration has been merged into 002_seed_data.sql. The chapter is now seeded in the initial data.",
+// Verify fails with ErrChecksumMismatch if any applied migration was modified on disk
+func (m *Migrator) Verify() error {
+	statuses, err := m.Status()
+	if err != nil {
+		return err
 	}
+	return checkDrift(statuses)
+}
 
-	// Execute each migration
-	for _, file := range migrationFiles {
-		// Skip deprecated migrations
-		if reason, isDeprecated := deprecatedMigrations[file]; isDeprecated {
-			fmt.Printf("⏭️  Skipping deprecated migration %s: %s\n", file, reason)
-			continue
+// PlanUp lists the pending migrations Up would apply for target, without running them
+func (m *Migrator) PlanUp(target int) ([]*Migration, error) {
+	statuses, err := m.checkedStatus()
+	if err != nil {
+		return nil, err
+	}
+	return planUp(statuses, target), nil
+}
+
+// PlanDown lists the migrations Down would roll back for target, newest first
+func (m *Migrator) PlanDown(target int) ([]*Migration, error) {
+	statuses, err := m.checkedStatus()
+	if err != nil {
+		return nil, err
+	}
+	return planDown(statuses, target)
+}
+
+// Up applies every pending migration up to and including target (0 means latest).
+// Each migration runs in its own transaction together with its ledger entry.
+func (m *Migrator) Up(target int) ([]*Migration, error) {
+	if err := m.ensureLedger(); err != nil {
+		return nil, err
+	}
+	pending, err := m.PlanUp(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []*Migration
+	for _, mig := range pending {
+		err := m.inTx(mig.UpSQL, func(tx *sql.Tx) error {
+			_, err := tx.Exec(
+				m.dialect.Rebind("INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)"),
+				mig.Version, mig.Name, mig.Checksum,
+			)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("failed to apply migration %s: %w", mig.Name, err)
 		}
+		fmt.Fprintf(m.Out, "✅ Migration applied: %s\n", mig.Name)
+		done = append(done, mig)
+	}
+
+	return done, nil
+}
 
-		path := filepath.Join(migrationsPath, file)
-		sqlContent, err := os.ReadFile(path)
+// Down rolls back applied migrations newer than target, newest first.
+// Nothing is rolled back if any of them lacks a down file.
+func (m *Migrator) Down(target int) ([]*Migration, error) {
+	if err := m.ensureLedger(); err != nil {
+		return nil, err
+	}
+	applied, err := m.PlanDown(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []*Migration
+	for _, mig := range applied {
+		err := m.inTx(mig.DownSQL, func(tx *sql.Tx) error {
+			_, err := tx.Exec(m.dialect.Rebind("DELETE FROM schema_migrations WHERE version = ?"), mig.Version)
+			return err
+		})
 		if err != nil {
-			return fmt.Errorf("failed to read migration %s: %w", file, err)
+			return done, fmt.Errorf("failed to roll back migration %s: %w", mig.Name, err)
+		}
+		fmt.Fprintf(m.Out, "↩️  Migration rolled back: %s\n", mig.Name)
+		done = append(done, mig)
+	}
+
+	return done, nil
+}
+
+func (m *Migrator) checkedStatus() ([]MigrationStatus, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDrift(statuses); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+func planUp(statuses []MigrationStatus, target int) []*Migration {
+	var pending []*Migration
+	for _, s := range statuses {
+		if s.Applied || s.Migration == nil || (target > 0 && s.Version > target) {
+			continue
 		}
+		pending = append(pending, s.Migration)
+	}
+	return pending
+}
 
-		// Check if migration file starts with DEPRECATED comment
-		contentStr := string(sqlContent)
-		if strings.HasPrefix(strings.TrimSpace(contentStr), "-- DEPRECATED") ||
-			strings.HasPrefix(strings.TrimSpace(contentStr), "-- SKIP") {
-			fmt.Printf("⏭️  Skipping deprecated migration %s (marked in file)\n", file)
+func planDown(statuses []MigrationStatus, target int) ([]*Migration, error) {
+	var applied []*Migration
+	for i := len(statuses) - 1; i >= 0; i-- {
+		s := statuses[i]
+		if !s.Applied || s.Version <= target {
 			continue
 		}
+		if s.Missing {
+			return nil, fmt.Errorf("cannot roll back %s: migration file no longer exists", s.Name)
+		}
+		if s.Migration.DownPath == "" {
+			return nil, fmt.Errorf("cannot roll back %s: no down migration (%s.down.sql)", s.Name, s.Name)
+		}
+		applied = append(applied, s.Migration)
+	}
+	return applied, nil
+}
+
+// inTx executes a migration script and a ledger update atomically
+func (m *Migrator) inTx(script string, record func(tx *sql.Tx) error) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if !isBlankSQL(script) {
+		if _, err := tx.Exec(script); err != nil {
+			return err
+		}
+	}
+	if err := record(tx); err != nil {
+		return fmt.Errorf("failed to update schema_migrations: %w", err)
+	}
+
+	return tx.Commit()
+}
 
-		// Execute migration - we'll handle errors gracefully for idempotent migrations
-		// SQLite doesn't support IF NOT EXISTS for ALTER TABLE, so some migrations might fail
-		// if they're run multiple times. We'll log but continue for certain errors.
-		_, err = db.Exec(contentStr)
+// ensureLedger creates schema_migrations on first use. When it is created on a
+// database that the old re-run-everything runner already populated, the legacy
+// migrations are recorded as applied rather than executed again.
+func (m *Migrator) ensureLedger() error {
+	exists, err := m.tableExists("schema_migrations")
+	if err != nil || exists {
+		return err
+	}
+
+	migrations, err := m.Load()
+	if err != nil {
+		return err
+	}
+	baseline, err := m.legacyBaseline(migrations)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		CREATE TABLE schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	for version, a := range baseline {
+		_, err := tx.Exec(
+			m.dialect.Rebind("INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)"),
+			version, a.name, a.checksum,
+		)
 		if err != nil {
-			// Check if error is about duplicate column/table/index (idempotent errors)
-			// These errors indicate the migration was already applied
-			errStr := strings.ToLower(err.Error())
-			isIdempotentError :=
-				// Duplicate column (ALTER TABLE ADD COLUMN when column exists)
-				strings.Contains(errStr, "duplicate column name") ||
-					strings.Contains(errStr, "duplicate column") ||
-					// Table/index already exists (CREATE TABLE/INDEX IF NOT EXISTS already handled, but check anyway)
-					(strings.Contains(errStr, "table") && strings.Contains(errStr, "already exists")) ||
-					(strings.Contains(errStr, "index") && strings.Contains(errStr, "already exists")) ||
-					// Trigger already exists
-					(strings.Contains(errStr, "trigger") && strings.Contains(errStr, "already exists")) ||
-					// Constraint already exists
-					(strings.Contains(errStr, "constraint") && strings.Contains(errStr, "already exists")) ||
-					// UNIQUE constraint violation (for idempotent inserts)
-					(strings.Contains(errStr, "unique constraint") || strings.Contains(errStr, "unique constraint failed"))
-
-			if isIdempotentError {
-				// This is an idempotent error - migration already applied, continue
-				fmt.Printf("⚠️  Migration %s already applied (idempotent): %v\n", file, err)
-				continue
-			}
-
-			// Check for syntax errors - these might indicate deprecated/broken migrations
-			isSyntaxError := strings.Contains(errStr, "syntax error") ||
-				strings.Contains(errStr, "near") ||
-				strings.Contains(errStr, "unrecognized token")
-
-			if isSyntaxError && file == "008_add_iran_cradle_freedom_chapter.sql" {
-				// This specific migration is known to be deprecated and has syntax errors in production
-				fmt.Printf("⏭️  Skipping deprecated migration %s (syntax error - merged into 002_seed_data.sql)\n", file)
-				continue
-			}
-
-			// For other errors, fail the migration
-			return fmt.Errorf("failed to execute migration %s: %w", file, err)
-		}
-
-		fmt.Printf("✅ Migration executed: %s\n", file)
+			return fmt.Errorf("failed to baseline schema_migrations: %w", err)
+		}
+	}
+	if len(baseline) > 0 {
+		fmt.Fprintf(m.Out, "📌 Existing database detected: recorded migrations up to %03d as applied\n", legacyBaselineVersion)
+	}
+
+	return tx.Commit()
+}
+
+// legacyBaseline returns the migrations a database without a ledger already
+// contains: none for an empty database, everything up to legacyBaselineVersion
+// for one created by the previous runner
+func (m *Migrator) legacyBaseline(migrations []*Migration) (map[int]appliedMigration, error) {
+	baseline := make(map[int]appliedMigration)
+
+	legacy, err := m.tableExists("chapters")
+	if err != nil || !legacy {
+		return baseline, err
+	}
+	for _, mig := range migrations {
+		if mig.Version <= legacyBaselineVersion {
+			baseline[mig.Version] = appliedMigration{name: mig.Name, checksum: mig.Checksum}
+		}
+	}
+	return baseline, nil
+}
+
+// applied reads the ledger without modifying the database
+func (m *Migrator) applied(migrations []*Migration) (map[int]appliedMigration, error) {
+	exists, err := m.tableExists("schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return m.legacyBaseline(migrations)
+	}
+
+	rows, err := m.db.Query("SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
 	}
+	defer rows.Close()
 
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &a.name, &a.checksum, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		a.appliedAt = &appliedAt
+		applied[version] = a
+	}
+
+	return applied, rows.Err()
+}
+
+func (m *Migrator) tableExists(name string) (bool, error) {
+	query := "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	if m.dialect == DialectPostgres {
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?"
+	}
+
+	var count int
+	if err := m.db.QueryRow(m.dialect.Rebind(query), name).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check for table %s: %w", name, err)
+	}
+	return count > 0, nil
+}
+
+func checkDrift(statuses []MigrationStatus) error {
+	var drifted []string
+	for _, s := range statuses {
+		if s.Drifted {
+			drifted = append(drifted, s.Name)
+		}
+	}
+	if len(drifted) > 0 {
+		return fmt.Errorf("%w: %s changed after being applied; add a new migration instead of editing it",
+			ErrChecksumMismatch, strings.Join(drifted, ", "))
+	}
 	return nil
 }
 
-// RunMigrationsOnDB runs migrations on an existing database connection
-func RunMigrationsOnDB(db *sql.DB) error {
+func migrationFiles(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string)
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sql") {
+			files[entry.Name()] = filepath.Join(dir, entry.Name())
+		}
+	}
+	return files, nil
+}
+
+func parseMigrationVersion(name string) (int, error) {
+	prefix, _, found := strings.Cut(name, "_")
+	if !found {
+		return 0, errors.New("expected NNN_description.sql")
+	}
+	version, err := strconv.Atoi(prefix)
+	if err != nil || version <= 0 {
+		return 0, errors.New("expected a positive numeric prefix")
+	}
+	return version, nil
+}
+
+// isBlankSQL reports whether a script contains nothing but whitespace and -- comments
+func isBlankSQL(script string) bool {
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateMigration writes an empty up/down pair named after the next free version in dir
+func CreateMigration(dir, description string) (upPath, downPath string, err error) {
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(description), "_"), "_")
+	if slug == "" {
+		return "", "", errors.New("migration description must contain letters or digits")
+	}
+
+	files, err := migrationFiles(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+	next := 1
+	for file := range files {
+		version, err := parseMigrationVersion(strings.TrimSuffix(file, ".sql"))
+		if err == nil && version >= next {
+			next = version + 1
+		}
+	}
+
+	name := fmt.Sprintf("%03d_%s", next, slug)
+	upPath = filepath.Join(dir, name+".sql")
+	downPath = filepath.Join(dir, name+".down.sql")
+
+	up := fmt.Sprintf("-- %s\n-- Runs once, inside a transaction. Never edit this file after it has been applied.\n\n", name)
+	down := fmt.Sprintf("-- Reverts %s. Delete this file if the migration cannot be undone.\n\n", name)
+	if err := os.WriteFile(upPath, []byte(up), 0o644); err != nil {
+		return "", "", fmt.Errorf("failed to write %s: %w", upPath, err)
+	}
+	if err := os.WriteFile(downPath, []byte(down), 0o644); err != nil {
+		return "", "", fmt.Errorf("failed to write %s: %w", downPath, err)
+	}
+
+	return upPath, downPath, nil
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// RunMigrations applies all pending migrations in migrationsPath
+func RunMigrations(db *sql.DB, dialect Dialect, migrationsPath string) error {
+	_, err := NewMigrator(db, dialect, migrationsPath).Up(0)
+	return err
+}
+
+// FindMigrationsDir locates the migrations directory relative to the working directory
+func FindMigrationsDir() (string, error) {
 	// Try multiple paths for migrations
 	possiblePaths := []string{
 		"migrations",
@@ -112,21 +528,40 @@ func RunMigrationsOnDB(db *sql.DB) error {
 		"./backend/migrations",
 	}
 
-	var migrationsPath string
 	for _, path := range possiblePaths {
 		if _, err := os.Stat(path); err == nil {
-			migrationsPath = path
-			break
+			return path, nil
 		}
 	}
 
-	if migrationsPath == "" {
-		return fmt.Errorf("migrations directory not found (tried: %v)", possiblePaths)
+	return "", fmt.Errorf("migrations directory not found (tried: %v)", possiblePaths)
+}
+
+// RunMigrationsOnDB runs migrations on an existing database connection
+func RunMigrationsOnDB(db *sql.DB, dialect Dialect) error {
+	migrationsPath, err := FindMigrationsDir()
+	if err != nil {
+		return err
 	}
 
-	if err := RunMigrations(db, migrationsPath); err != nil {
+	return RunMigrations(db, dialect, migrationsPath)
+}
+
+// checkMigrationsOnDB is used when migrations are applied out of band (cmd/migrate):
+// it still refuses checksum drift and warns about migrations that have not run yet
+func checkMigrationsOnDB(db *sql.DB, dialect Dialect) error {
+	migrationsPath, err := FindMigrationsDir()
+	if err != nil {
 		return err
 	}
 
+	m := NewMigrator(db, dialect, migrationsPath)
+	pending, err := m.PlanUp(0)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		fmt.Fprintf(m.Out, "⚠️  %d pending migration(s) and AUTO_MIGRATE is off; run `migrate up`\n", len(pending))
+	}
 	return nil
 }
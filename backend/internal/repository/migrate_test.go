@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMigrationsDir = "../../migrations"
+
+func openMemoryDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=1")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1) // every connection to :memory: is a separate database
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// setupMigratedDB returns an in-memory SQLite database with every migration applied
+func setupMigratedDB(t *testing.T) *sql.DB {
+	db := openMemoryDB(t)
+	m := NewMigrator(db, DialectSQLite, testMigrationsDir)
+	m.Out = io.Discard
+	_, err := m.Up(0)
+	require.NoError(t, err)
+	return db
+}
+
+func writeMigrations(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestMigrator_AppliesEachMigrationOnce(t *testing.T) {
+	db := setupMigratedDB(t)
+
+	m := NewMigrator(db, DialectSQLite, testMigrationsDir)
+	m.Out = io.Discard
+	applied, err := m.Up(0)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied, s.Name)
+		assert.False(t, s.Drifted, s.Name)
+	}
+}
+
+func TestMigrator_VoteTriggersKeepScore(t *testing.T) {
+	db := setupMigratedDB(t)
+
+	_, err := db.Exec(`INSERT INTO users (id, email, password, referral_code) VALUES (1, 'a@example.com', 'x', 'REF1')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO threads (id, user_id, title, content) VALUES (1, 1, 't', 'c')`)
+	require.NoError(t, err)
+
+	score := func() int {
+		var s int
+		require.NoError(t, db.QueryRow("SELECT score FROM threads WHERE id = 1").Scan(&s))
+		return s
+	}
+
+	_, err = db.Exec(`INSERT INTO votes (user_id, thread_id, vote_type) VALUES (1, 1, 1)`)
+	require.NoError(t, err)
+	assert.Equal(t, 1, score())
+
+	_, err = db.Exec(`UPDATE votes SET vote_type = -1 WHERE user_id = 1 AND thread_id = 1`)
+	require.NoError(t, err)
+	assert.Equal(t, -1, score())
+
+	_, err = db.Exec(`DELETE FROM votes WHERE user_id = 1 AND thread_id = 1`)
+	require.NoError(t, err)
+	assert.Equal(t, 0, score())
+}
+
+func TestMigrator_RefusesChecksumDrift(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"001_create_notes.sql": "CREATE TABLE notes (id INTEGER PRIMARY KEY);",
+	})
+	db := openMemoryDB(t)
+	m := NewMigrator(db, DialectSQLite, dir)
+	m.Out = io.Discard
+
+	_, err := m.Up(0)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "001_create_notes.sql"),
+		[]byte("CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT);"), 0o644))
+
+	_, err = m.Up(0)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.ErrorIs(t, m.Verify(), ErrChecksumMismatch)
+}
+
+func TestMigrator_FailedMigrationIsRolledBack(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"001_create_notes.sql": "CREATE TABLE notes (id INTEGER PRIMARY KEY);",
+		"002_broken.sql":       "CREATE TABLE tags (id INTEGER PRIMARY KEY);\nNOT VALID SQL;",
+	})
+	db := openMemoryDB(t)
+	m := NewMigrator(db, DialectSQLite, dir)
+	m.Out = io.Discard
+
+	applied, err := m.Up(0)
+	require.Error(t, err)
+	assert.Len(t, applied, 1)
+
+	exists, err := m.tableExists("tags")
+	require.NoError(t, err)
+	assert.False(t, exists, "partial migration should be rolled back")
+
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+}
+
+func TestMigrator_Down(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"001_create_notes.sql":      "CREATE TABLE notes (id INTEGER PRIMARY KEY);",
+		"002_create_tags.sql":       "CREATE TABLE tags (id INTEGER PRIMARY KEY);",
+		"002_create_tags.down.sql":  "DROP TABLE tags;",
+		"003_create_links.sql":      "CREATE TABLE links (id INTEGER PRIMARY KEY);",
+		"003_create_links.down.sql": "DROP TABLE links;",
+	})
+	db := openMemoryDB(t)
+	m := NewMigrator(db, DialectSQLite, dir)
+	m.Out = io.Discard
+
+	_, err := m.Up(0)
+	require.NoError(t, err)
+
+	rolledBack, err := m.Down(1)
+	require.NoError(t, err)
+	require.Len(t, rolledBack, 2)
+	assert.Equal(t, 3, rolledBack[0].Version)
+	assert.Equal(t, 2, rolledBack[1].Version)
+
+	exists, err := m.tableExists("tags")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// 001 has no down file
+	_, err = m.Down(0)
+	assert.Error(t, err)
+
+	applied, err := m.Up(0)
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+}
+
+func TestMigrator_BaselinesLegacyDatabase(t *testing.T) {
+	db := openMemoryDB(t)
+	// Schema left behind by the runner that re-executed every file on boot
+	_, err := db.Exec("CREATE TABLE chapters (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+
+	dir := writeMigrations(t, map[string]string{
+		"001_initial.sql":      "CREATE TABLE chapters (id INTEGER PRIMARY KEY);",
+		"010_discussions.sql":  "CREATE TABLE threads (id INTEGER PRIMARY KEY);",
+		"011_fix_triggers.sql": "CREATE TABLE notes (id INTEGER PRIMARY KEY);",
+	})
+	m := NewMigrator(db, DialectSQLite, dir)
+	m.Out = io.Discard
+
+	applied, err := m.Up(0)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, 11, applied[0].Version)
+}
+
+func TestMigrator_PlanDoesNotWrite(t *testing.T) {
+	db := openMemoryDB(t)
+	m := NewMigrator(db, DialectSQLite, testMigrationsDir)
+	m.Out = io.Discard
+
+	pending, err := m.PlanUp(3)
+	require.NoError(t, err)
+	assert.Len(t, pending, 3)
+
+	exists, err := m.tableExists("schema_migrations")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"001_initial.sql":        "SELECT 1;",
+		"009_discussions.sql":    "SELECT 1;",
+		"010_something.down.sql": "SELECT 1;",
+	})
+
+	upPath, downPath, err := CreateMigration(dir, "Add Search Index!")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "011_add_search_index.sql"), upPath)
+	assert.Equal(t, filepath.Join(dir, "011_add_search_index.down.sql"), downPath)
+
+	_, _, err = CreateMigration(dir, "!!!")
+	assert.Error(t, err)
+}
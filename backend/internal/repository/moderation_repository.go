@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+)
+
+// ErrUnsupportedAction is returned for actions that do not apply to the target
+var ErrUnsupportedAction = errors.New("unsupported moderation action")
+
+type ModerationRepository interface {
+	// Apply performs action on action.ThreadID or action.CommentID and records
+	// it in moderation_actions, in one transaction
+	Apply(action *models.ModerationAction) error
+	// ListActions returns the most recent actions first; threadID narrows the
+	// log to one thread and its comments
+	ListActions(threadID *int64, limit int) ([]models.ModerationAction, error)
+}
+
+type moderationRepository struct {
+	db *dialectDB
+}
+
+func NewModerationRepository(db *sql.DB) ModerationRepository {
+	return &moderationRepository{db: newDialectDB(db)}
+}
+
+// Column and value each action sets
+var threadModeration = map[string]string{
+	models.ModerationPin:     "is_pinned = TRUE",
+	models.ModerationUnpin:   "is_pinned = FALSE",
+	models.ModerationLock:    "is_locked = TRUE",
+	models.ModerationUnlock:  "is_locked = FALSE",
+	models.ModerationDelete:  "is_deleted = TRUE",
+	models.ModerationRestore: "is_deleted = FALSE",
+}
+
+var commentModeration = map[string]string{
+	models.ModerationDelete:  "is_deleted = TRUE",
+	models.ModerationRestore: "is_deleted = FALSE",
+}
+
+// authorHidden tells whether a row is hidden by its author's own deletion.
+// A thread with comments stays up as a "[deleted]" tombstone, so only one
+// without comments is hidden.
+var authorHidden = map[string]string{
+	"threads":  "author_deleted AND comment_count = 0",
+	"comments": "author_deleted",
+}
+
+// ErrDeletedByAuthor is returned when restoring a thread or comment its
+// author deleted; only moderator deletions can be undone
+var ErrDeletedByAuthor = fmt.Errorf("%w: it was deleted by its author", ErrUnsupportedAction)
+
+func (r *moderationRepository) Apply(action *models.ModerationAction) error {
+	var table, set string
+	var id int64
+	var ok bool
+	switch {
+	case action.ThreadID != nil:
+		table, id = "threads", *action.ThreadID
+		set, ok = threadModeration[action.Action]
+	case action.CommentID != nil:
+		table, id = "comments", *action.CommentID
+		set, ok = commentModeration[action.Action]
+	}
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnsupportedAction, action.Action)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if action.Action == models.ModerationRestore {
+		var hidden bool
+		query := fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", authorHidden[table], table)
+		err := tx.QueryRow(r.db.dialect.Rebind(query), id).Scan(&hidden)
+		if err == sql.ErrNoRows {
+			if table == "threads" {
+				return fmt.Errorf("thread %w", ErrNotFound)
+			}
+			return fmt.Errorf("comment %w", ErrNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get %s: %w", table, err)
+		}
+		if hidden {
+			return ErrDeletedByAuthor
+		}
+	}
+
+	result, err := tx.Exec(r.db.dialect.Rebind(fmt.Sprintf("UPDATE %s SET %s WHERE id = ?", table, set)), id)
+	if err != nil {
+		return fmt.Errorf("failed to moderate %s: %w", table, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		if table == "threads" {
+			return fmt.Errorf("thread %w", ErrNotFound)
+		}
+		return fmt.Errorf("comment %w", ErrNotFound)
+	}
+
+	query := `
+		INSERT INTO moderation_actions (moderator_id, thread_id, comment_id, action, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		RETURNING id, created_at
+	`
+	var createdAt sql.NullTime
+	err = tx.QueryRow(r.db.dialect.Rebind(query),
+		action.ModeratorID, action.ThreadID, action.CommentID, action.Action, action.Reason,
+	).Scan(&action.ID, &createdAt)
+	if err != nil {
+		return fmt.Errorf("failed to record moderation action: %w", err)
+	}
+	action.CreatedAt = createdAt.Time
+	if !createdAt.Valid {
+		action.CreatedAt = time.Now()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit moderation action: %w", err)
+	}
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *moderationRepository) ListActions(threadID *int64, limit int) ([]models.ModerationAction, error) {
+	query := `
+		SELECT m.id, m.moderator_id, m.thread_id, m.comment_id, m.action, m.reason, m.created_at
+		FROM moderation_actions m
+	`
+	var args []interface{}
+	if threadID != nil {
+		query += " WHERE m.thread_id = ? OR m.comment_id IN (SELECT id FROM comments WHERE thread_id = ?)"
+		args = append(args, *threadID, *threadID)
+	}
+	query += " ORDER BY m.created_at DESC, m.id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get moderation actions: %w", err)
+	}
+	defer rows.Close()
+
+	actions := []models.ModerationAction{}
+	for rows.Next() {
+		var a models.ModerationAction
+		var moderatorID, threadID, commentID sql.NullInt64
+		if err := rows.Scan(&a.ID, &moderatorID, &threadID, &commentID, &a.Action, &a.Reason, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan moderation action: %w", err)
+		}
+		if moderatorID.Valid {
+			a.ModeratorID = &moderatorID.Int64
+		}
+		if threadID.Valid {
+			a.ThreadID = &threadID.Int64
+		}
+		if commentID.Valid {
+			a.CommentID = &commentID.Int64
+		}
+		actions = append(actions, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get moderation actions: %w", err)
+	}
+
+	return actions, nil
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+)
+
+type OutboxRepository interface {
+	// Enqueue stores email as pending, due immediately
+	Enqueue(email *models.OutboxEmail) error
+	// Claim returns up to limit pending emails that are due and leases them
+	// until leaseUntil, so a crashed sender's emails are retried after it
+	Claim(limit int, leaseUntil time.Time) ([]models.OutboxEmail, error)
+	MarkSent(id int64) error
+	// MarkFailed records a failed attempt. A nil retryAt gives up on the email.
+	MarkFailed(id int64, lastError string, retryAt *time.Time) error
+	Get(id int64) (*models.OutboxEmail, error)
+}
+
+type outboxRepository struct {
+	db *dialectDB
+}
+
+func NewOutboxRepository(db *sql.DB) OutboxRepository {
+	return &outboxRepository{db: newDialectDB(db)}
+}
+
+func (r *outboxRepository) Enqueue(email *models.OutboxEmail) error {
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO email_outbox (recipient, subject, html_body, text_body, status, attempts, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, 0, ?, CURRENT_TIMESTAMP)
+		RETURNING id
+	`
+	err := r.db.QueryRow(query, email.Recipient, email.Subject, email.HTMLBody, email.TextBody, models.OutboxPending, now).
+		Scan(&email.ID)
+	if err != nil {
+		return fmt.Errorf("failed to queue email: %w", err)
+	}
+	email.Status = models.OutboxPending
+	email.NextAttemptAt = now
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *outboxRepository) Claim(limit int, leaseUntil time.Time) ([]models.OutboxEmail, error) {
+	rows, err := r.db.Query(`
+		SELECT id, recipient, subject, html_body, text_body, status, attempts, next_attempt_at, last_error
+		FROM email_outbox
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?
+	`, models.OutboxPending, time.Now().UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due emails: %w", err)
+	}
+
+	var due []models.OutboxEmail
+	for rows.Next() {
+		var e models.OutboxEmail
+		if err := rows.Scan(&e.ID, &e.Recipient, &e.Subject, &e.HTMLBody, &e.TextBody, &e.Status, &e.Attempts, &e.NextAttemptAt, &e.LastError); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan email: %w", err)
+		}
+		due = append(due, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get due emails: %w", err)
+	}
+
+	// Only the sender whose update still sees the old next_attempt_at owns the email
+	claimed := []models.OutboxEmail{}
+	for _, e := range due {
+		result, err := r.db.Exec(
+			"UPDATE email_outbox SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at = ?",
+			leaseUntil.UTC(), e.ID, models.OutboxPending, e.NextAttemptAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim email: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 1 {
+			claimed = append(claimed, e)
+		}
+	}
+
+	return claimed, nil
+}
+
+func (r *outboxRepository) MarkSent(id int64) error {
+	_, err := r.db.Exec(
+		"UPDATE email_outbox SET status = ?, attempts = attempts + 1, last_error = '', sent_at = ? WHERE id = ?",
+		models.OutboxSent, time.Now().UTC(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark email sent: %w", err)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *outboxRepository) MarkFailed(id int64, lastError string, retryAt *time.Time) error {
+	var err error
+	if retryAt != nil {
+		_, err = r.db.Exec(
+			"UPDATE email_outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?",
+			lastError, retryAt.UTC(), id,
+		)
+	} else {
+		_, err = r.db.Exec(
+			"UPDATE email_outbox SET status = ?, attempts = attempts + 1, last_error = ? WHERE id = ?",
+			models.OutboxFailed, lastError, id,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mark email failed: %w", err)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *outboxRepository) Get(id int64) (*models.OutboxEmail, error) {
+	var e models.OutboxEmail
+	var createdAt, sentAt sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT id, recipient, subject, html_body, text_body, status, attempts, next_attempt_at, last_error, created_at, sent_at
+		FROM email_outbox WHERE id = ?
+	`, id).Scan(&e.ID, &e.Recipient, &e.Subject, &e.HTMLBody, &e.TextBody, &e.Status, &e.Attempts, &e.NextAttemptAt, &e.LastError, &createdAt, &sentAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("email %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email: %w", err)
+	}
+	e.CreatedAt = createdAt.Time
+	if sentAt.Valid {
+		e.SentAt = &sentAt.Time
+	}
+
+	return &e, nil
+}
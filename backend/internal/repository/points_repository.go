@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+)
+
+type PointsRepository interface {
+	// Credit appends entry to the ledger and adds its amount to the user's
+	// balance. A second referral credit for the same invitee is ErrDuplicate.
+	Credit(entry *models.PointsEntry) error
+	// History returns userID's ledger, newest first
+	History(userID int64, limit int) ([]models.PointsEntry, error)
+	// Invitees returns the users invited by userID and, down to maxDepth
+	// levels, the users they invited, ordered by depth and join date
+	Invitees(userID int64, maxDepth int) ([]models.Referral, error)
+}
+
+type pointsRepository struct {
+	db *dialectDB
+}
+
+func NewPointsRepository(db *sql.DB) PointsRepository {
+	return &pointsRepository{db: newDialectDB(db)}
+}
+
+func (r *pointsRepository) Credit(entry *models.PointsEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var createdAt sql.NullTime
+	err = tx.QueryRow(r.db.dialect.Rebind(`
+		INSERT INTO points_ledger (user_id, amount, reason, source_user_id, created_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		RETURNING id, created_at
+	`), entry.UserID, entry.Amount, entry.Reason, entry.SourceUserID).Scan(&entry.ID, &createdAt)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%s points %w", entry.Reason, ErrDuplicate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to credit points: %w", err)
+	}
+	entry.CreatedAt = createdAt.Time
+
+	_, err = tx.Exec(r.db.dialect.Rebind("UPDATE users SET points = COALESCE(points, 0) + ? WHERE id = ?"), entry.Amount, entry.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to update points balance: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit points: %w", err)
+	}
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *pointsRepository) History(userID int64, limit int) ([]models.PointsEntry, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, amount, reason, source_user_id, created_at
+		FROM points_ledger
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get points history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.PointsEntry{}
+	for rows.Next() {
+		var e models.PointsEntry
+		var sourceUserID sql.NullInt64
+		var createdAt sql.NullTime
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Amount, &e.Reason, &sourceUserID, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan points entry: %w", err)
+		}
+		if sourceUserID.Valid {
+			e.SourceUserID = &sourceUserID.Int64
+		}
+		e.CreatedAt = createdAt.Time
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get points history: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (r *pointsRepository) Invitees(userID int64, maxDepth int) ([]models.Referral, error) {
+	rows, err := r.db.Query(`
+		WITH RECURSIVE tree (id, invited_by, depth) AS (
+			SELECT id, invited_by, 1 FROM users WHERE invited_by = ?
+			UNION ALL
+			SELECT u.id, u.invited_by, tree.depth + 1
+			FROM users u JOIN tree ON u.invited_by = tree.id
+			WHERE tree.depth < ?
+		)
+		SELECT u.id, u.name, u.created_at, u.email_verified_at IS NOT NULL, tree.invited_by, tree.depth
+		FROM tree JOIN users u ON u.id = tree.id
+		ORDER BY tree.depth, u.created_at, u.id
+	`, userID, maxDepth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitees: %w", err)
+	}
+	defer rows.Close()
+
+	referrals := []models.Referral{}
+	for rows.Next() {
+		var ref models.Referral
+		var name sql.NullString
+		if err := rows.Scan(&ref.UserID, &name, &ref.JoinedAt, &ref.Verified, &ref.InvitedBy, &ref.Depth); err != nil {
+			return nil, fmt.Errorf("failed to scan invitee: %w", err)
+		}
+		if name.Valid {
+			ref.Name = &name.String
+		}
+		referrals = append(referrals, ref)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get invitees: %w", err)
+	}
+
+	return referrals, nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+)
+
+func TestPointsRepository_Credit(t *testing.T) {
+	db := setupMigratedDB(t)
+	users := NewUserRepository(db)
+	points := NewPointsRepository(db)
+
+	inviter := &models.User{Email: "inviter@example.com", Password: "password"}
+	require.NoError(t, users.Create(inviter))
+	invitee := &models.User{Email: "invitee@example.com", Password: "password", InvitedBy: &inviter.ID}
+	require.NoError(t, users.Create(invitee))
+
+	entry := &models.PointsEntry{UserID: inviter.ID, Amount: 100, Reason: models.PointsReasonReferral, SourceUserID: &invitee.ID}
+	require.NoError(t, points.Credit(entry))
+	assert.NotZero(t, entry.ID)
+
+	again := &models.PointsEntry{UserID: inviter.ID, Amount: 100, Reason: models.PointsReasonReferral, SourceUserID: &invitee.ID}
+	assert.ErrorIs(t, points.Credit(again), ErrDuplicate, "an invitee is credited once")
+
+	saved, err := users.GetByID(inviter.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 100, saved.Points, "the balance follows the ledger")
+
+	history, err := points.History(inviter.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, invitee.ID, *history[0].SourceUserID)
+
+	_, err = db.Exec("UPDATE points_ledger SET amount = 1000 WHERE id = ?", entry.ID)
+	assert.Error(t, err, "ledger rows cannot be changed")
+}
+
+func TestPointsRepository_Invitees(t *testing.T) {
+	db := setupMigratedDB(t)
+	users := NewUserRepository(db)
+	points := NewPointsRepository(db)
+
+	// root -> a -> b -> c -> d
+	root := &models.User{Email: "root@example.com", Password: "password"}
+	require.NoError(t, users.Create(root))
+	parent := root.ID
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"} {
+		inviter := parent
+		u := &models.User{Email: email, Password: "password", InvitedBy: &inviter}
+		require.NoError(t, users.Create(u))
+		parent = u.ID
+	}
+
+	invitees, err := points.Invitees(root.ID, 3)
+	require.NoError(t, err)
+	require.Len(t, invitees, 3, "the tree stops at maxDepth")
+	for i, ref := range invitees {
+		assert.Equal(t, i+1, ref.Depth)
+	}
+	assert.Equal(t, root.ID, invitees[0].InvitedBy)
+	assert.Equal(t, invitees[0].UserID, invitees[1].InvitedBy)
+}
+
+func TestUserRepository_ReferralCodes(t *testing.T) {
+	db := setupMigratedDB(t)
+	users := NewUserRepository(db)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		code := GenerateReferralCode()
+		assert.Regexp(t, regexp.MustCompile(`^RF[2-9A-HJKMNP-Z]{8}$`), code)
+		seen[code] = true
+	}
+	assert.Len(t, seen, 50)
+
+	user := &models.User{Email: "reader@example.com", Password: "password"}
+	require.NoError(t, users.Create(user))
+	found, err := users.GetByReferralCode(" " + strings.ToLower(user.ReferralCode) + " ")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+
+	_, err = users.GetByReferralCode("RFNOSUCHCODE")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// An explicit code that is taken is a conflict, not silently replaced
+	dup := &models.User{Email: "other@example.com", Password: "password", ReferralCode: user.ReferralCode}
+	assert.ErrorIs(t, users.Create(dup), ErrDuplicate)
+}
@@ -0,0 +1,49 @@
+//go:build embeddedpg
+
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+)
+
+// TestMain starts a throwaway Postgres server for the Postgres tests when
+// TEST_POSTGRES_DSN is not set:
+//
+//	go test -tags embeddedpg ./internal/repository/
+//
+// The first run downloads the server binaries. Postgres refuses to run as root.
+func TestMain(m *testing.M) {
+	if os.Getenv("TEST_POSTGRES_DSN") != "" {
+		os.Exit(m.Run())
+	}
+
+	dir, err := os.MkdirTemp("", "freedom-postgres")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create postgres directory: %v\n", err)
+		os.Exit(1)
+	}
+	config := embeddedpostgres.DefaultConfig().
+		Port(15432).
+		RuntimePath(filepath.Join(dir, "runtime")).
+		DataPath(filepath.Join(dir, "data")).
+		Logger(nil)
+	server := embeddedpostgres.NewDatabase(config)
+	if err := server.Start(); err != nil {
+		os.RemoveAll(dir)
+		fmt.Fprintf(os.Stderr, "failed to start embedded postgres: %v\n", err)
+		os.Exit(1)
+	}
+	embeddedPostgresDSN = config.GetConnectionURL() + "?sslmode=disable"
+
+	code := m.Run()
+	if err := server.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to stop embedded postgres: %v\n", err)
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+)
+
+// embeddedPostgresDSN is set by TestMain in builds with the embeddedpg tag
+// (postgres_embedded_test.go)
+var embeddedPostgresDSN string
+
+// setupPostgresDB connects to the Postgres instance named by TEST_POSTGRES_DSN
+// (for example a local docker container), or else to the embedded server of
+// -tags embeddedpg, and runs all migrations inside a throwaway schema. The
+// test is skipped when neither is available.
+func setupPostgresDB(t *testing.T) (*sql.DB, func()) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		dsn = embeddedPostgresDSN
+	}
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set and not built with -tags embeddedpg; skipping Postgres integration test")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	require.NoError(t, admin.Ping())
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	_, err = admin.Exec("CREATE SCHEMA " + schema)
+	require.NoError(t, err)
+
+	u, err := url.Parse(dsn)
+	require.NoError(t, err)
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+
+	db, err := sql.Open("postgres", u.String())
+	require.NoError(t, err)
+	require.NoError(t, RunMigrations(db, DialectPostgres, "../../migrations"))
+
+	cleanup := func() {
+		db.Close()
+		_, _ = admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		admin.Close()
+	}
+
+	return db, cleanup
+}
+
+func TestPostgres_ChapterRepository(t *testing.T) {
+	db, cleanup := setupPostgresDB(t)
+	defer cleanup()
+
+	repo := NewChapterRepository(db)
+	chapters, err := repo.GetAll()
+	require.NoError(t, err)
+	assert.NotEmpty(t, chapters)
+
+	chapter, err := repo.GetBySlug("axiomatic-formal-system")
+	require.NoError(t, err)
+	assert.Equal(t, 2, chapter.Number)
+	assert.NotEmpty(t, chapter.ContentEn)
+}
+
+func TestPostgres_UserAndDiscussionRepositories(t *testing.T) {
+	db, cleanup := setupPostgresDB(t)
+	defer cleanup()
+
+	users := NewUserRepository(db)
+	author := &models.User{Email: "author@example.com", Password: "secret123"}
+	require.NoError(t, users.Create(author))
+	assert.NotZero(t, author.ID)
+
+	voter := &models.User{Email: "voter@example.com", Password: "secret123"}
+	require.NoError(t, users.Create(voter))
+
+	require.NoError(t, users.CreateVerificationCode(author.ID, author.Email, "01234", models.CodePurposeVerifyEmail))
+	vc, err := users.GetVerificationCode(author.Email, "01234", models.CodePurposeVerifyEmail)
+	require.NoError(t, err)
+	require.NoError(t, users.MarkVerificationCodeAsUsed(vc.ID))
+	require.NoError(t, users.VerifyEmail(author.ID))
+
+	fetched, err := users.GetByEmail(author.Email)
+	require.NoError(t, err)
+	assert.True(t, fetched.IsActive)
+	assert.NotNil(t, fetched.EmailVerifiedAt)
+
+	threads := NewThreadRepository(db)
+	thread := &models.Thread{UserID: author.ID, Title: "Hello", Content: "First thread on Postgres"}
+	require.NoError(t, threads.Create(thread))
+
+	comments := NewCommentRepository(db)
+	comment := &models.Comment{ThreadID: thread.ID, UserID: voter.ID, Content: "Nice"}
+	require.NoError(t, comments.Create(comment))
+
+	votes := NewVoteRepository(db)
+	require.NoError(t, votes.CreateOrUpdate(&models.Vote{UserID: voter.ID, ThreadID: &thread.ID, VoteType: 1}))
+
+	got, err := threads.GetByID(thread.ID, &voter.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Score)
+	assert.Equal(t, 1, got.CommentCount)
+	require.NotNil(t, got.UserVote)
+	assert.Equal(t, 1, *got.UserVote)
+
+	list, total, err := threads.GetAll("newest", 1, 20, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Len(t, list, 1)
+}
+
+func TestPostgres_AccountRepositories(t *testing.T) {
+	db, cleanup := setupPostgresDB(t)
+	defer cleanup()
+
+	users := NewUserRepository(db)
+	inviter := &models.User{Email: "inviter@example.com", Password: "secret123"}
+	require.NoError(t, users.Create(inviter))
+	invitee := &models.User{Email: "invitee@example.com", Password: "secret123", InvitedBy: &inviter.ID}
+	require.NoError(t, users.Create(invitee))
+
+	points := NewPointsRepository(db)
+	entry := &models.PointsEntry{UserID: inviter.ID, Amount: 100, Reason: models.PointsReasonReferral, SourceUserID: &invitee.ID}
+	require.NoError(t, points.Credit(entry))
+	again := &models.PointsEntry{UserID: inviter.ID, Amount: 100, Reason: models.PointsReasonReferral, SourceUserID: &invitee.ID}
+	assert.ErrorIs(t, points.Credit(again), ErrDuplicate)
+	invitees, err := points.Invitees(inviter.ID, 3)
+	require.NoError(t, err)
+	require.Len(t, invitees, 1)
+	assert.Equal(t, invitee.ID, invitees[0].UserID)
+
+	sessions := NewSessionRepository(db)
+	expires := time.Now().Add(time.Hour)
+	require.NoError(t, sessions.Create(&models.Session{ID: "current", UserID: invitee.ID, ExpiresAt: expires}, "hash-1"))
+	require.NoError(t, sessions.Create(&models.Session{ID: "other", UserID: invitee.ID, ExpiresAt: expires}, "hash-2"))
+	rotated, err := sessions.Rotate("hash-1", "hash-1b", expires, "test", "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "current", rotated.ID)
+	revoked, err := sessions.RevokeAll(invitee.ID, "current")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, revoked)
+	active, err := sessions.IsActive("other")
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	twoFactor := NewTwoFactorRepository(db)
+	require.NoError(t, twoFactor.SaveTOTP(invitee.ID, "secret"))
+	require.NoError(t, twoFactor.EnableTOTP(invitee.ID, 10, []string{"code-1", "code-2"}))
+	used, err := twoFactor.UseStep(invitee.ID, 10)
+	require.NoError(t, err)
+	assert.False(t, used, "a step works once")
+	used, err = twoFactor.UseRecoveryCode(invitee.ID, "code-1")
+	require.NoError(t, err)
+	assert.True(t, used)
+	remaining, err := twoFactor.CountRecoveryCodes(invitee.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, remaining)
+
+	identities := NewIdentityRepository(db)
+	require.NoError(t, identities.Create(&models.Identity{UserID: invitee.ID, Provider: models.ProviderGoogle, Subject: "g-1", Email: invitee.Email}))
+	identity, err := identities.Get(models.ProviderGoogle, "g-1")
+	require.NoError(t, err)
+	assert.Equal(t, invitee.ID, identity.UserID)
+
+	threads, comments := NewThreadRepository(db), NewCommentRepository(db)
+	thread := &models.Thread{UserID: inviter.ID, Title: "Hello", Content: "Moderated on Postgres"}
+	require.NoError(t, threads.Create(thread))
+	comment := &models.Comment{ThreadID: thread.ID, UserID: invitee.ID, Content: "Nice"}
+	require.NoError(t, comments.Create(comment))
+	moderation := NewModerationRepository(db)
+	require.NoError(t, moderation.Apply(&models.ModerationAction{CommentID: &comment.ID, Action: models.ModerationDelete, Reason: "spam"}))
+	require.NoError(t, moderation.Apply(&models.ModerationAction{CommentID: &comment.ID, Action: models.ModerationRestore, Reason: "not spam"}))
+	require.NoError(t, comments.Delete(comment.ID, invitee.ID))
+	assert.ErrorIs(t, moderation.Apply(&models.ModerationAction{CommentID: &comment.ID, Action: models.ModerationRestore, Reason: "undo"}), ErrDeletedByAuthor)
+
+	accounts := NewAccountRepository(db)
+	require.NoError(t, accounts.RequestDeletion(invitee.ID, time.Now().Add(-time.Hour)))
+	due, err := accounts.DueForDeletion(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, []int64{invitee.ID}, due)
+	require.NoError(t, accounts.Anonymize(invitee.ID))
+	deleted, err := users.GetByID(invitee.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DeletedUserEmail(invitee.ID), deleted.Email)
+	identityList, err := identities.List(invitee.ID)
+	require.NoError(t, err)
+	assert.Empty(t, identityList)
+}
@@ -13,11 +13,11 @@ type ReactionRepository interface {
 }
 
 type reactionRepository struct {
-	db *sql.DB
+	db *dialectDB
 }
 
 func NewReactionRepository(db *sql.DB) ReactionRepository {
-	return &reactionRepository{db: db}
+	return &reactionRepository{db: newDialectDB(db)}
 }
 
 func (r *reactionRepository) CreateOrDelete(reaction *models.Reaction) error {
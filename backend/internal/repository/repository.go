@@ -9,6 +9,16 @@ type Repository struct {
 	Comment   CommentRepository
 	Vote      VoteRepository
 	Reaction  ReactionRepository
+	Search    SearchRepository
+	Role       RoleRepository
+	Moderation ModerationRepository
+	Session    SessionRepository
+	Audit      AuditRepository
+	Outbox     OutboxRepository
+	Points     PointsRepository
+	Identity   IdentityRepository
+	TwoFactor  TwoFactorRepository
+	Account    AccountRepository
 }
 
 func NewRepository(db Database) *Repository {
@@ -20,5 +30,15 @@ func NewRepository(db Database) *Repository {
 		Comment:   NewCommentRepository(db.GetDB()),
 		Vote:      NewVoteRepository(db.GetDB()),
 		Reaction:  NewReactionRepository(db.GetDB()),
+		Search:    NewSearchRepository(db.GetDB()),
+		Role:       NewRoleRepository(db.GetDB()),
+		Moderation: NewModerationRepository(db.GetDB()),
+		Session:    NewSessionRepository(db.GetDB()),
+		Audit:      NewAuditRepository(db.GetDB()),
+		Outbox:     NewOutboxRepository(db.GetDB()),
+		Points:     NewPointsRepository(db.GetDB()),
+		Identity:   NewIdentityRepository(db.GetDB()),
+		TwoFactor:  NewTwoFactorRepository(db.GetDB()),
+		Account:    NewAccountRepository(db.GetDB()),
 	}
 }
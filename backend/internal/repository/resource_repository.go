@@ -14,11 +14,11 @@ type ResourceRepository interface {
 }
 
 type resourceRepository struct {
-	db *sql.DB
+	db *dialectDB
 }
 
 func NewResourceRepository(db *sql.DB) ResourceRepository {
-	return &resourceRepository{db: db}
+	return &resourceRepository{db: newDialectDB(db)}
 }
 
 func (r *resourceRepository) GetAll() ([]models.Resource, error) {
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+)
+
+type RoleRepository interface {
+	// List returns every role with its permissions, ordered by name
+	List() ([]models.Role, error)
+	Exists(role string) (bool, error)
+	// UserHasPermission reports whether the user's current role grants permission
+	UserHasPermission(userID int64, permission string) (bool, error)
+}
+
+type roleRepository struct {
+	db *dialectDB
+}
+
+func NewRoleRepository(db *sql.DB) RoleRepository {
+	return &roleRepository{db: newDialectDB(db)}
+}
+
+func (r *roleRepository) List() ([]models.Role, error) {
+	query := `
+		SELECT r.name, r.description, COALESCE(rp.permission, '')
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role = r.name
+		ORDER BY r.name, rp.permission
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		var name, description, permission string
+		if err := rows.Scan(&name, &description, &permission); err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		if len(roles) == 0 || roles[len(roles)-1].Name != name {
+			roles = append(roles, models.Role{Name: name, Description: description, Permissions: []string{}})
+		}
+		if permission != "" {
+			last := &roles[len(roles)-1]
+			last.Permissions = append(last.Permissions, permission)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+func (r *roleRepository) Exists(role string) (bool, error) {
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM roles WHERE name = ?", role).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check role: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r *roleRepository) UserHasPermission(userID int64, permission string) (bool, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM users u
+		JOIN role_permissions rp ON rp.role = u.role
+		WHERE u.id = ? AND rp.permission = ?
+	`
+
+	var count int
+	if err := r.db.QueryRow(query, userID, permission).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check permission: %w", err)
+	}
+	return count > 0, nil
+}
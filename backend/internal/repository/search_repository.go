@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/utils"
+)
+
+// Field weights for ranking: title, description, content
+var searchWeights = []float64{10, 5, 1}
+
+type SearchRepository interface {
+	// Rebuild recreates the search index from chapter_translations
+	Rebuild() error
+	// IndexTranslation refreshes the index entry of one chapter translation
+	// after it was written; removed translations are dropped from the index
+	IndexTranslation(chapterID int, locale string) error
+	// Search returns translations matching every term, best match first.
+	// An empty locale searches all locales.
+	Search(terms []string, locale string, limit int) ([]models.SearchResult, error)
+}
+
+type searchRepository struct {
+	db  *dialectDB
+	fts bool
+}
+
+func NewSearchRepository(db *sql.DB) SearchRepository {
+	return &searchRepository{db: newDialectDB(db)}
+}
+
+// The chapter_search FTS5 table is created here rather than in a migration:
+// mattn/go-sqlite3 only includes FTS5 when built with -tags sqlite_fts5, and on
+// other builds (and on Postgres) search falls back to ranking rows in Go.
+// The table holds StripHTML'd, NormalizeSearchText'd copies of the
+// translations, keyed by chapter_translations.id.
+func (r *searchRepository) Rebuild() error {
+	if r.db.dialect == DialectSQLite {
+		var enabled bool
+		if err := r.db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled); err != nil {
+			return fmt.Errorf("failed to detect FTS5 support: %w", err)
+		}
+		r.fts = enabled
+	}
+	if !r.fts {
+		return nil
+	}
+
+	_, err := r.db.Exec(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS chapter_search USING fts5(
+			title, description, content,
+			locale UNINDEXED,
+			tokenize = 'unicode61 remove_diacritics 2'
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create search index: %w", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM chapter_search"); err != nil {
+		return fmt.Errorf("failed to clear search index: %w", err)
+	}
+
+	rows, err := tx.Query("SELECT id, locale, title, description, COALESCE(content, '') FROM chapter_translations")
+	if err != nil {
+		return fmt.Errorf("failed to query chapter translations: %w", err)
+	}
+	type doc struct {
+		id                                  int64
+		locale, title, description, content string
+	}
+	var docs []doc
+	for rows.Next() {
+		var d doc
+		if err := rows.Scan(&d.id, &d.locale, &d.title, &d.description, &d.content); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan chapter translation: %w", err)
+		}
+		docs = append(docs, d)
+	}
+	rows.Close()
+
+	for _, d := range docs {
+		_, err := tx.Exec(
+			"INSERT INTO chapter_search (rowid, title, description, content, locale) VALUES (?, ?, ?, ?, ?)",
+			d.id, indexText(d.title), indexText(d.description), indexText(d.content), d.locale,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to index chapter translation %d: %w", d.id, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *searchRepository) IndexTranslation(chapterID int, locale string) error {
+	if !r.fts {
+		return nil
+	}
+
+	var id int64
+	var title, description, content string
+	err := r.db.QueryRow(
+		"SELECT id, title, description, COALESCE(content, '') FROM chapter_translations WHERE chapter_id = ? AND locale = ?",
+		chapterID, locale,
+	).Scan(&id, &title, &description, &content)
+	if err == sql.ErrNoRows {
+		_, err = r.db.Exec(
+			"DELETE FROM chapter_search WHERE rowid NOT IN (SELECT id FROM chapter_translations)",
+		)
+		if err != nil {
+			return fmt.Errorf("failed to remove stale search entries: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get chapter translation: %w", err)
+	}
+
+	if _, err := r.db.Exec("DELETE FROM chapter_search WHERE rowid = ?", id); err != nil {
+		return fmt.Errorf("failed to update search index: %w", err)
+	}
+	_, err = r.db.Exec(
+		"INSERT INTO chapter_search (rowid, title, description, content, locale) VALUES (?, ?, ?, ?, ?)",
+		id, indexText(title), indexText(description), indexText(content), locale,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update search index: %w", err)
+	}
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *searchRepository) Search(terms []string, locale string, limit int) ([]models.SearchResult, error) {
+	if len(terms) == 0 {
+		return []models.SearchResult{}, nil
+	}
+	if r.fts {
+		return r.searchFTS(terms, locale, limit)
+	}
+	return r.searchScan(terms, locale, limit)
+}
+
+func (r *searchRepository) searchFTS(terms []string, locale string, limit int) ([]models.SearchResult, error) {
+	// Every term as a quoted prefix query: "term"* "term"* (implicit AND)
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"*`
+	}
+
+	query := `
+		SELECT ct.chapter_id, c.number, ct.locale, ct.title, ct.slug, ct.description, COALESCE(ct.content, ''),
+			-bm25(chapter_search, 10.0, 5.0, 1.0) AS score
+		FROM chapter_search
+		JOIN chapter_translations ct ON ct.id = chapter_search.rowid
+		JOIN chapters c ON c.id = ct.chapter_id
+		WHERE chapter_search MATCH ?
+	`
+	args := []interface{}{strings.Join(quoted, " ")}
+	if locale != "" {
+		query += " AND chapter_search.locale = ?"
+		args = append(args, locale)
+	}
+	query += " ORDER BY score DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search chapters: %w", err)
+	}
+	defer rows.Close()
+
+	results := []models.SearchResult{}
+	for rows.Next() {
+		var s models.SearchResult
+		if err := rows.Scan(&s.ChapterID, &s.Number, &s.Locale, &s.Title, &s.Slug, &s.Description, &s.Content, &s.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		s.Content = utils.StripHTML(s.Content)
+		results = append(results, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search chapters: %w", err)
+	}
+
+	return results, nil
+}
+
+// searchScan ranks every translation in Go; the corpus is a handful of chapters
+func (r *searchRepository) searchScan(terms []string, locale string, limit int) ([]models.SearchResult, error) {
+	query := `
+		SELECT ct.chapter_id, c.number, ct.locale, ct.title, ct.slug, ct.description, COALESCE(ct.content, '')
+		FROM chapter_translations ct
+		JOIN chapters c ON c.id = ct.chapter_id
+	`
+	var args []interface{}
+	if locale != "" {
+		query += " WHERE ct.locale = ?"
+		args = append(args, locale)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search chapters: %w", err)
+	}
+	defer rows.Close()
+
+	results := []models.SearchResult{}
+	for rows.Next() {
+		var s models.SearchResult
+		if err := rows.Scan(&s.ChapterID, &s.Number, &s.Locale, &s.Title, &s.Slug, &s.Description, &s.Content); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		s.Content = utils.StripHTML(s.Content)
+		s.Score = utils.MatchScore(terms, searchWeights, s.Title, s.Description, s.Content)
+		if s.Score > 0 {
+			results = append(results, s)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search chapters: %w", err)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+func indexText(s string) string {
+	return utils.NormalizeSearchText(utils.StripHTML(s))
+}
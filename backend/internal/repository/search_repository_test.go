@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/utils"
+)
+
+// Runs against FTS5 when built with -tags sqlite_fts5 and against the Go
+// fallback otherwise; both must give the same answers
+func TestSearchRepository_Search(t *testing.T) {
+	db := setupMigratedDB(t)
+
+	_, err := db.Exec(`INSERT INTO chapters (id, number, title, slug, description, icon) VALUES (100, 100, 'x', 'search-test', 'x', '')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO chapter_translations (chapter_id, locale, title, slug, description, content) VALUES
+		(100, 'fa', 'کيهان‌شناسي', 'kayhan', 'فصلی آزمایشی', '<p>ما مي‌خواهيم زوبینگ را بشناسیم</p>'),
+		(100, 'en', 'Zubing cosmology', 'zubing', 'A test chapter', '<p>Zubing appears here.</p>')
+	`)
+	require.NoError(t, err)
+
+	repo := NewSearchRepository(db)
+	require.NoError(t, repo.Rebuild())
+
+	// Persian query typed with Persian yeh/kaf and no ZWNJ matches Arabic letters + ZWNJ
+	results, err := repo.Search(utils.SearchTerms("کیهانشناسی", 8), "fa", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, 100, results[0].ChapterID)
+	assert.Equal(t, "kayhan", results[0].Slug)
+
+	results, err = repo.Search(utils.SearchTerms("میخواهیم زوبین", 8), "fa", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	// Title matches rank above content-only matches; no locale searches all
+	results, err = repo.Search(utils.SearchTerms("zubing", 8), "", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "en", results[0].Locale)
+	assert.Equal(t, "Zubing appears here.", results[0].Content)
+
+	results, err = repo.Search(utils.SearchTerms("zubing nowhere", 8), "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	// Writes are picked up by IndexTranslation
+	_, err = db.Exec(`UPDATE chapter_translations SET content = 'Quasar text' WHERE chapter_id = 100 AND locale = 'en'`)
+	require.NoError(t, err)
+	require.NoError(t, repo.IndexTranslation(100, "en"))
+	results, err = repo.Search(utils.SearchTerms("quasar", 8), "en", 10)
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+}
@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+)
+
+// ErrRefreshTokenReused is returned by Rotate when a refresh token that was
+// already exchanged is presented again. The session has been revoked by then.
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// SessionRepository stores login sessions and their refresh tokens. Tokens are
+// only ever passed in hashed.
+type SessionRepository interface {
+	// Create inserts session and its first refresh token
+	Create(session *models.Session, tokenHash string) error
+	// Rotate exchanges the refresh token oldHash for newHash, extending the
+	// session to expiresAt. Unknown, expired and revoked tokens wrap ErrNotFound.
+	Rotate(oldHash, newHash string, expiresAt time.Time, userAgent, ipAddress string) (*models.Session, error)
+	// IsActive reports whether the session exists, is not revoked and has not expired
+	IsActive(sessionID string) (bool, error)
+	// List returns the active sessions of a user, most recently used first
+	List(userID int64) ([]models.Session, error)
+	// Revoke ends one session of a user
+	Revoke(userID int64, sessionID string) error
+	// RevokeAll ends every session of a user except the one with id except
+	RevokeAll(userID int64, except string) (int64, error)
+	// RevokeByToken ends the session a refresh token belongs to
+	RevokeByToken(tokenHash string) error
+}
+
+type sessionRepository struct {
+	db *dialectDB
+}
+
+func NewSessionRepository(db *sql.DB) SessionRepository {
+	return &sessionRepository{db: newDialectDB(db)}
+}
+
+func (r *sessionRepository) Create(session *models.Session, tokenHash string) error {
+	now := time.Now().UTC()
+	session.CreatedAt = now
+	session.LastUsedAt = now
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO sessions (id, user_id, user_agent, ip_address, created_at, last_used_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = tx.Exec(r.db.dialect.Rebind(query),
+		session.ID, session.UserID, session.UserAgent, session.IPAddress, now, now, session.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	if err := r.insertToken(tx, session.ID, tokenHash, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit session: %w", err)
+	}
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *sessionRepository) insertToken(tx *sql.Tx, sessionID, tokenHash string, now time.Time) error {
+	_, err := tx.Exec(r.db.dialect.Rebind(
+		"INSERT INTO refresh_tokens (session_id, token_hash, created_at) VALUES (?, ?, ?)",
+	), sessionID, tokenHash, now)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) Rotate(oldHash, newHash string, expiresAt time.Time, userAgent, ipAddress string) (*models.Session, error) {
+	now := time.Now().UTC()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT rt.id, rt.used_at, s.id, s.user_id, s.created_at, s.expires_at, s.revoked_at
+		FROM refresh_tokens rt
+		JOIN sessions s ON s.id = rt.session_id
+		WHERE rt.token_hash = ?
+	`
+	var tokenID int64
+	var usedAt sql.NullTime
+	session := &models.Session{}
+	var revokedAt sql.NullTime
+	err = tx.QueryRow(r.db.dialect.Rebind(query), oldHash).Scan(
+		&tokenID, &usedAt, &session.ID, &session.UserID, &session.CreatedAt, &session.ExpiresAt, &revokedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("refresh token %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if revokedAt.Valid || !session.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("refresh token %w", ErrNotFound)
+	}
+
+	// A used token coming back means two parties hold the family: end it
+	if usedAt.Valid {
+		return nil, r.revokeReused(tx, session.ID, now)
+	}
+	result, err := tx.Exec(r.db.dialect.Rebind(
+		"UPDATE refresh_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL",
+	), now, tokenID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use refresh token: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, r.revokeReused(tx, session.ID, now)
+	}
+
+	if err := r.insertToken(tx, session.ID, newHash, now); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(r.db.dialect.Rebind(`
+		UPDATE sessions SET last_used_at = ?, expires_at = ?, user_agent = ?, ip_address = ?
+		WHERE id = ?
+	`), now, expiresAt.UTC(), userAgent, ipAddress, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit refresh token: %w", err)
+	}
+	r.db.checkpoint()
+
+	session.UserAgent = userAgent
+	session.IPAddress = ipAddress
+	session.LastUsedAt = now
+	session.ExpiresAt = expiresAt.UTC()
+	return session, nil
+}
+
+// revokeReused revokes the session inside tx and commits, so the revocation
+// sticks even though the caller reports an error
+func (r *sessionRepository) revokeReused(tx *sql.Tx, sessionID string, now time.Time) error {
+	_, err := tx.Exec(r.db.dialect.Rebind(
+		"UPDATE sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL",
+	), now, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit session revocation: %w", err)
+	}
+	r.db.checkpoint()
+
+	return ErrRefreshTokenReused
+}
+
+func (r *sessionRepository) IsActive(sessionID string) (bool, error) {
+	var expiresAt time.Time
+	var revokedAt sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT expires_at, revoked_at FROM sessions WHERE id = ?", sessionID,
+	).Scan(&expiresAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return !revokedAt.Valid && expiresAt.After(time.Now()), nil
+}
+
+func (r *sessionRepository) List(userID int64) ([]models.Session, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, user_agent, ip_address, created_at, last_used_at, expires_at
+		FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL
+		ORDER BY last_used_at DESC, created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		if s.ExpiresAt.After(now) {
+			sessions = append(sessions, s)
+		}
+	}
+
+	return sessions, rows.Err()
+}
+
+func (r *sessionRepository) Revoke(userID int64, sessionID string) error {
+	result, err := r.db.Exec(
+		"UPDATE sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL",
+		time.Now().UTC(), sessionID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("session %w", ErrNotFound)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *sessionRepository) RevokeAll(userID int64, except string) (int64, error) {
+	result, err := r.db.Exec(
+		"UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND id <> ? AND revoked_at IS NULL",
+		time.Now().UTC(), userID, except,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	r.db.checkpoint()
+
+	return result.RowsAffected()
+}
+
+func (r *sessionRepository) RevokeByToken(tokenHash string) error {
+	result, err := r.db.Exec(`
+		UPDATE sessions SET revoked_at = ?
+		WHERE id = (SELECT session_id FROM refresh_tokens WHERE token_hash = ?) AND revoked_at IS NULL
+	`, time.Now().UTC(), tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("session %w", ErrNotFound)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
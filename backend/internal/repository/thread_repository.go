@@ -13,6 +13,8 @@ type ThreadRepository interface {
 	GetByID(id int64, userID *int64) (*models.Thread, error)
 	GetAll(sortBy string, page, perPage int, userID *int64) ([]*models.Thread, int, error)
 	Update(thread *models.Thread) error
+	// Delete marks a thread deleted by its author (see models.Thread.Redact)
+	Delete(id, userID int64) error
 	IncrementViewCount(threadID int64) error
 	GetUserVote(threadID, userID int64) (*int, error)
 	GetUserReactions(threadID, userID int64) ([]string, error)
@@ -20,17 +22,17 @@ type ThreadRepository interface {
 }
 
 type threadRepository struct {
-	db *sql.DB
+	db *dialectDB
 }
 
 func NewThreadRepository(db *sql.DB) ThreadRepository {
-	return &threadRepository{db: db}
+	return &threadRepository{db: newDialectDB(db)}
 }
 
 func (r *threadRepository) Create(thread *models.Thread) error {
 	query := `
 		INSERT INTO threads (user_id, title, content, score, comment_count, view_count, is_pinned, is_locked, created_at, updated_at)
-		VALUES (?, ?, ?, 0, 0, 0, 0, 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		VALUES (?, ?, ?, 0, 0, 0, FALSE, FALSE, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		RETURNING id, created_at, updated_at
 	`
 	
@@ -54,11 +56,11 @@ func (r *threadRepository) Create(thread *models.Thread) error {
 func (r *threadRepository) GetByID(id int64, userID *int64) (*models.Thread, error) {
 	query := `
 		SELECT t.id, t.user_id, t.title, t.content, t.score, t.comment_count, t.view_count,
-		       t.is_pinned, t.is_locked, t.created_at, t.updated_at, t.edited_at,
+		       t.is_pinned, t.is_locked, t.author_deleted, t.created_at, t.updated_at, t.edited_at,
 		       u.id, u.email, u.name, u.email_verified_at, u.photo_url, u.created_at
 		FROM threads t
 		LEFT JOIN users u ON t.user_id = u.id
-		WHERE t.id = ?
+		WHERE t.id = ? AND t.is_deleted = FALSE
 	`
 	
 	thread := &models.Thread{}
@@ -72,14 +74,14 @@ func (r *threadRepository) GetByID(id int64, userID *int64) (*models.Thread, err
 	err := r.db.QueryRow(query, id).Scan(
 		&thread.ID, &thread.UserID, &thread.Title, &thread.Content,
 		&thread.Score, &thread.CommentCount, &thread.ViewCount,
-		&thread.IsPinned, &thread.IsLocked,
+		&thread.IsPinned, &thread.IsLocked, &thread.IsDeleted,
 		&thread.CreatedAt, &thread.UpdatedAt, &editedAt,
 		&authorID, &authorEmail, &authorName, &authorEmailVerifiedAt, &authorPhotoURL, &authorCreatedAt,
 	)
 	
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("thread not found")
+			return nil, fmt.Errorf("thread %w", ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get thread: %w", err)
 	}
@@ -140,7 +142,7 @@ func (r *threadRepository) GetAll(sortBy string, page, perPage int, userID *int6
 	
 	// Get total count
 	var total int
-	err := r.db.QueryRow("SELECT COUNT(*) FROM threads").Scan(&total)
+	err := r.db.QueryRow("SELECT COUNT(*) FROM threads WHERE is_deleted = FALSE").Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get thread count: %w", err)
 	}
@@ -149,10 +151,11 @@ func (r *threadRepository) GetAll(sortBy string, page, perPage int, userID *int6
 	offset := (page - 1) * perPage
 	query := fmt.Sprintf(`
 		SELECT t.id, t.user_id, t.title, t.content, t.score, t.comment_count, t.view_count,
-		       t.is_pinned, t.is_locked, t.created_at, t.updated_at, t.edited_at,
+		       t.is_pinned, t.is_locked, t.author_deleted, t.created_at, t.updated_at, t.edited_at,
 		       u.id, u.email, u.name, u.email_verified_at, u.photo_url, u.created_at
 		FROM threads t
 		LEFT JOIN users u ON t.user_id = u.id
+		WHERE t.is_deleted = FALSE
 		ORDER BY %s
 		LIMIT ? OFFSET ?
 	`, orderBy)
@@ -176,7 +179,7 @@ func (r *threadRepository) GetAll(sortBy string, page, perPage int, userID *int6
 		err := rows.Scan(
 			&thread.ID, &thread.UserID, &thread.Title, &thread.Content,
 			&thread.Score, &thread.CommentCount, &thread.ViewCount,
-			&thread.IsPinned, &thread.IsLocked,
+			&thread.IsPinned, &thread.IsLocked, &thread.IsDeleted,
 			&thread.CreatedAt, &thread.UpdatedAt, &editedAt,
 			&authorID, &authorEmail, &authorName, &authorEmailVerifiedAt, &authorPhotoURL, &authorCreatedAt,
 		)
@@ -229,7 +232,7 @@ func (r *threadRepository) Update(thread *models.Thread) error {
 	query := `
 		UPDATE threads 
 		SET title = ?, content = ?, edited_at = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ? AND user_id = ?
+		WHERE id = ? AND user_id = ? AND author_deleted = FALSE
 	`
 	
 	result, err := r.db.Exec(query, thread.Title, thread.Content, now, thread.ID, thread.UserID)
@@ -252,6 +255,34 @@ func (r *threadRepository) Update(thread *models.Thread) error {
 	return nil
 }
 
+func (r *threadRepository) Delete(id, userID int64) error {
+	// Without comments there is nothing to keep a tombstone for
+	query := `
+		UPDATE threads
+		SET author_deleted = TRUE,
+		    is_deleted = CASE WHEN comment_count = 0 THEN TRUE ELSE is_deleted END
+		WHERE id = ? AND user_id = ? AND author_deleted = FALSE AND is_deleted = FALSE
+	`
+	
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete thread: %w", err)
+	}
+	
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	
+	if rowsAffected == 0 {
+		return fmt.Errorf("thread not found or user not authorized")
+	}
+	
+	r.db.checkpoint()
+	
+	return nil
+}
+
 func (r *threadRepository) IncrementViewCount(threadID int64) error {
 	_, err := r.db.Exec("UPDATE threads SET view_count = view_count + 1 WHERE id = ?", threadID)
 	return err
@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+)
+
+// TwoFactorRepository stores TOTP secrets, recovery codes and the logins
+// waiting for their second factor. Codes and tokens are only passed in hashed.
+type TwoFactorRepository interface {
+	GetTOTP(userID int64) (*models.TOTP, error)
+	// SaveTOTP stores a new, not yet enabled secret for a user, replacing a
+	// secret that was not enabled
+	SaveTOTP(userID int64, secret string) error
+	// EnableTOTP turns the secret on, records step as used and replaces the
+	// recovery codes
+	EnableTOTP(userID int64, step int64, codeHashes []string) error
+	// UseStep records step as the last used time step; it reports false when
+	// step is not newer than the last one, i.e. the code was already used
+	UseStep(userID int64, step int64) (bool, error)
+	// DeleteTOTP turns two-factor authentication off and drops the recovery codes
+	DeleteTOTP(userID int64) error
+	IsEnabled(userID int64) (bool, error)
+
+	ReplaceRecoveryCodes(userID int64, codeHashes []string) error
+	// UseRecoveryCode marks an unused code as used; false when there is none
+	UseRecoveryCode(userID int64, codeHash string) (bool, error)
+	CountRecoveryCodes(userID int64) (int, error)
+
+	CreateChallenge(tokenHash string, userID int64, expiresAt time.Time) error
+	// GetChallenge returns the user of an active challenge; expired and
+	// exhausted ones wrap ErrNotFound
+	GetChallenge(tokenHash string) (int64, error)
+	// FailChallenge counts a wrong code against a challenge
+	FailChallenge(tokenHash string) error
+	DeleteChallenge(tokenHash string) error
+}
+
+type twoFactorRepository struct {
+	db *dialectDB
+}
+
+func NewTwoFactorRepository(db *sql.DB) TwoFactorRepository {
+	return &twoFactorRepository{db: newDialectDB(db)}
+}
+
+func (r *twoFactorRepository) GetTOTP(userID int64) (*models.TOTP, error) {
+	var totp models.TOTP
+	var enabledAt sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT user_id, secret, last_step, created_at, enabled_at FROM user_totp WHERE user_id = ?", userID,
+	).Scan(&totp.UserID, &totp.Secret, &totp.LastStep, &totp.CreatedAt, &enabledAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("two-factor secret %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor secret: %w", err)
+	}
+	if enabledAt.Valid {
+		totp.EnabledAt = &enabledAt.Time
+	}
+	return &totp, nil
+}
+
+func (r *twoFactorRepository) SaveTOTP(userID int64, secret string) error {
+	// The guard on enabled_at keeps a pending setup from replacing a secret in use
+	result, err := r.db.Exec(`
+		INSERT INTO user_totp (user_id, secret, last_step, created_at)
+		VALUES (?, ?, 0, ?)
+		ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, last_step = 0, created_at = excluded.created_at
+		WHERE user_totp.enabled_at IS NULL
+	`, userID, secret, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save two-factor secret: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("two-factor secret %w", ErrDuplicate)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *twoFactorRepository) EnableTOTP(userID int64, step int64, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	result, err := tx.Exec(r.db.dialect.Rebind(
+		"UPDATE user_totp SET enabled_at = ?, last_step = ? WHERE user_id = ? AND enabled_at IS NULL",
+	), now, step, userID)
+	if err != nil {
+		return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("pending two-factor secret %w", ErrNotFound)
+	}
+	if err := r.replaceRecoveryCodes(tx, userID, codeHashes, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit two-factor authentication: %w", err)
+	}
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *twoFactorRepository) UseStep(userID int64, step int64) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE user_totp SET last_step = ? WHERE user_id = ? AND last_step < ?", step, userID, step,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to use two-factor code: %w", err)
+	}
+
+	r.db.checkpoint()
+
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+func (r *twoFactorRepository) DeleteTOTP(userID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(r.db.dialect.Rebind("DELETE FROM recovery_codes WHERE user_id = ?"), userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	result, err := tx.Exec(r.db.dialect.Rebind("DELETE FROM user_totp WHERE user_id = ?"), userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete two-factor secret: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("two-factor secret %w", ErrNotFound)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit two-factor removal: %w", err)
+	}
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *twoFactorRepository) IsEnabled(userID int64) (bool, error) {
+	var enabled bool
+	err := r.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM user_totp WHERE user_id = ? AND enabled_at IS NOT NULL)", userID,
+	).Scan(&enabled)
+	if err != nil {
+		return false, fmt.Errorf("failed to check two-factor authentication: %w", err)
+	}
+	return enabled, nil
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(userID int64, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.replaceRecoveryCodes(tx, userID, codeHashes, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *twoFactorRepository) replaceRecoveryCodes(tx *sql.Tx, userID int64, codeHashes []string, now time.Time) error {
+	if _, err := tx.Exec(r.db.dialect.Rebind("DELETE FROM recovery_codes WHERE user_id = ?"), userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	insert := r.db.dialect.Rebind("INSERT INTO recovery_codes (user_id, code_hash, created_at) VALUES (?, ?, ?)")
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(insert, userID, hash, now); err != nil {
+			return fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) UseRecoveryCode(userID int64, codeHash string) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL",
+		time.Now().UTC(), userID, codeHash,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	r.db.checkpoint()
+
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+func (r *twoFactorRepository) CountRecoveryCodes(userID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		"SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL", userID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+func (r *twoFactorRepository) CreateChallenge(tokenHash string, userID int64, expiresAt time.Time) error {
+	now := time.Now().UTC()
+	// Piggyback cleanup of abandoned logins
+	if _, err := r.db.Exec("DELETE FROM two_factor_challenges WHERE expires_at <= ?", now); err != nil {
+		return fmt.Errorf("failed to delete expired challenges: %w", err)
+	}
+	_, err := r.db.Exec(
+		"INSERT INTO two_factor_challenges (token_hash, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)",
+		tokenHash, userID, now, expiresAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create challenge: %w", err)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *twoFactorRepository) GetChallenge(tokenHash string) (int64, error) {
+	var userID int64
+	var attempts int
+	var expiresAt time.Time
+	err := r.db.QueryRow(
+		"SELECT user_id, attempts, expires_at FROM two_factor_challenges WHERE token_hash = ?", tokenHash,
+	).Scan(&userID, &attempts, &expiresAt)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("challenge %w", ErrNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get challenge: %w", err)
+	}
+	if attempts >= models.MaxCodeAttempts || !expiresAt.After(time.Now()) {
+		return 0, fmt.Errorf("challenge %w", ErrNotFound)
+	}
+	return userID, nil
+}
+
+func (r *twoFactorRepository) FailChallenge(tokenHash string) error {
+	if _, err := r.db.Exec(
+		"UPDATE two_factor_challenges SET attempts = attempts + 1 WHERE token_hash = ?", tokenHash,
+	); err != nil {
+		return fmt.Errorf("failed to record challenge failure: %w", err)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *twoFactorRepository) DeleteChallenge(tokenHash string) error {
+	result, err := r.db.Exec("DELETE FROM two_factor_challenges WHERE token_hash = ?", tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to delete challenge: %w", err)
+	}
+	// Two requests finishing the same login: only one wins
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("challenge %w", ErrNotFound)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
@@ -1,8 +1,10 @@
 package repository
 
 import (
+	"crypto/rand"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/whatisrealfreedom/freedom-website/internal/models"
@@ -13,20 +15,48 @@ type UserRepository interface {
 	Create(user *models.User) error
 	GetByEmail(email string) (*models.User, error)
 	GetByID(id int64) (*models.User, error)
+	// GetByReferralCode ignores case and surrounding spaces
+	GetByReferralCode(code string) (*models.User, error)
+	GetByTelegramID(telegramID int64) (*models.User, error)
+	// SetTelegramID links a Telegram account, or unlinks it when telegramID is
+	// nil. An account linked to another user is ErrDuplicate.
+	SetTelegramID(userID int64, telegramID *int64) error
+	// Update saves the profile fields of user (see models.ProfileInput)
 	Update(user *models.User) error
+	// LookupExists reports whether id is a row of the languages, countries or
+	// currencies table
+	LookupExists(table string, id int64) (bool, error)
 	VerifyEmail(userID int64) error
-	CreateVerificationCode(userID int64, email, code string) error
-	GetVerificationCode(email, code string) (*models.EmailVerificationCode, error)
+	// Verification codes are scoped by purpose (models.CodePurpose*)
+	CreateVerificationCode(userID int64, email, code, purpose string) error
+	GetVerificationCode(email, code, purpose string) (*models.EmailVerificationCode, error)
+	// MarkVerificationCodeAsUsed fails if the code was already used, so a code
+	// works once even under concurrent requests
 	MarkVerificationCodeAsUsed(codeID int64) error
+	// RecordCodeFailure counts a wrong guess against every active code of
+	// email and purpose; at models.MaxCodeAttempts they stop working
+	RecordCodeFailure(email, purpose string) error
+	// CancelVerificationCodes stops every active code of userID for purpose
+	CancelVerificationCodes(userID int64, purpose string) error
 	DeleteExpiredVerificationCodes() error
+	// ChangeEmail sets a new, verified email and stops every active code of
+	// the user. An email used by another account is ErrDuplicate.
+	ChangeEmail(userID int64, email string) error
+	GetLanguageCode(userID int64) (string, error)
+	GetRole(userID int64) (string, error)
+	SetRole(userID int64, role string) error
+	// ResetPassword sets a new password, which the user now knows (see
+	// models.User.HasPassword), and revokes every issued token
+	ResetPassword(userID int64, password string) error
+	GetTokenVersion(userID int64) (int, error)
 }
 
 type userRepository struct {
-	db *sql.DB
+	db *dialectDB
 }
 
 func NewUserRepository(db *sql.DB) UserRepository {
-	return &userRepository{db: db}
+	return &userRepository{db: newDialectDB(db)}
 }
 
 // HashPassword hashes a password using bcrypt
@@ -41,9 +71,30 @@ func CheckPassword(password, hash string) bool {
 	return err == nil
 }
 
-// GenerateReferralCode generates a unique referral code
+// referralAlphabet leaves out characters that are easy to misread (0/O, 1/I/L)
+const referralAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// referralCodeAttempts is how often Create draws a new code after a collision
+const referralCodeAttempts = 5
+
+// GenerateReferralCode returns a random code such as "RF7KQ2M9XD". With 31^8
+// possible codes collisions are rare; Create retries the ones that happen.
 func GenerateReferralCode() string {
-	return fmt.Sprintf("RF%d", time.Now().UnixNano()%10000000)
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	code := make([]byte, len(b))
+	for i, v := range b {
+		// 256 % 31 leaves a slight bias toward the first characters, harmless here
+		code[i] = referralAlphabet[int(v)%len(referralAlphabet)]
+	}
+	return "RF" + string(code)
+}
+
+// NormalizeReferralCode makes codes typed by hand comparable
+func NormalizeReferralCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
 }
 
 func (r *userRepository) Create(user *models.User) error {
@@ -54,9 +105,15 @@ func (r *userRepository) Create(user *models.User) error {
 	}
 
 	// Generate referral code if not set
-	if user.ReferralCode == "" {
+	generatedCode := user.ReferralCode == ""
+	if generatedCode {
 		user.ReferralCode = GenerateReferralCode()
 	}
+	if user.Role == "" {
+		user.Role = models.RoleReader
+	}
+	// Telegram, Google and GitHub sign-ups get a random password they never see
+	user.PasswordSet = user.RegistrationSrc == nil
 
 	query := `
 		INSERT INTO users (
@@ -64,13 +121,44 @@ func (r *userRepository) Create(user *models.User) error {
 			preferred_date_format, preferred_timezone, number_format_preference,
 			currency_display_preference, city, address, job_title, bio, mobile, phone,
 			is_active, birthdate, photo_url, referral_code, invited_by, points, registration_src,
-			created_at, updated_at
+			password_set, role, created_at, updated_at
 		) VALUES (
-			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
 		)
+		RETURNING id
 	`
 
-	result, err := r.db.Exec(query,
+	// RETURNING works on both SQLite and Postgres; lib/pq has no LastInsertId
+	var id int64
+	for attempt := 1; ; attempt++ {
+		err = r.insertUser(query, user, hashedPassword).Scan(&id)
+		if err == nil || !generatedCode || attempt == referralCodeAttempts || !isUniqueViolation(err) {
+			break
+		}
+		// Draw another code only if it was the code that collided, not the email
+		taken, lookupErr := r.referralCodeTaken(user.ReferralCode)
+		if lookupErr != nil || !taken {
+			break
+		}
+		user.ReferralCode = GenerateReferralCode()
+	}
+	if isUniqueViolation(err) {
+		return fmt.Errorf("user %w", ErrDuplicate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	user.ID = id
+
+	// Force checkpoint for WAL mode to ensure data is persisted
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *userRepository) insertUser(query string, user *models.User, hashedPassword string) *sql.Row {
+	return r.db.QueryRow(query,
 		user.Email,
 		hashedPassword,
 		user.Name,
@@ -95,168 +183,60 @@ func (r *userRepository) Create(user *models.User) error {
 		user.InvitedBy,
 		user.Points,
 		user.RegistrationSrc,
+		user.PasswordSet,
+		user.Role,
 	)
-	if err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
-	}
+}
 
-	id, err := result.LastInsertId()
+func (r *userRepository) referralCodeTaken(code string) (bool, error) {
+	var taken bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE referral_code = ?)", code).Scan(&taken)
 	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
+		return false, fmt.Errorf("failed to check referral code: %w", err)
 	}
-
-	user.ID = id
-
-	// Force checkpoint for WAL mode to ensure data is persisted
-	_, _ = r.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
-
-	return nil
+	return taken, nil
 }
 
-func (r *userRepository) GetByEmail(email string) (*models.User, error) {
-	query := `
-		SELECT id, email, password, name, created_at, updated_at, telegram_id,
+// userColumns is the column list scanned by scanUser
+const userColumns = `id, email, password, name, created_at, updated_at, telegram_id,
 			language_id, currency_id, country_id, preferred_date_format, preferred_timezone,
 			number_format_preference, currency_display_preference, city, address, job_title,
 			bio, mobile, phone, is_active, birthdate, email_verified_at, mobile_verified_at,
-			phone_verified_at, photo_url, referral_code, invited_by, points, registration_src
-		FROM users
-		WHERE email = ?
-	`
+			phone_verified_at, photo_url, referral_code, invited_by, points, registration_src,
+			password_set, role, token_version, deletion_requested_at`
 
-	var user models.User
-	var name sql.NullString
-	var telegramID, languageID, currencyID, countryID, invitedBy sql.NullInt64
-	var preferredDateFormat, preferredTimezone, numberFormatPreference sql.NullString
-	var currencyDisplayPreference, city, address, jobTitle, bio sql.NullString
-	var mobile, phone, photoURL, registrationSrc sql.NullString
-	var birthdate, emailVerifiedAt, mobileVerifiedAt, phoneVerifiedAt sql.NullTime
-	var points sql.NullInt64
+func (r *userRepository) GetByEmail(email string) (*models.User, error) {
+	return scanUser(r.db.QueryRow("SELECT "+userColumns+" FROM users WHERE email = ?", email))
+}
 
-	err := r.db.QueryRow(query, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Password,
-		&name,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&telegramID,
-		&languageID,
-		&currencyID,
-		&countryID,
-		&preferredDateFormat,
-		&preferredTimezone,
-		&numberFormatPreference,
-		&currencyDisplayPreference,
-		&city,
-		&address,
-		&jobTitle,
-		&bio,
-		&mobile,
-		&phone,
-		&user.IsActive,
-		&birthdate,
-		&emailVerifiedAt,
-		&mobileVerifiedAt,
-		&phoneVerifiedAt,
-		&photoURL,
-		&user.ReferralCode,
-		&invitedBy,
-		&points,
-		&registrationSrc,
-	)
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+func (r *userRepository) GetByID(id int64) (*models.User, error) {
+	return scanUser(r.db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", id))
+}
+
+func (r *userRepository) GetByReferralCode(code string) (*models.User, error) {
+	return scanUser(r.db.QueryRow("SELECT "+userColumns+" FROM users WHERE referral_code = ?", NormalizeReferralCode(code)))
+}
+
+func (r *userRepository) GetByTelegramID(telegramID int64) (*models.User, error) {
+	return scanUser(r.db.QueryRow("SELECT "+userColumns+" FROM users WHERE telegram_id = ?", telegramID))
+}
+
+func (r *userRepository) SetTelegramID(userID int64, telegramID *int64) error {
+	_, err := r.db.Exec("UPDATE users SET telegram_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", telegramID, userID)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("telegram account %w", ErrDuplicate)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return fmt.Errorf("failed to set telegram account: %w", err)
 	}
 
-	// Convert nullable fields
-	if name.Valid {
-		user.Name = &name.String
-	}
-	if telegramID.Valid {
-		user.TelegramID = &telegramID.Int64
-	}
-	if languageID.Valid {
-		user.LanguageID = &languageID.Int64
-	}
-	if currencyID.Valid {
-		user.CurrencyID = &currencyID.Int64
-	}
-	if countryID.Valid {
-		user.CountryID = &countryID.Int64
-	}
-	if preferredDateFormat.Valid {
-		user.PreferredDateFormat = &preferredDateFormat.String
-	}
-	if preferredTimezone.Valid {
-		user.PreferredTimezone = &preferredTimezone.String
-	}
-	if numberFormatPreference.Valid {
-		user.NumberFormatPreference = &numberFormatPreference.String
-	}
-	if currencyDisplayPreference.Valid {
-		user.CurrencyDisplayPreference = &currencyDisplayPreference.String
-	}
-	if city.Valid {
-		user.City = &city.String
-	}
-	if address.Valid {
-		user.Address = &address.String
-	}
-	if jobTitle.Valid {
-		user.JobTitle = &jobTitle.String
-	}
-	if bio.Valid {
-		user.Bio = &bio.String
-	}
-	if mobile.Valid {
-		user.Mobile = &mobile.String
-	}
-	if phone.Valid {
-		user.Phone = &phone.String
-	}
-	if birthdate.Valid {
-		user.Birthdate = &birthdate.Time
-	}
-	if emailVerifiedAt.Valid {
-		user.EmailVerifiedAt = &emailVerifiedAt.Time
-	}
-	if mobileVerifiedAt.Valid {
-		user.MobileVerifiedAt = &mobileVerifiedAt.Time
-	}
-	if phoneVerifiedAt.Valid {
-		user.PhoneVerifiedAt = &phoneVerifiedAt.Time
-	}
-	if photoURL.Valid {
-		user.PhotoURL = &photoURL.String
-	}
-	if invitedBy.Valid {
-		user.InvitedBy = &invitedBy.Int64
-	}
-	if points.Valid {
-		user.Points = int(points.Int64)
-	}
-	if registrationSrc.Valid {
-		user.RegistrationSrc = &registrationSrc.String
-	}
+	r.db.checkpoint()
 
-	return &user, nil
+	return nil
 }
 
-func (r *userRepository) GetByID(id int64) (*models.User, error) {
-	query := `
-		SELECT id, email, password, name, created_at, updated_at, telegram_id,
-			language_id, currency_id, country_id, preferred_date_format, preferred_timezone,
-			number_format_preference, currency_display_preference, city, address, job_title,
-			bio, mobile, phone, is_active, birthdate, email_verified_at, mobile_verified_at,
-			phone_verified_at, photo_url, referral_code, invited_by, points, registration_src
-		FROM users
-		WHERE id = ?
-	`
-
+// scanUser reads one row selected with userColumns
+func scanUser(row interface{ Scan(dest ...interface{}) error }) (*models.User, error) {
 	var user models.User
 	var name sql.NullString
 	var telegramID, languageID, currencyID, countryID, invitedBy sql.NullInt64
@@ -264,9 +244,10 @@ func (r *userRepository) GetByID(id int64) (*models.User, error) {
 	var currencyDisplayPreference, city, address, jobTitle, bio sql.NullString
 	var mobile, phone, photoURL, registrationSrc sql.NullString
 	var birthdate, emailVerifiedAt, mobileVerifiedAt, phoneVerifiedAt sql.NullTime
+	var deletionRequestedAt sql.NullTime
 	var points sql.NullInt64
 
-	err := r.db.QueryRow(query, id).Scan(
+	err := row.Scan(
 		&user.ID,
 		&user.Email,
 		&user.Password,
@@ -297,15 +278,19 @@ func (r *userRepository) GetByID(id int64) (*models.User, error) {
 		&invitedBy,
 		&points,
 		&registrationSrc,
+		&user.PasswordSet,
+		&user.Role,
+		&user.TokenVersion,
+		&deletionRequestedAt,
 	)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user %w", ErrNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	// Convert nullable fields (same as GetByEmail)
+	// Convert nullable fields
 	if name.Valid {
 		user.Name = &name.String
 	}
@@ -375,6 +360,9 @@ func (r *userRepository) GetByID(id int64) (*models.User, error) {
 	if registrationSrc.Valid {
 		user.RegistrationSrc = &registrationSrc.String
 	}
+	if deletionRequestedAt.Valid {
+		user.DeletionRequestedAt = &deletionRequestedAt.Time
+	}
 
 	return &user, nil
 }
@@ -382,22 +370,44 @@ func (r *userRepository) GetByID(id int64) (*models.User, error) {
 func (r *userRepository) Update(user *models.User) error {
 	query := `
 		UPDATE users
-		SET name = ?, updated_at = CURRENT_TIMESTAMP
+		SET name = ?, bio = ?, city = ?, job_title = ?, preferred_timezone = ?,
+			preferred_date_format = ?, photo_url = ?, language_id = ?, currency_id = ?,
+			country_id = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
 	`
 
-	_, err := r.db.Exec(query, user.Name, user.ID)
+	_, err := r.db.Exec(query, user.Name, user.Bio, user.City, user.JobTitle, user.PreferredTimezone,
+		user.PreferredDateFormat, user.PhotoURL, user.LanguageID, user.CurrencyID, user.CountryID, user.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
+	r.db.checkpoint()
+
 	return nil
 }
 
+// lookupTables are the tables LookupExists may query
+var lookupTables = map[string]bool{"languages": true, "countries": true, "currencies": true}
+
+func (r *userRepository) LookupExists(table string, id int64) (bool, error) {
+	if !lookupTables[table] {
+		return false, fmt.Errorf("unknown lookup table %q", table)
+	}
+
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM "+table+" WHERE id = ?)", id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up %s: %w", table, err)
+	}
+
+	return exists, nil
+}
+
 func (r *userRepository) VerifyEmail(userID int64) error {
 	query := `
 		UPDATE users
-		SET email_verified_at = CURRENT_TIMESTAMP, is_active = 1
+		SET email_verified_at = CURRENT_TIMESTAMP, is_active = TRUE
 		WHERE id = ?
 	`
 
@@ -407,31 +417,31 @@ func (r *userRepository) VerifyEmail(userID int64) error {
 	}
 
 	// Force checkpoint for WAL mode to ensure data is persisted
-	_, _ = r.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	r.db.checkpoint()
 
 	return nil
 }
 
-func (r *userRepository) CreateVerificationCode(userID int64, email, code string) error {
-	expiresAt := time.Now().Add(15 * time.Minute)
+func (r *userRepository) CreateVerificationCode(userID int64, email, code, purpose string) error {
+	expiresAt := time.Now().Add(models.CodeTTL)
 
 	query := `
-		INSERT INTO email_verification_codes (user_id, code, email, expires_at, used, created_at)
-		VALUES (?, ?, ?, ?, 0, CURRENT_TIMESTAMP)
+		INSERT INTO email_verification_codes (user_id, code, email, expires_at, used, purpose, created_at)
+		VALUES (?, ?, ?, ?, FALSE, ?, CURRENT_TIMESTAMP)
 	`
 
-	_, err := r.db.Exec(query, userID, code, email, expiresAt)
+	_, err := r.db.Exec(query, userID, code, email, expiresAt, purpose)
 	if err != nil {
 		return fmt.Errorf("failed to create verification code: %w", err)
 	}
 
 	// Force checkpoint for WAL mode to ensure data is persisted
-	_, _ = r.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	r.db.checkpoint()
 
 	return nil
 }
 
-func (r *userRepository) GetVerificationCode(email, code string) (*models.EmailVerificationCode, error) {
+func (r *userRepository) GetVerificationCode(email, code, purpose string) (*models.EmailVerificationCode, error) {
 	// Ensure code is exactly 5 digits with leading zeros
 	// Remove any non-digit characters first
 	normalizedCode := ""
@@ -448,21 +458,23 @@ func (r *userRepository) GetVerificationCode(email, code string) (*models.EmailV
 	}
 
 	query := `
-		SELECT id, user_id, code, email, expires_at, used, created_at
+		SELECT id, user_id, code, email, expires_at, used, purpose, attempts, created_at
 		FROM email_verification_codes
-		WHERE email = ? AND code = ? AND used = 0
+		WHERE email = ? AND code = ? AND purpose = ? AND used = FALSE AND attempts < ?
 		ORDER BY created_at DESC
 		LIMIT 1
 	`
 
 	var vc models.EmailVerificationCode
-	err := r.db.QueryRow(query, email, normalizedCode).Scan(
+	err := r.db.QueryRow(query, email, normalizedCode, purpose, models.MaxCodeAttempts).Scan(
 		&vc.ID,
 		&vc.UserID,
 		&vc.Code,
 		&vc.Email,
 		&vc.ExpiresAt,
 		&vc.Used,
+		&vc.Purpose,
+		&vc.Attempts,
 		&vc.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -483,17 +495,52 @@ func (r *userRepository) GetVerificationCode(email, code string) (*models.EmailV
 func (r *userRepository) MarkVerificationCodeAsUsed(codeID int64) error {
 	query := `
 		UPDATE email_verification_codes
-		SET used = 1
-		WHERE id = ?
+		SET used = TRUE
+		WHERE id = ? AND used = FALSE
 	`
 
-	_, err := r.db.Exec(query, codeID)
+	result, err := r.db.Exec(query, codeID)
 	if err != nil {
 		return fmt.Errorf("failed to mark verification code as used: %w", err)
 	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("verification code not found or already used")
+	}
 
 	// Force checkpoint for WAL mode to ensure data is persisted
-	_, _ = r.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *userRepository) RecordCodeFailure(email, purpose string) error {
+	query := `
+		UPDATE email_verification_codes
+		SET attempts = attempts + 1
+		WHERE email = ? AND purpose = ? AND used = FALSE
+	`
+
+	if _, err := r.db.Exec(query, email, purpose); err != nil {
+		return fmt.Errorf("failed to record verification code failure: %w", err)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *userRepository) CancelVerificationCodes(userID int64, purpose string) error {
+	query := `
+		UPDATE email_verification_codes
+		SET used = TRUE
+		WHERE user_id = ? AND purpose = ? AND used = FALSE
+	`
+
+	if _, err := r.db.Exec(query, userID, purpose); err != nil {
+		return fmt.Errorf("failed to cancel verification codes: %w", err)
+	}
+
+	r.db.checkpoint()
 
 	return nil
 }
@@ -501,7 +548,7 @@ func (r *userRepository) MarkVerificationCodeAsUsed(codeID int64) error {
 func (r *userRepository) DeleteExpiredVerificationCodes() error {
 	query := `
 		DELETE FROM email_verification_codes
-		WHERE expires_at < CURRENT_TIMESTAMP OR used = 1
+		WHERE expires_at < CURRENT_TIMESTAMP OR used = TRUE
 	`
 
 	_, err := r.db.Exec(query)
@@ -511,3 +558,129 @@ func (r *userRepository) DeleteExpiredVerificationCodes() error {
 
 	return nil
 }
+
+// GetLanguageCode returns the code of the user's preferred language, or "" if none is set
+func (r *userRepository) GetLanguageCode(userID int64) (string, error) {
+	query := `
+		SELECT COALESCE(l.code, '')
+		FROM users u
+		LEFT JOIN languages l ON l.id = u.language_id
+		WHERE u.id = ?
+	`
+
+	var code string
+	err := r.db.QueryRow(query, userID).Scan(&code)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get user language: %w", err)
+	}
+
+	return code, nil
+}
+
+func (r *userRepository) GetRole(userID int64) (string, error) {
+	var role string
+	err := r.db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("user %w", ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get user role: %w", err)
+	}
+
+	return role, nil
+}
+
+func (r *userRepository) SetRole(userID int64, role string) error {
+	result, err := r.db.Exec("UPDATE users SET role = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", role, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set user role: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("user %w", ErrNotFound)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *userRepository) ResetPassword(userID int64, password string) error {
+	hashedPassword, err := HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	query := `
+		UPDATE users
+		SET password = ?, password_set = TRUE, token_version = token_version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+
+	result, err := r.db.Exec(query, hashedPassword, userID)
+	if err != nil {
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("user %w", ErrNotFound)
+	}
+
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *userRepository) ChangeEmail(userID int64, email string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(r.db.dialect.Rebind(`
+		UPDATE users
+		SET email = ?, email_verified_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND deleted_at IS NULL
+	`), email, userID)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("email %w", ErrDuplicate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to change email: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("user %w", ErrNotFound)
+	}
+
+	// Codes sent to the old address, such as a password reset, must not
+	// outlive it
+	if _, err := tx.Exec(r.db.dialect.Rebind(`
+		UPDATE email_verification_codes
+		SET used = TRUE
+		WHERE user_id = ? AND used = FALSE
+	`), userID); err != nil {
+		return fmt.Errorf("failed to cancel verification codes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit email change: %w", err)
+	}
+	r.db.checkpoint()
+
+	return nil
+}
+
+func (r *userRepository) GetTokenVersion(userID int64) (int, error) {
+	var version int
+	err := r.db.QueryRow("SELECT token_version FROM users WHERE id = ?", userID).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("user %w", ErrNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get token version: %w", err)
+	}
+
+	return version, nil
+}
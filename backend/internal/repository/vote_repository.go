@@ -14,11 +14,11 @@ type VoteRepository interface {
 }
 
 type voteRepository struct {
-	db *sql.DB
+	db *dialectDB
 }
 
 func NewVoteRepository(db *sql.DB) VoteRepository {
-	return &voteRepository{db: db}
+	return &voteRepository{db: newDialectDB(db)}
 }
 
 func (r *voteRepository) CreateOrUpdate(vote *models.Vote) error {
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+// ErrNoDeletionPending is returned when cancelling a deletion that was not requested
+var ErrNoDeletionPending = fmt.Errorf("%w: this account is not waiting to be deleted", ErrInvalidInput)
+
+// AccountService exports a user's data and deletes accounts after
+// models.AccountDeletionGrace
+type AccountService interface {
+	// Export returns a ZIP with one JSON file per kind of data: profile,
+	// threads, comments, votes, reactions and drafts
+	Export(userID int64) ([]byte, error)
+	// RequestDeletion schedules the account for deletion. Callers confirm it
+	// is the owner with ReauthService first.
+	RequestDeletion(userID int64) (*models.AccountDeletion, error)
+	CancelDeletion(userID int64) error
+	// PurgeDue anonymizes the accounts whose grace period is over and
+	// returns how many
+	PurgeDue() (int, error)
+}
+
+type accountService struct {
+	accounts repository.AccountRepository
+	users    repository.UserRepository
+	avatars  AvatarService
+	audit    repository.AuditRepository
+	now      func() time.Time
+}
+
+func NewAccountService(accounts repository.AccountRepository, users repository.UserRepository, avatars AvatarService, audit repository.AuditRepository) AccountService {
+	return &accountService{accounts: accounts, users: users, avatars: avatars, audit: audit, now: time.Now}
+}
+
+// exportFiles names the files of an export, in the order they are written
+var exportFiles = []string{"profile", "threads", "comments", "votes", "reactions", "drafts"}
+
+func (s *accountService) Export(userID int64) ([]byte, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	export, err := s.accounts.Export(userID)
+	if err != nil {
+		return nil, err
+	}
+	export.Profile = user
+
+	parts := map[string]interface{}{
+		"profile":   export.Profile,
+		"threads":   export.Threads,
+		"comments":  export.Comments,
+		"votes":     export.Votes,
+		"reactions": export.Reactions,
+		"drafts":    export.Drafts,
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	modified := s.now()
+	for _, name := range exportFiles {
+		data, err := json.MarshalIndent(parts[name], "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", name, err)
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name + ".json", Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write export: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to write export: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write export: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *accountService) RequestDeletion(userID int64) (*models.AccountDeletion, error) {
+	if err := s.accounts.RequestDeletion(userID, s.now()); err != nil {
+		return nil, err
+	}
+	// Read back: a request that was already pending keeps its time
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AccountDeletion{
+		RequestedAt: *user.DeletionRequestedAt,
+		DeleteAfter: user.DeletionRequestedAt.Add(models.AccountDeletionGrace),
+	}, nil
+}
+
+func (s *accountService) CancelDeletion(userID int64) error {
+	err := s.accounts.CancelDeletion(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrNoDeletionPending
+	}
+	return err
+}
+
+func (s *accountService) PurgeDue() (int, error) {
+	due, err := s.accounts.DueForDeletion(s.now().Add(-models.AccountDeletionGrace))
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, userID := range due {
+		user, err := s.users.GetByID(userID)
+		if err != nil {
+			return purged, err
+		}
+		if err := s.accounts.Anonymize(userID); err != nil {
+			return purged, err
+		}
+		if user.PhotoURL != nil {
+			s.avatars.RemoveFiles(userID, *user.PhotoURL)
+		}
+		id := userID
+		if err := s.audit.Record(&models.AuditEntry{Event: models.AuditAccountDeleted, UserID: &id}); err != nil {
+			fmt.Printf("⚠️  Failed to record account deletion: %v\n", err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// RunAccountPurge calls PurgeDue every interval until ctx is done
+func RunAccountPurge(ctx context.Context, accounts AccountService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := accounts.PurgeDue(); err != nil {
+			fmt.Printf("⚠️  Account deletion: %v\n", err)
+		} else if n > 0 {
+			fmt.Printf("🗑️  Deleted %d account(s)\n", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
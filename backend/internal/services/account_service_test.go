@@ -0,0 +1,163 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+type accountFixture struct {
+	service *accountService
+	db      *sql.DB
+	users   repository.UserRepository
+	user    *models.User
+	thread  *models.Thread
+}
+
+func setupAccounts(t *testing.T) *accountFixture {
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=1")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, repository.RunMigrations(db, repository.DialectSQLite, "../../migrations"))
+
+	users := repository.NewUserRepository(db)
+	name := "Reader"
+	user := &models.User{Email: "reader@example.com", Password: "password", Name: &name, IsActive: true}
+	require.NoError(t, users.Create(user))
+
+	// A discussion the user started and someone else answered
+	other := &models.User{Email: "other@example.com", Password: "password", IsActive: true}
+	require.NoError(t, users.Create(other))
+	thread := &models.Thread{UserID: user.ID, Title: "A question", Content: "What is freedom, really?"}
+	require.NoError(t, repository.NewThreadRepository(db).Create(thread))
+	comments := repository.NewCommentRepository(db)
+	require.NoError(t, comments.Create(&models.Comment{ThreadID: thread.ID, UserID: other.ID, Content: "An answer"}))
+	require.NoError(t, comments.Create(&models.Comment{ThreadID: thread.ID, UserID: user.ID, Content: "Thanks"}))
+	require.NoError(t, repository.NewVoteRepository(db).CreateOrUpdate(&models.Vote{UserID: user.ID, ThreadID: &thread.ID, VoteType: 1}))
+	require.NoError(t, repository.NewReactionRepository(db).CreateOrDelete(&models.Reaction{UserID: user.ID, ThreadID: &thread.ID, ReactionType: "heart"}))
+	_, err = db.Exec("INSERT INTO thread_drafts (user_id, title, content) VALUES (?, ?, ?)", user.ID, "Draft", "Not yet")
+	require.NoError(t, err)
+
+	service := NewAccountService(repository.NewAccountRepository(db), users,
+		NewAvatarService(users, NewLocalStorage(t.TempDir())), repository.NewAuditRepository(db)).(*accountService)
+	return &accountFixture{service: service, db: db, users: users, user: user, thread: thread}
+}
+
+func TestAccountService_Export(t *testing.T) {
+	f := setupAccounts(t)
+
+	data, err := f.service.Export(f.user.ID)
+	require.NoError(t, err)
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	files := map[string][]byte{}
+	for _, file := range zr.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		files[file.Name], err = io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+	}
+	assert.Len(t, files, len(exportFiles))
+
+	var profile map[string]interface{}
+	require.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+	assert.Equal(t, "reader@example.com", profile["email"])
+	assert.NotContains(t, string(files["profile.json"]), "password")
+
+	var threads []models.Thread
+	require.NoError(t, json.Unmarshal(files["threads.json"], &threads))
+	require.Len(t, threads, 1)
+	assert.Equal(t, "What is freedom, really?", threads[0].Content)
+
+	var comments []models.Comment
+	require.NoError(t, json.Unmarshal(files["comments.json"], &comments))
+	require.Len(t, comments, 1, "only the user's own comments")
+	assert.Equal(t, "Thanks", comments[0].Content)
+
+	for name, want := range map[string]int{"votes.json": 1, "reactions.json": 1, "drafts.json": 1} {
+		var items []json.RawMessage
+		require.NoError(t, json.Unmarshal(files[name], &items))
+		assert.Len(t, items, want, name)
+	}
+}
+
+func TestAccountService_Deletion(t *testing.T) {
+	f := setupAccounts(t)
+
+	deletion, err := f.service.RequestDeletion(f.user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, deletion.RequestedAt.Add(models.AccountDeletionGrace), deletion.DeleteAfter)
+
+	n, err := f.service.PurgeDue()
+	require.NoError(t, err)
+	assert.Zero(t, n, "nothing is deleted during the grace period")
+
+	require.NoError(t, f.service.CancelDeletion(f.user.ID))
+	assert.ErrorIs(t, f.service.CancelDeletion(f.user.ID), ErrNoDeletionPending)
+
+	_, err = f.service.RequestDeletion(f.user.ID)
+	require.NoError(t, err)
+	// Mail to the user, including a pending address change, and to someone else
+	outbox := repository.NewOutboxRepository(f.db)
+	require.NoError(t, f.users.CreateVerificationCode(f.user.ID, "new@example.com", "12345", models.CodePurposeChangeEmail))
+	for _, to := range []string{"reader@example.com", "new@example.com", "other@example.com"} {
+		require.NoError(t, outbox.Enqueue(&models.OutboxEmail{Recipient: to, Subject: "Code", TextBody: "12345"}))
+	}
+	later := time.Now().Add(models.AccountDeletionGrace + time.Minute)
+	f.service.now = func() time.Time { return later }
+	n, err = f.service.PurgeDue()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	user, err := f.users.GetByID(f.user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DeletedUserEmail(f.user.ID), user.Email)
+	assert.True(t, models.IsPlaceholderEmail(user.Email))
+	assert.Equal(t, models.DeletedPlaceholder, *user.Name)
+	assert.False(t, user.IsActive)
+	assert.False(t, repository.CheckPassword("password", user.Password))
+	_, err = f.users.GetByEmail("reader@example.com")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = f.users.GetByReferralCode(user.ReferralCode)
+	assert.ErrorIs(t, err, repository.ErrNotFound, "referrals cannot credit a deleted user")
+
+	// The discussion survives, answer included
+	var threads, comments, drafts int
+	require.NoError(t, f.db.QueryRow("SELECT COUNT(*) FROM threads WHERE id = ?", f.thread.ID).Scan(&threads))
+	require.NoError(t, f.db.QueryRow("SELECT COUNT(*) FROM comments WHERE thread_id = ?", f.thread.ID).Scan(&comments))
+	require.NoError(t, f.db.QueryRow("SELECT COUNT(*) FROM thread_drafts").Scan(&drafts))
+	assert.Equal(t, 1, threads)
+	assert.Equal(t, 2, comments)
+	assert.Zero(t, drafts)
+
+	var recipients []string
+	rows, err := f.db.Query("SELECT recipient FROM email_outbox")
+	require.NoError(t, err)
+	for rows.Next() {
+		var to string
+		require.NoError(t, rows.Scan(&to))
+		recipients = append(recipients, to)
+	}
+	require.NoError(t, rows.Close())
+	assert.Equal(t, []string{"other@example.com"}, recipients, "mail to the deleted user is erased")
+
+	entries, err := repository.NewAuditRepository(f.db).List(models.AuditAccountDeleted, 10)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	n, err = f.service.PurgeDue()
+	require.NoError(t, err)
+	assert.Zero(t, n, "accounts are anonymized once")
+}
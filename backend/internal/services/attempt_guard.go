@@ -0,0 +1,202 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+// Attempts is the failure record of one account or IP address in one scope
+type Attempts struct {
+	Failures     int
+	FirstFailure time.Time
+	// Lockouts counts lockouts so far; each doubles the next one
+	Lockouts    int
+	LockedUntil time.Time
+}
+
+// AttemptStore keeps Attempts by key. The in-process MemoryAttemptStore is
+// enough for a single server; several servers need a shared implementation.
+type AttemptStore interface {
+	Get(key string) (Attempts, bool)
+	// Put stores a record that may be forgotten after ttl
+	Put(key string, attempts Attempts, ttl time.Duration)
+	Delete(key string)
+}
+
+// LockoutPolicy decides when failures lock a key out and for how long
+type LockoutPolicy struct {
+	// MaxFailures within Window lock the key
+	MaxFailures int
+	Window      time.Duration
+	// BaseLockout is the first lockout; each further one doubles, up to MaxLockout
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	// Memory is how long after the last failure the lockout count is kept
+	Memory time.Duration
+}
+
+var (
+	// DefaultAccountPolicy guards a single account against password and code guessing
+	DefaultAccountPolicy = LockoutPolicy{MaxFailures: 5, Window: 15 * time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour, Memory: 24 * time.Hour}
+	// DefaultIPPolicy allows more failures, as many users can share an address
+	DefaultIPPolicy = LockoutPolicy{MaxFailures: 20, Window: 15 * time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour, Memory: 24 * time.Hour}
+)
+
+// Scopes guarded by AttemptGuard; each has its own counters
+const (
+	ScopeLogin         = "login"
+	ScopeVerifyEmail   = "verify_email"
+	ScopeResetPassword = "reset_password"
+	ScopeTwoFactor     = "two_factor"
+	ScopeChangeEmail   = "change_email"
+	ScopeReauth        = "reauth"
+)
+
+// AttemptGuard counts failed authentication attempts per account and per IP
+// address and locks them out with exponential backoff. Lockouts are written
+// to the audit log.
+type AttemptGuard struct {
+	store   AttemptStore
+	audit   repository.AuditRepository
+	account LockoutPolicy
+	ip      LockoutPolicy
+	now     func() time.Time
+
+	// Serializes read-modify-write of the store
+	mu sync.Mutex
+}
+
+func NewAttemptGuard(store AttemptStore, audit repository.AuditRepository, account, ip LockoutPolicy) *AttemptGuard {
+	return &AttemptGuard{store: store, audit: audit, account: account, ip: ip, now: time.Now}
+}
+
+// Check returns how long the caller must wait before trying scope again for
+// account from ip; zero means go ahead
+func (g *AttemptGuard) Check(scope, account, ip string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	accountKey, ipKey := g.keys(scope, account, ip)
+	var wait time.Duration
+	for _, key := range []string{accountKey, ipKey} {
+		if a, ok := g.store.Get(key); ok && a.LockedUntil.After(now) {
+			if d := a.LockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait
+}
+
+// Fail records a failed attempt for account and ip
+func (g *AttemptGuard) Fail(scope, account, ip string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	accountKey, ipKey := g.keys(scope, account, ip)
+	g.fail(accountKey, g.account, ip)
+	g.fail(ipKey, g.ip, ip)
+}
+
+// Succeed clears the account's failures. The IP address keeps its count so
+// that one working account cannot reset it.
+func (g *AttemptGuard) Succeed(scope, account string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	accountKey, _ := g.keys(scope, account, "")
+	g.store.Delete(accountKey)
+}
+
+func (g *AttemptGuard) keys(scope, account, ip string) (accountKey, ipKey string) {
+	return scope + ":account:" + strings.ToLower(strings.TrimSpace(account)), scope + ":ip:" + ip
+}
+
+func (g *AttemptGuard) fail(key string, policy LockoutPolicy, ip string) {
+	now := g.now()
+	a, _ := g.store.Get(key)
+	if a.Failures == 0 || now.Sub(a.FirstFailure) > policy.Window {
+		a.Failures = 0
+		a.FirstFailure = now
+	}
+	a.Failures++
+
+	if a.Failures >= policy.MaxFailures {
+		lockout := policy.BaseLockout << a.Lockouts
+		if lockout > policy.MaxLockout || lockout <= 0 {
+			lockout = policy.MaxLockout
+		}
+		a.Lockouts++
+		a.Failures = 0
+		a.LockedUntil = now.Add(lockout)
+		g.record(key, ip, fmt.Sprintf("%d failed attempts, locked for %s (lockout %d)", policy.MaxFailures, lockout, a.Lockouts))
+	}
+
+	g.store.Put(key, a, policy.Memory)
+}
+
+func (g *AttemptGuard) record(key, ip, details string) {
+	if g.audit == nil {
+		return
+	}
+	entry := &models.AuditEntry{Event: models.AuditLockout, Subject: key, IPAddress: ip, Details: details}
+	if err := g.audit.Record(entry); err != nil {
+		fmt.Printf("⚠️  Failed to record lockout of %s: %v\n", key, err)
+	}
+}
+
+// MemoryAttemptStore is an in-process AttemptStore
+type MemoryAttemptStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryAttempts
+	now     func() time.Time
+}
+
+type memoryAttempts struct {
+	attempts  Attempts
+	expiresAt time.Time
+}
+
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{entries: map[string]memoryAttempts{}, now: time.Now}
+}
+
+func (s *MemoryAttemptStore) Get(key string) (Attempts, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || !e.expiresAt.After(s.now()) {
+		return Attempts{}, false
+	}
+	return e.attempts, true
+}
+
+func (s *MemoryAttemptStore) Put(key string, attempts Attempts, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	// Drop expired records now and then so the map stays bounded
+	if len(s.entries) >= 10000 {
+		for k, e := range s.entries {
+			if !e.expiresAt.After(now) {
+				delete(s.entries, k)
+			}
+		}
+	}
+	s.entries[key] = memoryAttempts{attempts: attempts, expiresAt: now.Add(ttl)}
+}
+
+func (s *MemoryAttemptStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+}
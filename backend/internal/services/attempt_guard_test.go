@@ -0,0 +1,83 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+)
+
+type auditRecorder struct {
+	entries []models.AuditEntry
+}
+
+func (a *auditRecorder) Record(entry *models.AuditEntry) error {
+	a.entries = append(a.entries, *entry)
+	return nil
+}
+
+func (a *auditRecorder) List(event string, limit int) ([]models.AuditEntry, error) {
+	return a.entries, nil
+}
+
+func newTestGuard() (*AttemptGuard, *auditRecorder, *time.Time) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	store := NewMemoryAttemptStore()
+	store.now = clock
+	audit := &auditRecorder{}
+	policy := LockoutPolicy{MaxFailures: 3, Window: 10 * time.Minute, BaseLockout: time.Minute, MaxLockout: 3 * time.Minute, Memory: time.Hour}
+	ipPolicy := policy
+	ipPolicy.MaxFailures = 5
+	guard := NewAttemptGuard(store, audit, policy, ipPolicy)
+	guard.now = clock
+	return guard, audit, &now
+}
+
+func TestAttemptGuard_ExponentialLockout(t *testing.T) {
+	guard, audit, now := newTestGuard()
+
+	for i := 0; i < 2; i++ {
+		guard.Fail(ScopeLogin, "a@example.com", "1.1.1.1")
+	}
+	assert.Zero(t, guard.Check(ScopeLogin, "a@example.com", "1.1.1.1"))
+
+	guard.Fail(ScopeLogin, "A@example.com ", "1.1.1.1")
+	assert.Equal(t, time.Minute, guard.Check(ScopeLogin, "a@example.com", "1.1.1.1"), "accounts are matched case-insensitively")
+	assert.Zero(t, guard.Check(ScopeVerifyEmail, "a@example.com", "1.1.1.1"), "scopes count separately")
+	require.Len(t, audit.entries, 1)
+	assert.Equal(t, models.AuditLockout, audit.entries[0].Event)
+	assert.Equal(t, "login:account:a@example.com", audit.entries[0].Subject)
+
+	// Each further lockout doubles, up to the maximum
+	for _, want := range []time.Duration{2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		*now = now.Add(guard.Check(ScopeLogin, "a@example.com", "1.1.1.1"))
+		for i := 0; i < 3; i++ {
+			guard.Fail(ScopeLogin, "a@example.com", "2.2.2.2")
+		}
+		assert.Equal(t, want, guard.Check(ScopeLogin, "a@example.com", "3.3.3.3"))
+	}
+
+	// Failures spread beyond the window do not add up
+	guard.Succeed(ScopeLogin, "a@example.com")
+	for i := 0; i < 4; i++ {
+		*now = now.Add(6 * time.Minute)
+		guard.Fail(ScopeLogin, "a@example.com", "4.4.4.4")
+		assert.Zero(t, guard.Check(ScopeLogin, "a@example.com", "4.4.4.4"))
+	}
+}
+
+func TestAttemptGuard_IPLockoutSurvivesSuccess(t *testing.T) {
+	guard, _, _ := newTestGuard()
+
+	// Spraying one password over many accounts locks the address
+	for i := 0; i < 5; i++ {
+		account := string(rune('a'+i)) + "@example.com"
+		guard.Fail(ScopeLogin, account, "1.1.1.1")
+		guard.Succeed(ScopeLogin, account)
+	}
+	assert.Equal(t, time.Minute, guard.Check(ScopeLogin, "new@example.com", "1.1.1.1"))
+	assert.Zero(t, guard.Check(ScopeLogin, "new@example.com", "2.2.2.2"))
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	// Decoders for the accepted upload formats
+	_ "image/gif"
+
+	_ "golang.org/x/image/webp"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"golang.org/x/image/draw"
+)
+
+// Avatar upload limits
+const (
+	MaxAvatarBytes     = 5 << 20
+	MinAvatarDimension = 64
+	// MaxAvatarDimension bounds decoding memory; a small file can declare a huge image
+	MaxAvatarDimension = 6000
+	// avatarDefaultSize is the size photo_url points at
+	avatarDefaultSize = 256
+)
+
+// AvatarSizes are the square sizes generated for every upload, in pixels
+var AvatarSizes = []int{64, 128, 256, 512}
+
+// avatarContentTypes are the accepted uploads, by sniffed content type
+var avatarContentTypes = map[string]string{
+	"image/jpeg": "jpeg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// Stored avatars are avatars/<user id>/<version>-<size>.<jpg|png>
+var avatarFileRe = regexp.MustCompile(`^([0-9a-f]{12})-([0-9]+)\.(jpg|png)$`)
+
+// AvatarService validates uploaded images and stores them as avatars
+type AvatarService interface {
+	// Upload replaces userID's avatar with the image read from r
+	Upload(userID int64, r io.Reader) (*models.Avatar, error)
+	// Open returns a stored avatar file, named as in the avatar URLs
+	Open(userID int64, file string) (*StoredFile, error)
+	// RemoveFiles deletes the stored files behind photoURL, if it points to
+	// an avatar uploaded by userID
+	RemoveFiles(userID int64, photoURL string)
+}
+
+type avatarService struct {
+	users   repository.UserRepository
+	storage Storage
+}
+
+func NewAvatarService(users repository.UserRepository, storage Storage) AvatarService {
+	return &avatarService{users: users, storage: storage}
+}
+
+func (s *avatarService) Upload(userID int64, r io.Reader) (*models.Avatar, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxAvatarBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if len(data) > MaxAvatarBytes {
+		return nil, fmt.Errorf("%w: the image must be at most %d MB", ErrInvalidInput, MaxAvatarBytes>>20)
+	}
+
+	variants, ext, err := resizeAvatar(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files are named by content so they can be cached forever
+	sum := sha256.Sum256(variants[len(variants)-1])
+	version := hex.EncodeToString(sum[:])[:12]
+
+	avatar := &models.Avatar{Sizes: make(map[int]string)}
+	for i, size := range AvatarSizes {
+		name := fmt.Sprintf("%s-%d.%s", version, size, ext)
+		if err := s.storage.Put(avatarKey(userID, name), bytes.NewReader(variants[i])); err != nil {
+			return nil, err
+		}
+		avatar.Sizes[size] = fmt.Sprintf("%s%d/%s", models.AvatarURLPrefix, userID, name)
+	}
+	avatar.PhotoURL = avatar.Sizes[avatarDefaultSize]
+
+	previous := user.PhotoURL
+	user.PhotoURL = &avatar.PhotoURL
+	if err := s.users.Update(user); err != nil {
+		return nil, err
+	}
+	if previous != nil && *previous != avatar.PhotoURL {
+		s.deleteUploaded(userID, *previous)
+	}
+
+	return avatar, nil
+}
+
+func (s *avatarService) Open(userID int64, file string) (*StoredFile, error) {
+	if !avatarFileRe.MatchString(file) {
+		return nil, ErrStorageNotFound
+	}
+	return s.storage.Open(avatarKey(userID, file))
+}
+
+func (s *avatarService) RemoveFiles(userID int64, photoURL string) {
+	s.deleteUploaded(userID, photoURL)
+}
+
+// deleteUploaded removes every size of a previous upload. photoURL may point
+// anywhere; only our own avatars are deleted.
+func (s *avatarService) deleteUploaded(userID int64, photoURL string) {
+	prefix := fmt.Sprintf("%s%d/", models.AvatarURLPrefix, userID)
+	if !strings.HasPrefix(photoURL, prefix) {
+		return
+	}
+	m := avatarFileRe.FindStringSubmatch(strings.TrimPrefix(photoURL, prefix))
+	if m == nil {
+		return
+	}
+	for _, size := range AvatarSizes {
+		key := avatarKey(userID, fmt.Sprintf("%s-%d.%s", m[1], size, m[3]))
+		if err := s.storage.Delete(key); err != nil {
+			fmt.Printf("⚠️  Failed to delete old avatar %s: %v\n", key, err)
+		}
+	}
+}
+
+func avatarKey(userID int64, file string) string {
+	return "avatars/" + strconv.FormatInt(userID, 10) + "/" + file
+}
+
+// resizeAvatar checks that data is an accepted image and returns it cropped to
+// a square at each of AvatarSizes. The output is re-encoded, which leaves
+// EXIF and other metadata behind: JPEG for opaque images, PNG otherwise.
+func resizeAvatar(data []byte) ([][]byte, string, error) {
+	// Trust the bytes, not the file name or the client's content type
+	contentType := http.DetectContentType(data)
+	format, ok := avatarContentTypes[contentType]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: the image must be JPEG, PNG, GIF or WebP, got %s", ErrInvalidInput, contentType)
+	}
+
+	cfg, decodedFormat, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || decodedFormat != format {
+		return nil, "", fmt.Errorf("%w: the image could not be read", ErrInvalidInput)
+	}
+	if cfg.Width < MinAvatarDimension || cfg.Height < MinAvatarDimension {
+		return nil, "", fmt.Errorf("%w: the image must be at least %dx%d pixels", ErrInvalidInput, MinAvatarDimension, MinAvatarDimension)
+	}
+	if cfg.Width > MaxAvatarDimension || cfg.Height > MaxAvatarDimension {
+		return nil, "", fmt.Errorf("%w: the image must be at most %dx%d pixels", ErrInvalidInput, MaxAvatarDimension, MaxAvatarDimension)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: the image could not be read", ErrInvalidInput)
+	}
+
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	// Centered square crop
+	b := src.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	crop := image.Rect(x0, y0, x0+side, y0+side)
+
+	opaque := true
+	if o, ok := src.(interface{ Opaque() bool }); ok {
+		opaque = o.Opaque()
+	}
+	ext := "png"
+	if opaque {
+		ext = "jpg"
+	}
+
+	variants := make([][]byte, 0, len(AvatarSizes))
+	for _, size := range AvatarSizes {
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+		dst = orient(dst, orientation)
+
+		var buf bytes.Buffer
+		if opaque {
+			err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&buf, dst)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode avatar: %w", err)
+		}
+		variants = append(variants, buf.Bytes())
+	}
+
+	return variants, ext, nil
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+// testImage is w x h, red on its left half and blue on its right half
+func testImage(w, h int, alpha uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{R: 255, A: alpha}
+			if x >= w/2 {
+				c = color.NRGBA{B: 255, A: alpha}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}))
+	return buf.Bytes()
+}
+
+// withEXIF inserts an EXIF segment with the given orientation and a comment
+// after the JPEG start marker
+func withEXIF(data []byte, orientation byte) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // big endian, first IFD at 8
+		0, 2, // two entries
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0, // Orientation, SHORT
+		0x01, 0x0E, 0, 2, 0, 0, 0, 4, 'G', 'P', 'S', 0, // ImageDescription, ASCII
+		0, 0, 0, 0,
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	length := len(payload) + 2
+	segment := append([]byte{0xFF, 0xE1, byte(length >> 8), byte(length)}, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func setupAvatars(t *testing.T) (AvatarService, repository.UserRepository, *models.User) {
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=1")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, repository.RunMigrations(db, repository.DialectSQLite, "../../migrations"))
+
+	users := repository.NewUserRepository(db)
+	user := &models.User{Email: "reader@example.com", Password: "password"}
+	require.NoError(t, users.Create(user))
+
+	return NewAvatarService(users, NewLocalStorage(t.TempDir())), users, user
+}
+
+func TestAvatarService_Upload(t *testing.T) {
+	avatars, users, user := setupAvatars(t)
+
+	upload := withEXIF(encodeJPEG(t, testImage(300, 200, 255)), 1)
+	avatar, err := avatars.Upload(user.ID, bytes.NewReader(upload))
+	require.NoError(t, err)
+
+	require.Len(t, avatar.Sizes, len(AvatarSizes))
+	assert.Equal(t, avatar.Sizes[256], avatar.PhotoURL)
+	assert.True(t, strings.HasPrefix(avatar.PhotoURL, models.AvatarURLPrefix))
+
+	saved, err := users.GetByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, avatar.PhotoURL, *saved.PhotoURL)
+
+	for size, url := range avatar.Sizes {
+		name := url[strings.LastIndex(url, "/")+1:]
+		file, err := avatars.Open(user.ID, name)
+		require.NoError(t, err)
+		data, err := io.ReadAll(file)
+		file.Close()
+		require.NoError(t, err)
+
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, "jpeg", format)
+		assert.Equal(t, size, cfg.Width)
+		assert.Equal(t, size, cfg.Height)
+		assert.NotContains(t, string(data), "Exif", "metadata is stripped")
+	}
+
+	// A new upload replaces the old files
+	second, err := avatars.Upload(user.ID, bytes.NewReader(encodeJPEG(t, testImage(100, 100, 255))))
+	require.NoError(t, err)
+	assert.NotEqual(t, avatar.PhotoURL, second.PhotoURL)
+	old := avatar.PhotoURL[strings.LastIndex(avatar.PhotoURL, "/")+1:]
+	_, err = avatars.Open(user.ID, old)
+	assert.ErrorIs(t, err, ErrStorageNotFound)
+}
+
+func TestAvatarService_AppliesOrientation(t *testing.T) {
+	avatars, _, user := setupAvatars(t)
+
+	// Stored sideways: displayed after turning 90° clockwise, so the red left
+	// half ends up on top
+	avatar, err := avatars.Upload(user.ID, bytes.NewReader(withEXIF(encodeJPEG(t, testImage(200, 200, 255)), 6)))
+	require.NoError(t, err)
+
+	url := avatar.Sizes[64]
+	file, err := avatars.Open(user.ID, url[strings.LastIndex(url, "/")+1:])
+	require.NoError(t, err)
+	defer file.Close()
+	img, err := jpeg.Decode(file)
+	require.NoError(t, err)
+
+	r, _, b, _ := img.At(32, 8).RGBA()
+	assert.Greater(t, r, b, "top is red")
+	r, _, b, _ = img.At(32, 56).RGBA()
+	assert.Greater(t, b, r, "bottom is blue")
+}
+
+func TestAvatarService_KeepsTransparency(t *testing.T) {
+	avatars, _, user := setupAvatars(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, testImage(100, 100, 128)))
+	avatar, err := avatars.Upload(user.ID, &buf)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(avatar.PhotoURL, ".png"))
+}
+
+func TestAvatarService_RejectsInvalidImages(t *testing.T) {
+	avatars, users, user := setupAvatars(t)
+
+	var tiny bytes.Buffer
+	require.NoError(t, png.Encode(&tiny, testImage(32, 32, 255)))
+	var huge bytes.Buffer
+	require.NoError(t, png.Encode(&huge, image.NewGray(image.Rect(0, 0, MaxAvatarDimension+1, 1))))
+
+	for name, upload := range map[string][]byte{
+		"not an image":  []byte("<svg xmlns='http://www.w3.org/2000/svg'></svg>"),
+		"html as image": []byte("<html><script>alert(1)</script></html>"),
+		"truncated":     encodeJPEG(t, testImage(100, 100, 255))[:200],
+		"too small":     tiny.Bytes(),
+		"too large":     huge.Bytes(),
+		"too many bytes": append(encodeJPEG(t, testImage(100, 100, 255)),
+			make([]byte, MaxAvatarBytes)...),
+	} {
+		_, err := avatars.Upload(user.ID, bytes.NewReader(upload))
+		assert.ErrorIs(t, err, ErrInvalidInput, name)
+	}
+
+	saved, err := users.GetByID(user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, saved.PhotoURL)
+}
+
+func TestLocalStorage_RejectsEscapingKeys(t *testing.T) {
+	storage := NewLocalStorage(t.TempDir())
+	for _, key := range []string{"../secret", "avatars/../../x", "/etc/passwd", "", "a//b"} {
+		assert.Error(t, storage.Put(key, strings.NewReader("x")), key)
+		_, err := storage.Open(key)
+		assert.ErrorIs(t, err, ErrStorageNotFound, key)
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+// ErrInvalidInput is wrapped by validation failures; the message says what is wrong
+var ErrInvalidInput = errors.New("invalid input")
+
+// Lowercase letters (any script) and digits separated by single hyphens
+var slugRe = regexp.MustCompile(`^[\p{Ll}\p{Lo}\p{N}]+(-[\p{Ll}\p{Lo}\p{N}]+)*$`)
+
+// ChapterAdminService backs the admin chapter API
+type ChapterAdminService interface {
+	ListChapters() ([]models.ChapterSummary, error)
+	CreateChapter(input *models.ChapterInput) (*models.Chapter, error)
+	UpdateChapter(id int, input *models.ChapterInput) (*models.Chapter, error)
+	DeleteChapter(id int) error
+	ReorderChapters(ids []int) error
+
+	ListTranslations(chapterID int) ([]models.ChapterTranslation, error)
+	SaveTranslation(chapterID int, locale string, input *models.TranslationInput) (*models.ChapterTranslation, error)
+	DeleteTranslation(chapterID int, locale string) error
+}
+
+type chapterAdminService struct {
+	chapters repository.ChapterRepository
+	search   repository.SearchRepository
+}
+
+// NewChapterAdminService keeps the search index in step with translation writes;
+// search may be nil
+func NewChapterAdminService(chapters repository.ChapterRepository, search repository.SearchRepository) ChapterAdminService {
+	return &chapterAdminService{chapters: chapters, search: search}
+}
+
+func (s *chapterAdminService) ListChapters() ([]models.ChapterSummary, error) {
+	return s.chapters.GetAll()
+}
+
+func (s *chapterAdminService) CreateChapter(input *models.ChapterInput) (*models.Chapter, error) {
+	if input.Number == nil || input.Title == nil || input.Slug == nil || input.Description == nil {
+		return nil, fmt.Errorf("%w: number, title, slug and description are required", ErrInvalidInput)
+	}
+
+	chapter := &models.Chapter{}
+	input.ApplyTo(chapter)
+	if input.Order == nil {
+		chapter.Order = chapter.Number
+	}
+	if err := validateChapter(chapter); err != nil {
+		return nil, err
+	}
+
+	if err := s.chapters.Create(chapter); err != nil {
+		return nil, err
+	}
+	return s.chapters.GetByID(chapter.ID)
+}
+
+func (s *chapterAdminService) UpdateChapter(id int, input *models.ChapterInput) (*models.Chapter, error) {
+	chapter, err := s.chapters.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	input.ApplyTo(chapter)
+	if err := validateChapter(chapter); err != nil {
+		return nil, err
+	}
+
+	if err := s.chapters.Update(chapter); err != nil {
+		return nil, err
+	}
+	return s.chapters.GetByID(id)
+}
+
+func (s *chapterAdminService) DeleteChapter(id int) error {
+	translations, err := s.chapters.GetTranslations(id)
+	if err != nil {
+		return err
+	}
+	if err := s.chapters.Delete(id); err != nil {
+		return err
+	}
+
+	for _, t := range translations {
+		s.reindex(id, t.Locale)
+	}
+	return nil
+}
+
+func (s *chapterAdminService) ReorderChapters(ids []int) error {
+	if len(ids) == 0 {
+		return fmt.Errorf("%w: ids must list the chapters in their new order", ErrInvalidInput)
+	}
+	seen := make(map[int]bool)
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("%w: chapter %d is listed twice", ErrInvalidInput, id)
+		}
+		seen[id] = true
+	}
+
+	return s.chapters.Reorder(ids)
+}
+
+func (s *chapterAdminService) ListTranslations(chapterID int) ([]models.ChapterTranslation, error) {
+	if _, err := s.chapters.GetByID(chapterID); err != nil {
+		return nil, err
+	}
+	return s.chapters.GetTranslations(chapterID)
+}
+
+func (s *chapterAdminService) SaveTranslation(chapterID int, locale string, input *models.TranslationInput) (*models.ChapterTranslation, error) {
+	if NormalizeLocale(locale) != locale {
+		return nil, fmt.Errorf("%w: locale must be a language code such as fa or en", ErrInvalidInput)
+	}
+	translation := &models.ChapterTranslation{
+		ChapterID:   chapterID,
+		Locale:      locale,
+		Title:       strings.TrimSpace(input.Title),
+		Slug:        strings.TrimSpace(input.Slug),
+		Description: strings.TrimSpace(input.Description),
+		Content:     input.Content,
+	}
+	if translation.Title == "" || translation.Description == "" {
+		return nil, fmt.Errorf("%w: title and description are required", ErrInvalidInput)
+	}
+	if !slugRe.MatchString(translation.Slug) {
+		return nil, fmt.Errorf("%w: slug must be lowercase words separated by hyphens", ErrInvalidInput)
+	}
+
+	if _, err := s.chapters.GetByID(chapterID); err != nil {
+		return nil, err
+	}
+	if err := s.chapters.UpsertTranslation(translation); err != nil {
+		return nil, err
+	}
+
+	s.reindex(chapterID, locale)
+	return translation, nil
+}
+
+func (s *chapterAdminService) DeleteTranslation(chapterID int, locale string) error {
+	if err := s.chapters.DeleteTranslation(chapterID, locale); err != nil {
+		return err
+	}
+
+	s.reindex(chapterID, locale)
+	return nil
+}
+
+// reindex refreshes search; a stale index entry is not worth failing the write for
+func (s *chapterAdminService) reindex(chapterID int, locale string) {
+	if s.search == nil {
+		return
+	}
+	if err := s.search.IndexTranslation(chapterID, locale); err != nil {
+		fmt.Printf("⚠️  Failed to update search index for chapter %d (%s): %v\n", chapterID, locale, err)
+	}
+}
+
+func validateChapter(c *models.Chapter) error {
+	switch {
+	case c.Number <= 0:
+		return fmt.Errorf("%w: number must be positive", ErrInvalidInput)
+	case strings.TrimSpace(c.Title) == "" || strings.TrimSpace(c.Description) == "":
+		return fmt.Errorf("%w: title and description must not be empty", ErrInvalidInput)
+	case !slugRe.MatchString(c.Slug):
+		return fmt.Errorf("%w: slug must be lowercase words separated by hyphens", ErrInvalidInput)
+	case c.Pages < 0 || c.ReadTime < 0:
+		return fmt.Errorf("%w: pages and read_time must not be negative", ErrInvalidInput)
+	}
+	return nil
+}
@@ -1,32 +1,140 @@
 package services
 
 import (
+	"fmt"
+
 	"github.com/whatisrealfreedom/freedom-website/internal/models"
 	"github.com/whatisrealfreedom/freedom-website/internal/repository"
 )
 
+// ChapterService serves chapters in the first available locale of a preference
+// list (see LocaleService.Preferences)
 type ChapterService interface {
-	GetAllChapters() ([]models.ChapterSummary, error)
-	GetChapterByID(id int) (*models.Chapter, error)
-	GetChapterBySlug(slug string) (*models.Chapter, error)
+	GetAllChapters(locales []string) ([]models.ChapterSummary, error)
+	GetChapterByID(id int, locales []string) (*models.Chapter, error)
+	GetChapterBySlug(slug string, locales []string) (*models.Chapter, error)
+	// GetChapterByLocaleSlug serves the translation with the given slug in that
+	// locale. A former slug yields a *SlugMovedError carrying the current one.
+	GetChapterByLocaleSlug(locale, slug string) (*models.Chapter, error)
+}
+
+// SlugMovedError reports that a chapter slug was renamed
+type SlugMovedError struct {
+	Locale string
+	Slug   string
+}
+
+func (e *SlugMovedError) Error() string {
+	return fmt.Sprintf("chapter moved to %s/%s", e.Locale, e.Slug)
 }
 
 type chapterService struct {
-	repo repository.ChapterRepository
+	repo          repository.ChapterRepository
+	defaultLocale string
+}
+
+// NewChapterService takes the locale of the legacy title/description/content
+// columns on chapters, used for chapters that have no translations at all
+func NewChapterService(repo repository.ChapterRepository, defaultLocale string) ChapterService {
+	return &chapterService{repo: repo, defaultLocale: defaultLocale}
+}
+
+func (s *chapterService) GetAllChapters(locales []string) ([]models.ChapterSummary, error) {
+	chapters, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	translations, err := s.repo.ListTranslationSummaries()
+	if err != nil {
+		return nil, err
+	}
+	byChapter := make(map[int][]models.ChapterTranslation)
+	for _, t := range translations {
+		byChapter[t.ChapterID] = append(byChapter[t.ChapterID], t)
+	}
+
+	for i := range chapters {
+		c := &chapters[i]
+		c.Locale = s.defaultLocale
+		if t := pickTranslation(byChapter[c.ID], locales); t != nil {
+			c.Title, c.Slug, c.Description, c.Locale = t.Title, t.Slug, t.Description, t.Locale
+		}
+	}
+
+	return chapters, nil
 }
 
-func NewChapterService(repo repository.ChapterRepository) ChapterService {
-	return &chapterService{repo: repo}
+func (s *chapterService) GetChapterByID(id int, locales []string) (*models.Chapter, error) {
+	chapter, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.localize(chapter, locales)
 }
 
-func (s *chapterService) GetAllChapters() ([]models.ChapterSummary, error) {
-	return s.repo.GetAll()
+func (s *chapterService) GetChapterBySlug(slug string, locales []string) (*models.Chapter, error) {
+	chapter, err := s.repo.GetBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	return s.localize(chapter, locales)
 }
 
-func (s *chapterService) GetChapterByID(id int) (*models.Chapter, error) {
-	return s.repo.GetByID(id)
+func (s *chapterService) GetChapterByLocaleSlug(locale, slug string) (*models.Chapter, error) {
+	translation, err := s.repo.GetTranslationBySlug(locale, slug)
+	if err != nil {
+		if current, redirectErr := s.repo.GetRedirectSlug(locale, slug); redirectErr == nil {
+			return nil, &SlugMovedError{Locale: locale, Slug: current}
+		}
+		return nil, err
+	}
+
+	chapter, err := s.repo.GetByID(translation.ChapterID)
+	if err != nil {
+		return nil, err
+	}
+	return s.localize(chapter, []string{translation.Locale})
+}
+
+func (s *chapterService) localize(chapter *models.Chapter, locales []string) (*models.Chapter, error) {
+	translations, err := s.repo.GetTranslations(chapter.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	chapter.ContentEn = ""
+	chapter.Locale = s.defaultLocale
+	chapter.AvailableLocales = []string{}
+	chapter.Slugs = make(map[string]string)
+	for _, t := range translations {
+		chapter.AvailableLocales = append(chapter.AvailableLocales, t.Locale)
+		chapter.Slugs[t.Locale] = t.Slug
+	}
+
+	if t := pickTranslation(translations, locales); t != nil {
+		chapter.Title = t.Title
+		chapter.Slug = t.Slug
+		chapter.Description = t.Description
+		chapter.Content = t.Content
+		chapter.Locale = t.Locale
+	}
+
+	return chapter, nil
 }
 
-func (s *chapterService) GetChapterBySlug(slug string) (*models.Chapter, error) {
-	return s.repo.GetBySlug(slug)
+// pickTranslation returns the translation for the first preferred locale that
+// exists, or any translation when none of them do
+func pickTranslation(translations []models.ChapterTranslation, locales []string) *models.ChapterTranslation {
+	for _, l := range locales {
+		for i := range translations {
+			if translations[i].Locale == l {
+				return &translations[i]
+			}
+		}
+	}
+	if len(translations) > 0 {
+		return &translations[0]
+	}
+	return nil
 }
@@ -3,21 +3,46 @@ package services
 import (
 	"fmt"
 	"math/rand"
-	"strconv"
 	"time"
 
 	"github.com/whatisrealfreedom/freedom-website/internal/config"
-	"gopkg.in/mail.v2"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
 )
 
+// EmailService composes emails and queues them in the outbox; OutboxWorker
+// delivers them, so a mail outage never fails a request. Emails are rendered
+// from the templates in email_templates, in the recipient's language.
 type EmailService struct {
-	config *config.Config
+	config    *config.Config
+	outbox    repository.OutboxRepository
+	locales   LocaleService
+	templates *EmailTemplates
 }
 
-func NewEmailService(cfg *config.Config) *EmailService {
-	return &EmailService{
-		config: cfg,
+// DigestItem is one entry of a notification digest
+type DigestItem struct {
+	Title   string
+	URL     string
+	Summary string
+}
+
+// NewEmailService picks each recipient's locale with locales: the user's
+// language, then the fallback chain. A nil locales uses LOCALE_FALLBACK only.
+func NewEmailService(cfg *config.Config, outbox repository.OutboxRepository, locales LocaleService) (*EmailService, error) {
+	templates, err := LoadEmailTemplates()
+	if err != nil {
+		return nil, err
+	}
+	if locales == nil {
+		locales = NewLocaleService(nil, cfg.LocaleFallback)
 	}
+	return &EmailService{
+		config:    cfg,
+		outbox:    outbox,
+		locales:   locales,
+		templates: templates,
+	}, nil
 }
 
 // GenerateVerificationCode generates a random 5-digit code
@@ -27,55 +52,74 @@ func (es *EmailService) GenerateVerificationCode() string {
 	return fmt.Sprintf("%05d", code)
 }
 
-// SendVerificationEmail sends a verification code email
-func (es *EmailService) SendVerificationEmail(to, code string) error {
-	if es.config.MailUsername == "" || es.config.MailPassword == "" {
-		return fmt.Errorf("email configuration is missing")
-	}
+// SendVerificationEmail queues a verification code email
+func (es *EmailService) SendVerificationEmail(userID int64, to, code string) error {
+	return es.send(userID, to, EmailVerify, codeEmail(code))
+}
 
-	m := mail.NewMessage()
-	m.SetHeader("From", fmt.Sprintf("%s <%s>", es.config.MailFromName, es.config.MailFromAddress))
-	m.SetHeader("To", to)
-	m.SetHeader("Subject", "Verify Your Email - RealFreedom")
+// SendPasswordResetEmail queues a password reset code email
+func (es *EmailService) SendPasswordResetEmail(userID int64, to, code string) error {
+	return es.send(userID, to, EmailPasswordReset, codeEmail(code))
+}
 
-	body := fmt.Sprintf(`
-		<html>
-		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
-			<div style="max-width: 600px; margin: 0 auto; padding: 20px;">
-				<h1 style="color: #2563eb;">RealFreedom</h1>
-				<h2 style="color: #1e40af;">Email Verification</h2>
-				<p>Thank you for registering with RealFreedom!</p>
-				<p>Please use the following code to verify your email address:</p>
-				<div style="background-color: #f3f4f6; border: 2px solid #2563eb; border-radius: 8px; padding: 20px; text-align: center; margin: 20px 0;">
-					<h1 style="color: #2563eb; font-size: 32px; letter-spacing: 5px; margin: 0;">%s</h1>
-				</div>
-				<p>This code will expire in 15 minutes.</p>
-				<p>If you didn't create an account with RealFreedom, please ignore this email.</p>
-				<hr style="border: none; border-top: 1px solid #e5e7eb; margin: 20px 0;">
-				<p style="color: #6b7280; font-size: 12px;">© RealFreedom - All rights reserved</p>
-			</div>
-		</body>
-		</html>
-	`, code)
+// SendEmailChangeEmail queues the code that confirms a new address; to is the
+// new address
+func (es *EmailService) SendEmailChangeEmail(userID int64, to, code string) error {
+	return es.send(userID, to, EmailChange, codeEmail(code))
+}
 
-	m.SetBody("text/html", body)
+// SendEmailChangeNotice tells the current address that a change to newEmail
+// was requested
+func (es *EmailService) SendEmailChangeNotice(userID int64, to, newEmail string) error {
+	return es.send(userID, to, EmailChangeNotice, map[string]interface{}{"NewEmail": newEmail})
+}
 
-	port, err := strconv.Atoi(es.config.MailPort)
-	if err != nil {
-		return fmt.Errorf("invalid mail port: %w", err)
-	}
+// SendReauthCode queues the code that confirms a sensitive change, such as
+// deleting the account
+func (es *EmailService) SendReauthCode(userID int64, to, code string) error {
+	return es.send(userID, to, EmailReauth, codeEmail(code))
+}
 
-	d := mail.NewDialer(es.config.MailHost, port, es.config.MailUsername, es.config.MailPassword)
+// SendDigest queues a notification digest. An empty digest is not sent.
+func (es *EmailService) SendDigest(userID int64, to string, items []DigestItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return es.send(userID, to, EmailDigest, map[string]interface{}{"Items": items})
+}
 
-	if es.config.MailEncryption == "tls" {
-		d.StartTLSPolicy = mail.MandatoryStartTLS
-	} else if es.config.MailEncryption == "ssl" {
-		d.SSL = true
+func codeEmail(code string) map[string]interface{} {
+	return map[string]interface{}{
+		"Code":    code,
+		"Minutes": int(models.CodeTTL / time.Minute),
 	}
+}
 
-	if err := d.DialAndSend(m); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+// Locale returns the locale emails to userID are written in: the first of
+// their preferences with templates
+func (es *EmailService) Locale(userID int64) string {
+	for _, l := range es.locales.Preferences("", "", userID) {
+		if es.templates.Has(l) {
+			return l
+		}
 	}
+	return "en"
+}
 
-	return nil
+// send renders kind for the recipient and queues it with its plain text part.
+// Users who signed up with Telegram have no address to send to.
+func (es *EmailService) send(userID int64, to, kind string, data interface{}) error {
+	if models.IsPlaceholderEmail(to) {
+		return nil
+	}
+	email, err := es.templates.Render(es.Locale(userID), kind, data)
+	if err != nil {
+		return err
+	}
+	return es.outbox.Enqueue(&models.OutboxEmail{
+		Recipient: to,
+		Subject:   email.Subject,
+		HTMLBody:  email.HTML,
+		TextBody:  email.Text,
+	})
 }
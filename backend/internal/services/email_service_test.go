@@ -0,0 +1,86 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/config"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+func TestEmailTemplates_AllKindsInEveryLocale(t *testing.T) {
+	templates, err := LoadEmailTemplates()
+	require.NoError(t, err)
+
+	data := map[string]interface{}{"Code": "12345", "Minutes": 15, "NewEmail": "new@example.com", "Items": []DigestItem{{Title: "T", URL: "https://example.com"}}}
+	for _, locale := range []string{"en", "fa"} {
+		for _, kind := range emailKinds {
+			email, err := templates.Render(locale, kind, data)
+			require.NoError(t, err, "%s/%s", locale, kind)
+			assert.NotEmpty(t, email.Subject, "%s/%s", locale, kind)
+			assert.Contains(t, email.HTML, `lang="`+locale+`"`)
+			assert.NotContains(t, email.HTML, "ZgotmplZ", "%s/%s has an unsafe value", locale, kind)
+			assert.NotContains(t, email.Text, "<")
+		}
+	}
+}
+
+func TestEmailService_UsesRecipientLanguage(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=1")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, repository.RunMigrations(db, repository.DialectSQLite, "../../migrations"))
+
+	users := repository.NewUserRepository(db)
+	outbox := repository.NewOutboxRepository(db)
+	emails, err := NewEmailService(&config.Config{}, outbox, NewLocaleService(users, "fa,en"))
+	require.NoError(t, err)
+
+	reader := &models.User{Email: "reader@example.com", Password: "password"}
+	require.NoError(t, users.Create(reader))
+	_, err = db.Exec("UPDATE users SET language_id = (SELECT id FROM languages WHERE code = 'en') WHERE id = ?", reader.ID)
+	require.NoError(t, err)
+
+	// English reader, and a visitor without a language who gets the Persian default
+	require.NoError(t, emails.SendVerificationEmail(reader.ID, reader.Email, "12345"))
+	require.NoError(t, emails.SendPasswordResetEmail(0, "visitor@example.com", "54321"))
+
+	en, err := outbox.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, "Verify Your Email - RealFreedom", en.Subject)
+	assert.Contains(t, en.HTMLBody, `dir="ltr"`)
+	assert.Contains(t, en.TextBody, "12345")
+	assert.Contains(t, en.TextBody, "expire in 15 minutes")
+
+	fa, err := outbox.Get(2)
+	require.NoError(t, err)
+	assert.Equal(t, "بازیابی رمز عبور - RealFreedom", fa.Subject)
+	assert.Contains(t, fa.HTMLBody, `dir="rtl"`)
+	assert.Contains(t, fa.HTMLBody, "text-align: right")
+	assert.Contains(t, fa.HTMLBody, `<div dir="ltr"`, "the code reads left to right inside RTL text")
+	assert.Contains(t, fa.TextBody, "54321")
+}
+
+func TestEmailService_SendDigest(t *testing.T) {
+	outbox := setupOutbox(t)
+	emails, err := NewEmailService(&config.Config{LocaleFallback: "en"}, outbox, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, emails.SendDigest(0, "reader@example.com", nil))
+	_, err = outbox.Get(1)
+	assert.ErrorIs(t, err, repository.ErrNotFound, "an empty digest is not sent")
+
+	require.NoError(t, emails.SendDigest(0, "reader@example.com", []DigestItem{
+		{Title: "Freedom & <faith>", URL: "https://example.com/threads/1", Summary: "3 new replies"},
+	}))
+	digest, err := outbox.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, "What's new on RealFreedom (1)", digest.Subject)
+	assert.Contains(t, digest.HTMLBody, "Freedom &amp; &lt;faith&gt;")
+	assert.Contains(t, digest.TextBody, "Freedom & <faith>")
+	assert.Contains(t, digest.TextBody, "https://example.com/threads/1")
+}
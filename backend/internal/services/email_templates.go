@@ -0,0 +1,132 @@
+package services
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Email kinds, each with a <kind>.html and <kind>.txt per locale
+const (
+	EmailVerify        = "verify_email"
+	EmailPasswordReset = "password_reset"
+	EmailDigest        = "digest"
+	EmailChange        = "email_change"
+	EmailChangeNotice  = "email_change_notice"
+	EmailReauth        = "reauth_code"
+)
+
+// emailKinds lists every kind; each locale must have templates for all of them
+var emailKinds = []string{EmailVerify, EmailPasswordReset, EmailDigest, EmailChange, EmailChangeNotice, EmailReauth}
+
+//go:embed email_templates
+var emailTemplateFS embed.FS
+
+// rtlLocales are laid out right to left
+var rtlLocales = map[string]bool{"fa": true, "ar": true, "he": true, "ur": true}
+
+// EmailTemplates renders the embedded email templates. Every locale directory
+// under email_templates defines "subject" and "content" for each kind in both
+// an HTML and a plain text version; layout.html and layout.txt wrap them.
+type EmailTemplates struct {
+	html map[string]map[string]*htmltemplate.Template
+	text map[string]map[string]*texttemplate.Template
+}
+
+// RenderedEmail is one email in both formats
+type RenderedEmail struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// emailTemplateData is what templates see: the kind-specific values under
+// .Data plus the locale's direction
+type emailTemplateData struct {
+	Locale string
+	Dir    string
+	// Start is the side text starts from, "left" or "right"
+	Start string
+	Data  interface{}
+}
+
+// LoadEmailTemplates parses the embedded templates
+func LoadEmailTemplates() (*EmailTemplates, error) {
+	root, err := fs.Sub(emailTemplateFS, "email_templates")
+	if err != nil {
+		return nil, err
+	}
+	locales, err := fs.ReadDir(root, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email templates: %w", err)
+	}
+
+	t := &EmailTemplates{
+		html: make(map[string]map[string]*htmltemplate.Template),
+		text: make(map[string]map[string]*texttemplate.Template),
+	}
+	for _, dir := range locales {
+		if !dir.IsDir() {
+			continue
+		}
+		locale := dir.Name()
+		t.html[locale] = make(map[string]*htmltemplate.Template)
+		t.text[locale] = make(map[string]*texttemplate.Template)
+
+		for _, kind := range emailKinds {
+			h, err := htmltemplate.ParseFS(root, "layout.html", locale+"/common.tmpl", locale+"/"+kind+".html")
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s/%s.html: %w", locale, kind, err)
+			}
+			x, err := texttemplate.ParseFS(root, "layout.txt", locale+"/common.tmpl", locale+"/"+kind+".txt")
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s/%s.txt: %w", locale, kind, err)
+			}
+			t.html[locale][kind] = h
+			t.text[locale][kind] = x
+		}
+	}
+
+	return t, nil
+}
+
+// Has reports whether there are templates for locale
+func (t *EmailTemplates) Has(locale string) bool {
+	_, ok := t.html[locale]
+	return ok
+}
+
+// Render renders kind in locale with data
+func (t *EmailTemplates) Render(locale, kind string, data interface{}) (*RenderedEmail, error) {
+	h, ok := t.html[locale][kind]
+	if !ok {
+		return nil, fmt.Errorf("no %s email template for locale %q", kind, locale)
+	}
+	x := t.text[locale][kind]
+
+	d := emailTemplateData{Locale: locale, Dir: "ltr", Start: "left", Data: data}
+	if rtlLocales[locale] {
+		d.Dir, d.Start = "rtl", "right"
+	}
+
+	var subject, html, text bytes.Buffer
+	if err := x.ExecuteTemplate(&subject, "subject", d); err != nil {
+		return nil, fmt.Errorf("failed to render %s subject: %w", kind, err)
+	}
+	if err := h.ExecuteTemplate(&html, "layout", d); err != nil {
+		return nil, fmt.Errorf("failed to render %s email: %w", kind, err)
+	}
+	if err := x.ExecuteTemplate(&text, "layout", d); err != nil {
+		return nil, fmt.Errorf("failed to render %s email: %w", kind, err)
+	}
+
+	return &RenderedEmail{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+	}, nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"golang.org/x/text/language"
+)
+
+// LocaleService negotiates which chapter translation to serve
+type LocaleService interface {
+	// Preferences returns the locales to try, most preferred first: the explicit
+	// ?locale, the signed-in user's language, Accept-Language (by q-value) and
+	// finally the configured fallback chain
+	Preferences(requested, acceptLanguage string, userID int64) []string
+	// Default is the first locale of the fallback chain
+	Default() string
+}
+
+type localeService struct {
+	users    repository.UserRepository
+	fallback []string
+}
+
+// NewLocaleService takes the comma-separated fallback chain, e.g. "fa,en".
+// users may be nil, in which case the user's language is ignored.
+func NewLocaleService(users repository.UserRepository, fallback string) LocaleService {
+	var chain []string
+	for _, l := range strings.Split(fallback, ",") {
+		if l = NormalizeLocale(l); l != "" {
+			chain = append(chain, l)
+		}
+	}
+	if len(chain) == 0 {
+		chain = []string{"fa"}
+	}
+	return &localeService{users: users, fallback: chain}
+}
+
+// NormalizeLocale reduces a BCP 47 tag to its base language ("en-US" -> "en").
+// Invalid tags yield "".
+func NormalizeLocale(tag string) string {
+	t, err := language.Parse(strings.TrimSpace(tag))
+	if err != nil || t == language.Und {
+		return ""
+	}
+	base, _ := t.Base()
+	return base.String()
+}
+
+func (s *localeService) Preferences(requested, acceptLanguage string, userID int64) []string {
+	var prefs []string
+	seen := make(map[string]bool)
+	add := func(l string) {
+		if l != "" && !seen[l] {
+			seen[l] = true
+			prefs = append(prefs, l)
+		}
+	}
+
+	add(NormalizeLocale(requested))
+
+	if userID != 0 && s.users != nil {
+		if code, err := s.users.GetLanguageCode(userID); err == nil {
+			add(NormalizeLocale(code))
+		}
+	}
+
+	if acceptLanguage != "" {
+		// Tags come back sorted by q-value; q=0 means "not acceptable"
+		tags, q, _ := language.ParseAcceptLanguage(acceptLanguage)
+		for i, t := range tags {
+			if q[i] > 0 {
+				base, _ := t.Base()
+				add(base.String())
+			}
+		}
+	}
+
+	for _, l := range s.fallback {
+		add(l)
+	}
+
+	return prefs
+}
+
+func (s *localeService) Default() string {
+	return s.fallback[0]
+}
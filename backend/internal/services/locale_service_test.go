@@ -0,0 +1,27 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+)
+
+func TestLocaleService_Preferences(t *testing.T) {
+	s := NewLocaleService(nil, "fa, en")
+
+	assert.Equal(t, []string{"fa", "en"}, s.Preferences("", "", 0))
+	assert.Equal(t, []string{"en", "fa"}, s.Preferences("en-US", "", 0))
+	assert.Equal(t, []string{"de", "en", "fa"}, s.Preferences("", "en;q=0.5, de-DE, fr;q=0", 0))
+	assert.Equal(t, []string{"ar", "de", "fa", "en"}, s.Preferences("ar", "de", 0))
+	assert.Equal(t, []string{"fa", "en"}, s.Preferences("not a locale!", "", 0))
+	assert.Equal(t, "fa", s.Default())
+}
+
+func TestPickTranslation(t *testing.T) {
+	translations := []models.ChapterTranslation{{Locale: "en"}, {Locale: "fa"}}
+
+	assert.Equal(t, "fa", pickTranslation(translations, []string{"de", "fa", "en"}).Locale)
+	assert.Equal(t, "en", pickTranslation(translations, []string{"de"}).Locale)
+	assert.Nil(t, pickTranslation(nil, []string{"fa"}))
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/config"
+	"gopkg.in/mail.v2"
+)
+
+// Message is one email to deliver
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+	// Text is the plain text alternative, optional
+	Text string
+}
+
+// Mailer delivers email. Requests never call it directly: they queue through
+// EmailService and OutboxWorker delivers.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// NewMailer picks the transport from MAIL_TRANSPORT: "smtp", "log" (stdout) or
+// "file" (MAIL_LOG_FILE). Without MAIL_TRANSPORT it is smtp when credentials
+// are configured and log otherwise.
+func NewMailer(cfg *config.Config, stdout io.Writer) (Mailer, error) {
+	transport := cfg.MailTransport
+	if transport == "" {
+		transport = "log"
+		if cfg.MailUsername != "" && cfg.MailPassword != "" {
+			transport = "smtp"
+		}
+	}
+
+	switch transport {
+	case "smtp":
+		return NewSMTPMailer(cfg), nil
+	case "log":
+		return NewLogMailer(stdout), nil
+	case "file":
+		if cfg.MailLogFile == "" {
+			return nil, fmt.Errorf("MAIL_TRANSPORT=file needs MAIL_LOG_FILE")
+		}
+		return NewFileMailer(cfg.MailLogFile), nil
+	}
+	return nil, fmt.Errorf("unknown MAIL_TRANSPORT %q", transport)
+}
+
+// SMTPMailer sends through the configured SMTP server
+type SMTPMailer struct {
+	config *config.Config
+}
+
+func NewSMTPMailer(cfg *config.Config) *SMTPMailer {
+	return &SMTPMailer{config: cfg}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	if m.config.MailUsername == "" || m.config.MailPassword == "" {
+		return fmt.Errorf("email configuration is missing")
+	}
+
+	message := mail.NewMessage()
+	message.SetHeader("From", fmt.Sprintf("%s <%s>", m.config.MailFromName, m.config.MailFromAddress))
+	message.SetHeader("To", msg.To)
+	message.SetHeader("Subject", msg.Subject)
+	if msg.Text != "" {
+		message.SetBody("text/plain", msg.Text)
+		message.AddAlternative("text/html", msg.HTML)
+	} else {
+		message.SetBody("text/html", msg.HTML)
+	}
+
+	port, err := strconv.Atoi(m.config.MailPort)
+	if err != nil {
+		return fmt.Errorf("invalid mail port: %w", err)
+	}
+
+	d := mail.NewDialer(m.config.MailHost, port, m.config.MailUsername, m.config.MailPassword)
+
+	if m.config.MailEncryption == "tls" {
+		d.StartTLSPolicy = mail.MandatoryStartTLS
+	} else if m.config.MailEncryption == "ssl" {
+		d.SSL = true
+	}
+
+	if err := d.DialAndSend(message); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// LogMailer writes emails to a writer instead of sending them, for development
+type LogMailer struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func NewLogMailer(out io.Writer) *LogMailer {
+	return &LogMailer{out: out}
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	body := msg.Text
+	if body == "" {
+		body = msg.HTML
+	}
+	_, err := fmt.Fprintf(m.out, "📧 To: %s\nSubject: %s\n\n%s\n\n", msg.To, msg.Subject, body)
+	return err
+}
+
+// FileMailer appends emails to a file, like LogMailer
+type FileMailer struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileMailer(path string) *FileMailer {
+	return &FileMailer{path: path}
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open mail log: %w", err)
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "--- %s\n", time.Now().Format(time.RFC3339))
+	return NewLogMailer(f).Send(msg)
+}
+
+// MemoryMailer keeps sent emails in memory, for tests
+type MemoryMailer struct {
+	mu   sync.Mutex
+	sent []Message
+	// Err, when set, fails every send
+	Err error
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Sent returns the emails sent so far
+func (m *MemoryMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.sent...)
+}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"golang.org/x/oauth2"
+)
+
+// ErrInvalidOAuthLogin is returned when a provider rejects the code or sends
+// back an identity that does not check out
+var ErrInvalidOAuthLogin = errors.New("invalid OAuth login")
+
+// OAuthProvider is an OAuth2 or OpenID Connect provider users sign in with.
+// The caller generates state, nonce and the PKCE verifier and keeps them
+// until the callback.
+type OAuthProvider interface {
+	Name() string
+	// AuthCodeURL is the provider page the user is sent to
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	// Exchange redeems the code from the callback and returns the user
+	Exchange(ctx context.Context, code, nonce, verifier string) (*models.ExternalUser, error)
+}
+
+// OAuthClientConfig is the registration of this site at a provider
+type OAuthClientConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// oauthHTTPTimeout bounds every request to a provider
+const oauthHTTPTimeout = 10 * time.Second
+
+// OIDCProvider is an OpenID Connect provider, configured by discovery from its
+// issuer URL. The ID token is verified against the provider's published keys.
+type OIDCProvider struct {
+	name   string
+	issuer string
+	client OAuthClientConfig
+	scopes []string
+	http   *http.Client
+
+	mu       sync.Mutex
+	metadata *oidcMetadata
+	keys     map[string]crypto.PublicKey
+	keysAt   time.Time
+}
+
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// GoogleIssuer is the OpenID Connect issuer of Google accounts
+const GoogleIssuer = "https://accounts.google.com"
+
+// NewOIDCProvider configures an OpenID Connect provider. Discovery happens on
+// first use, so an unreachable provider does not stop the server starting.
+func NewOIDCProvider(name, issuer string, client OAuthClientConfig) *OIDCProvider {
+	return &OIDCProvider{
+		name:   name,
+		issuer: strings.TrimSuffix(issuer, "/"),
+		client: client,
+		scopes: []string{"openid", "email", "profile"},
+		http:   &http.Client{Timeout: oauthHTTPTimeout},
+	}
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.name
+}
+
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var metadata oidcMetadata
+	if err := getJSON(ctx, p.http, p.issuer+"/.well-known/openid-configuration", "", &metadata); err != nil {
+		return nil, fmt.Errorf("failed to discover %s: %w", p.name, err)
+	}
+	// The document must be about the issuer we were configured with
+	if strings.TrimSuffix(metadata.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("failed to discover %s: issuer is %q", p.name, metadata.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("failed to discover %s: incomplete metadata", p.name)
+	}
+	p.metadata = &metadata
+	return p.metadata, nil
+}
+
+func (p *OIDCProvider) config(metadata *oidcMetadata) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.client.ClientID,
+		ClientSecret: p.client.ClientSecret,
+		RedirectURL:  p.client.RedirectURL,
+		Scopes:       p.scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  metadata.AuthorizationEndpoint,
+			TokenURL: metadata.TokenEndpoint,
+		},
+	}
+}
+
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.config(metadata).AuthCodeURL(state,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	), nil
+}
+
+func (p *OIDCProvider) Exchange(ctx context.Context, code, nonce, verifier string) (*models.ExternalUser, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.config(metadata).Exchange(context.WithValue(ctx, oauth2.HTTPClient, p.http), code,
+		oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOAuthLogin, err)
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, fmt.Errorf("%w: no ID token", ErrInvalidOAuthLogin)
+	}
+
+	return p.verifyIDToken(ctx, metadata, rawIDToken, nonce)
+}
+
+// idTokenClaims are the ID token claims we read; registered claims are
+// checked by jwt.Parse
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce           string `json:"nonce"`
+	AuthorizedParty string `json:"azp"`
+	Email           string `json:"email"`
+	EmailVerified   any    `json:"email_verified"` // some providers send "true"
+	Name            string `json:"name"`
+	Picture         string `json:"picture"`
+}
+
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, metadata *oidcMetadata, raw, nonce string) (*models.ExternalUser, error) {
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, metadata, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(p.client.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOAuthLogin, err)
+	}
+	// The nonce ties the token to the login this browser started
+	if claims.Nonce == "" || claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidOAuthLogin)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.client.ClientID {
+		return nil, fmt.Errorf("%w: token issued to another client", ErrInvalidOAuthLogin)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidOAuthLogin)
+	}
+
+	verified := false
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		verified = v
+	case string:
+		verified, _ = strconv.ParseBool(v)
+	}
+	return &models.ExternalUser{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: verified,
+		Name:          claims.Name,
+		PictureURL:    claims.Picture,
+	}, nil
+}
+
+// jwksRefreshInterval limits refetching the keys for unknown key IDs, which
+// anyone can put in a token
+const jwksRefreshInterval = time.Minute
+
+// key returns the provider's signing key kid, refetching the key set when
+// the provider has rotated keys
+func (p *OIDCProvider) key(ctx context.Context, metadata *oidcMetadata, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := lookupKey(p.keys, kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	keys, err := fetchJWKS(ctx, p.http, metadata.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+	p.keysAt = time.Now()
+
+	if key, ok := lookupKey(p.keys, kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func lookupKey(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if key, ok := keys[kid]; ok {
+		return key, true
+	}
+	// Providers with a single key may leave kid out of the token
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// fetchJWKS reads the RSA and P-256 signing keys of a JSON Web Key Set
+func fetchJWKS(ctx context.Context, client *http.Client, url string) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := getJSON(ctx, client, url, "", &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if k.Crv != "P-256" || errX != nil || errY != nil {
+				continue
+			}
+			key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+			if !key.Curve.IsOnCurve(key.X, key.Y) {
+				continue
+			}
+			keys[k.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("failed to fetch signing keys: no usable keys")
+	}
+	return keys, nil
+}
+
+// GitHubProvider signs users in with GitHub, which speaks OAuth2 but not
+// OpenID Connect: the user is read from its REST API.
+type GitHubProvider struct {
+	client OAuthClientConfig
+	http   *http.Client
+	// Endpoints, overridable for tests
+	AuthURL  string
+	TokenURL string
+	APIURL   string
+}
+
+func NewGitHubProvider(client OAuthClientConfig) *GitHubProvider {
+	return &GitHubProvider{
+		client:   client,
+		http:     &http.Client{Timeout: oauthHTTPTimeout},
+		AuthURL:  "https://github.com/login/oauth/authorize",
+		TokenURL: "https://github.com/login/oauth/access_token",
+		APIURL:   "https://api.github.com",
+	}
+}
+
+func (p *GitHubProvider) Name() string {
+	return models.ProviderGitHub
+}
+
+func (p *GitHubProvider) config() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.client.ClientID,
+		ClientSecret: p.client.ClientSecret,
+		RedirectURL:  p.client.RedirectURL,
+		Scopes:       []string{"read:user", "user:email"},
+		Endpoint:     oauth2.Endpoint{AuthURL: p.AuthURL, TokenURL: p.TokenURL},
+	}
+}
+
+// AuthCodeURL ignores nonce: without an ID token, state and PKCE protect the
+// login
+func (p *GitHubProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	return p.config().AuthCodeURL(state, oauth2.S256ChallengeOption(verifier)), nil
+}
+
+func (p *GitHubProvider) Exchange(ctx context.Context, code, nonce, verifier string) (*models.ExternalUser, error) {
+	token, err := p.config().Exchange(context.WithValue(ctx, oauth2.HTTPClient, p.http), code,
+		oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOAuthLogin, err)
+	}
+
+	var profile struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := getJSON(ctx, p.http, p.APIURL+"/user", token.AccessToken, &profile); err != nil {
+		return nil, fmt.Errorf("failed to get GitHub user: %w", err)
+	}
+	if profile.ID == 0 {
+		return nil, fmt.Errorf("%w: no GitHub user ID", ErrInvalidOAuthLogin)
+	}
+
+	// The profile email is whatever the user made public; /user/emails says
+	// which addresses GitHub has verified
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, p.http, p.APIURL+"/user/emails", token.AccessToken, &emails); err != nil {
+		return nil, fmt.Errorf("failed to get GitHub emails: %w", err)
+	}
+
+	user := &models.ExternalUser{
+		Subject:    strconv.FormatInt(profile.ID, 10),
+		Name:       profile.Name,
+		PictureURL: profile.AvatarURL,
+	}
+	if user.Name == "" {
+		user.Name = profile.Login
+	}
+	for _, e := range emails {
+		if e.Primary {
+			user.Email = e.Email
+			user.EmailVerified = e.Verified
+		}
+	}
+	return user, nil
+}
+
+// getJSON GETs url, with accessToken as a bearer token when set, and decodes
+// the JSON response into v
+func getJSON(ctx context.Context, client *http.Client, url, accessToken string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrUnknownProvider is returned for providers that are not configured
+	ErrUnknownProvider = fmt.Errorf("oauth provider %w", repository.ErrNotFound)
+	// ErrInvalidOAuthState is returned for callbacks whose state is unknown,
+	// expired, already used or started for another provider or user
+	ErrInvalidOAuthState = fmt.Errorf("%w: invalid or expired OAuth state, start the login again", ErrInvalidInput)
+	// ErrOAuthEmailUnverified is returned when a new account would be created
+	// for an email the provider has not verified
+	ErrOAuthEmailUnverified = fmt.Errorf("%w: the provider has no verified email for this account", ErrInvalidInput)
+	// ErrOAuthEmailTaken is returned when signing in with a provider account
+	// whose email already has an account here: it must be linked from that
+	// account, so nobody takes over an account through a provider
+	ErrOAuthEmailTaken = fmt.Errorf("%w: an account with this email exists; sign in and link the provider from your account", repository.ErrDuplicate)
+	// ErrLastSignInMethod is returned when unlinking would leave a user with
+	// no way to sign in
+	ErrLastSignInMethod = fmt.Errorf("%w: this is the only way this account signs in", ErrInvalidInput)
+)
+
+// OAuthService signs users in with OAuth providers and links provider
+// accounts to users
+type OAuthService interface {
+	// Providers lists the configured providers by name
+	Providers() []string
+	// StartLogin returns the provider URL that starts signing in.
+	// referralCode only applies if a new account is created.
+	StartLogin(ctx context.Context, provider, referralCode string) (string, error)
+	// StartLink returns the provider URL that starts linking an account to userID
+	StartLink(ctx context.Context, provider string, userID int64) (string, error)
+	// SignIn completes a login started by StartLogin and returns the user
+	// linked to the provider account, registering one when there is none;
+	// created tells which
+	SignIn(ctx context.Context, provider, code, state string) (user *models.User, created bool, err error)
+	// Link completes linking started by StartLink
+	Link(ctx context.Context, userID int64, provider, code, state string) (*models.Identity, error)
+	Identities(userID int64) ([]models.Identity, error)
+	Unlink(userID int64, provider string) error
+}
+
+type oauthService struct {
+	providers  map[string]OAuthProvider
+	identities repository.IdentityRepository
+	users      repository.UserRepository
+	referrals  ReferralService
+	now        func() time.Time
+}
+
+func NewOAuthService(identities repository.IdentityRepository, users repository.UserRepository, referrals ReferralService, providers ...OAuthProvider) OAuthService {
+	byName := make(map[string]OAuthProvider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
+	return &oauthService{providers: byName, identities: identities, users: users, referrals: referrals, now: time.Now}
+}
+
+func (s *oauthService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *oauthService) provider(name string) (OAuthProvider, error) {
+	if p, ok := s.providers[name]; ok {
+		return p, nil
+	}
+	return nil, ErrUnknownProvider
+}
+
+func (s *oauthService) StartLogin(ctx context.Context, provider, referralCode string) (string, error) {
+	// Reject a bad code now rather than after the round trip to the provider
+	if _, err := s.referrals.Inviter(referralCode); err != nil {
+		return "", err
+	}
+	return s.start(ctx, provider, nil, strings.TrimSpace(referralCode))
+}
+
+func (s *oauthService) StartLink(ctx context.Context, provider string, userID int64) (string, error) {
+	return s.start(ctx, provider, &userID, "")
+}
+
+func (s *oauthService) start(ctx context.Context, name string, userID *int64, referralCode string) (string, error) {
+	p, err := s.provider(name)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := randomToken(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomToken(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	url, err := p.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", err
+	}
+
+	now := s.now()
+	if err := s.identities.DeleteExpiredStates(); err != nil {
+		fmt.Printf("⚠️  Failed to delete expired OAuth states: %v\n", err)
+	}
+	err = s.identities.SaveState(hashToken(state), &models.OAuthState{
+		Provider:     name,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		UserID:       userID,
+		ReferralCode: referralCode,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(models.OAuthStateTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return url, nil
+}
+
+// finish checks the state of a callback and exchanges its code. userID is
+// the user linking, or nil when signing in.
+func (s *oauthService) finish(ctx context.Context, name, code, state string, userID *int64) (*models.OAuthState, *models.ExternalUser, error) {
+	p, err := s.provider(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	saved, err := s.identities.TakeState(hashToken(state))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, ErrInvalidOAuthState
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if saved.Provider != name || (saved.UserID == nil) != (userID == nil) ||
+		(userID != nil && *saved.UserID != *userID) {
+		return nil, nil, ErrInvalidOAuthState
+	}
+
+	external, err := p.Exchange(ctx, code, saved.Nonce, saved.CodeVerifier)
+	if err != nil {
+		return nil, nil, err
+	}
+	return saved, external, nil
+}
+
+func (s *oauthService) SignIn(ctx context.Context, provider, code, state string) (*models.User, bool, error) {
+	saved, external, err := s.finish(ctx, provider, code, state, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	user, err := s.linkedUser(provider, external.Subject)
+	if err == nil || !errors.Is(err, repository.ErrNotFound) {
+		return user, false, err
+	}
+
+	if !external.EmailVerified || external.Email == "" {
+		return nil, false, ErrOAuthEmailUnverified
+	}
+	if _, err := s.users.GetByEmail(external.Email); err == nil {
+		return nil, false, ErrOAuthEmailTaken
+	}
+
+	invitedBy, err := s.referrals.Inviter(saved.ReferralCode)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Nobody knows this password; the user signs in with the provider, or
+	// sets a password through password reset
+	password, err := randomToken(32, hex.EncodeToString)
+	if err != nil {
+		return nil, false, err
+	}
+	source := provider
+	user = &models.User{
+		Email:           external.Email,
+		Password:        password,
+		IsActive:        true,
+		InvitedBy:       invitedBy,
+		RegistrationSrc: &source,
+	}
+	if name := strings.TrimSpace(external.Name); name != "" {
+		user.Name = &name
+	}
+	if strings.HasPrefix(external.PictureURL, "https://") {
+		photo := external.PictureURL
+		user.PhotoURL = &photo
+	}
+
+	if err := s.users.Create(user); err != nil {
+		// Another callback for the same account signed it up first
+		if errors.Is(err, repository.ErrDuplicate) {
+			if existing, getErr := s.linkedUser(provider, external.Subject); getErr == nil {
+				return existing, false, nil
+			}
+			return nil, false, ErrOAuthEmailTaken
+		}
+		return nil, false, err
+	}
+	// The provider verified the email, so we do not send a code
+	if err := s.users.VerifyEmail(user.ID); err != nil {
+		return nil, false, err
+	}
+	if err := s.identities.Create(&models.Identity{
+		UserID: user.ID, Provider: provider, Subject: external.Subject, Email: external.Email,
+	}); err != nil {
+		return nil, false, err
+	}
+	if err := s.referrals.CreditVerified(user); err != nil {
+		fmt.Printf("⚠️  Failed to credit referral for user %d: %v\n", user.ID, err)
+	}
+
+	created, err := s.users.GetByID(user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	return created, true, nil
+}
+
+// linkedUser returns the user a provider account is linked to
+func (s *oauthService) linkedUser(provider, subject string) (*models.User, error) {
+	identity, err := s.identities.Get(provider, subject)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.identities.TouchLogin(identity.ID); err != nil {
+		return nil, err
+	}
+	return s.users.GetByID(identity.UserID)
+}
+
+func (s *oauthService) Link(ctx context.Context, userID int64, provider, code, state string) (*models.Identity, error) {
+	_, external, err := s.finish(ctx, provider, code, state, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Linking the same account again is not an error
+	if existing, err := s.identities.Get(provider, external.Subject); err == nil && existing.UserID == userID {
+		return existing, nil
+	}
+
+	identity := &models.Identity{UserID: userID, Provider: provider, Subject: external.Subject, Email: external.Email}
+	if err := s.identities.Create(identity); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (s *oauthService) Identities(userID int64) ([]models.Identity, error) {
+	return s.identities.List(userID)
+}
+
+func (s *oauthService) Unlink(userID int64, provider string) error {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	// Users without a real email sign in only with Telegram or a provider
+	if models.IsPlaceholderEmail(user.Email) && user.TelegramID == nil {
+		identities, err := s.identities.List(userID)
+		if err != nil {
+			return err
+		}
+		if len(identities) <= 1 {
+			return ErrLastSignInMethod
+		}
+	}
+	return s.identities.Delete(userID, provider)
+}
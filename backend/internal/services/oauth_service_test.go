@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"github.com/whatisrealfreedom/freedom-website/internal/services/oidctest"
+)
+
+func setupOAuth(t *testing.T) (OAuthService, *oidctest.Issuer, repository.UserRepository) {
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=1")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, repository.RunMigrations(db, repository.DialectSQLite, "../../migrations"))
+
+	issuer := oidctest.NewIssuer(t)
+	provider := NewOIDCProvider(models.ProviderGoogle, issuer.URL, OAuthClientConfig{
+		ClientID:     issuer.ClientID,
+		ClientSecret: issuer.ClientSecret,
+		RedirectURL:  "http://localhost:8098/oauth/callback/google",
+	})
+
+	users := repository.NewUserRepository(db)
+	referrals := NewReferralService(users, repository.NewPointsRepository(db))
+	return NewOAuthService(repository.NewIdentityRepository(db), users, referrals, provider), issuer, users
+}
+
+var googleUser = oidctest.User{
+	Subject:       "10769150350006150715113082367",
+	Email:         "reader@gmail.com",
+	EmailVerified: true,
+	Name:          "Reader",
+	Picture:       "https://lh3.googleusercontent.com/a/reader",
+}
+
+func TestOAuthService_SignIn(t *testing.T) {
+	service, issuer, users := setupOAuth(t)
+	ctx := context.Background()
+
+	login := func(user oidctest.User) (*models.User, bool, error) {
+		authURL, err := service.StartLogin(ctx, models.ProviderGoogle, "")
+		require.NoError(t, err)
+		code, state := issuer.Authorize(t, authURL, user)
+		return service.SignIn(ctx, models.ProviderGoogle, code, state)
+	}
+
+	user, created, err := login(googleUser)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "reader@gmail.com", user.Email)
+	assert.Equal(t, "Reader", *user.Name)
+	assert.Equal(t, models.ProviderGoogle, *user.RegistrationSrc)
+	assert.NotNil(t, user.EmailVerifiedAt, "the provider verified the email")
+	assert.True(t, user.IsActive)
+
+	again, created, err := login(googleUser)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, user.ID, again.ID)
+
+	identities, err := service.Identities(user.ID)
+	require.NoError(t, err)
+	require.Len(t, identities, 1)
+	assert.Equal(t, googleUser.Subject, identities[0].Subject)
+	assert.NotNil(t, identities[0].LastLoginAt)
+
+	t.Run("unverified email", func(t *testing.T) {
+		unverified := googleUser
+		unverified.Subject, unverified.Email, unverified.EmailVerified = "2", "new@gmail.com", false
+		_, _, err := login(unverified)
+		assert.ErrorIs(t, err, ErrOAuthEmailUnverified)
+	})
+
+	t.Run("email of another account", func(t *testing.T) {
+		require.NoError(t, users.Create(&models.User{Email: "taken@example.com", Password: "password"}))
+		other := googleUser
+		other.Subject, other.Email = "3", "taken@example.com"
+		_, _, err := login(other)
+		assert.ErrorIs(t, err, ErrOAuthEmailTaken)
+		assert.ErrorIs(t, err, repository.ErrDuplicate)
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		_, err := service.StartLogin(ctx, "myspace", "")
+		assert.ErrorIs(t, err, ErrUnknownProvider)
+	})
+}
+
+func TestOAuthService_State(t *testing.T) {
+	service, issuer, _ := setupOAuth(t)
+	ctx := context.Background()
+
+	authURL, err := service.StartLogin(ctx, models.ProviderGoogle, "")
+	require.NoError(t, err)
+	q, _ := url.Parse(authURL)
+	assert.Equal(t, "S256", q.Query().Get("code_challenge_method"))
+	assert.NotEmpty(t, q.Query().Get("nonce"))
+
+	code, state := issuer.Authorize(t, authURL, googleUser)
+	_, _, err = service.SignIn(ctx, models.ProviderGoogle, code, "forged")
+	assert.ErrorIs(t, err, ErrInvalidOAuthState)
+
+	_, _, err = service.SignIn(ctx, models.ProviderGoogle, code, state)
+	require.NoError(t, err)
+	_, _, err = service.SignIn(ctx, models.ProviderGoogle, code, state)
+	assert.ErrorIs(t, err, ErrInvalidOAuthState, "a state works once")
+
+	_, err = service.StartLogin(ctx, models.ProviderGoogle, "RFNOSUCHCODE")
+	assert.ErrorIs(t, err, ErrInvalidReferralCode)
+}
+
+func TestOAuthService_IDTokenChecks(t *testing.T) {
+	service, issuer, _ := setupOAuth(t)
+	ctx := context.Background()
+
+	for name, edit := range map[string]func(jwt.MapClaims){
+		"other nonce":    func(c jwt.MapClaims) { c["nonce"] = "replayed" },
+		"no nonce":       func(c jwt.MapClaims) { delete(c, "nonce") },
+		"other audience": func(c jwt.MapClaims) { c["aud"] = "another-client" },
+		"other issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"expired":        func(c jwt.MapClaims) { c["exp"] = 1 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			issuer.Claims = edit
+			defer func() { issuer.Claims = nil }()
+
+			authURL, err := service.StartLogin(ctx, models.ProviderGoogle, "")
+			require.NoError(t, err)
+			code, state := issuer.Authorize(t, authURL, googleUser)
+			_, _, err = service.SignIn(ctx, models.ProviderGoogle, code, state)
+			assert.ErrorIs(t, err, ErrInvalidOAuthLogin)
+		})
+	}
+}
+
+func TestOAuthService_Link(t *testing.T) {
+	service, issuer, users := setupOAuth(t)
+	ctx := context.Background()
+
+	user := &models.User{Email: "reader@example.com", Password: "password"}
+	require.NoError(t, users.Create(user))
+
+	authURL, err := service.StartLink(ctx, models.ProviderGoogle, user.ID)
+	require.NoError(t, err)
+	code, state := issuer.Authorize(t, authURL, googleUser)
+	_, _, err = service.SignIn(ctx, models.ProviderGoogle, code, state)
+	assert.ErrorIs(t, err, ErrInvalidOAuthState, "a link cannot be finished as a login")
+
+	authURL, err = service.StartLink(ctx, models.ProviderGoogle, user.ID)
+	require.NoError(t, err)
+	code, state = issuer.Authorize(t, authURL, googleUser)
+	_, err = service.Link(ctx, user.ID+1, models.ProviderGoogle, code, state)
+	assert.ErrorIs(t, err, ErrInvalidOAuthState, "a link is finished by the user who started it")
+
+	authURL, err = service.StartLink(ctx, models.ProviderGoogle, user.ID)
+	require.NoError(t, err)
+	code, state = issuer.Authorize(t, authURL, googleUser)
+	identity, err := service.Link(ctx, user.ID, models.ProviderGoogle, code, state)
+	require.NoError(t, err)
+	assert.Equal(t, googleUser.Email, identity.Email)
+
+	// Now Google signs in to the existing account, whatever its email
+	authURL, err = service.StartLogin(ctx, models.ProviderGoogle, "")
+	require.NoError(t, err)
+	code, state = issuer.Authorize(t, authURL, googleUser)
+	signedIn, created, err := service.SignIn(ctx, models.ProviderGoogle, code, state)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, user.ID, signedIn.ID)
+
+	require.NoError(t, service.Unlink(user.ID, models.ProviderGoogle))
+	assert.ErrorIs(t, service.Unlink(user.ID, models.ProviderGoogle), repository.ErrNotFound)
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+// OutboxWorker delivers queued emails with a Mailer. A failed email is retried
+// after BaseBackoff, doubling each time up to MaxBackoff, and given up after
+// MaxAttempts.
+type OutboxWorker struct {
+	outbox repository.OutboxRepository
+	mailer Mailer
+
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	// Lease is how long a claimed email is reserved for this worker
+	Lease time.Duration
+}
+
+func NewOutboxWorker(outbox repository.OutboxRepository, mailer Mailer) *OutboxWorker {
+	return &OutboxWorker{
+		outbox:       outbox,
+		mailer:       mailer,
+		PollInterval: 5 * time.Second,
+		BatchSize:    20,
+		MaxAttempts:  8,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   time.Hour,
+		Lease:        5 * time.Minute,
+	}
+}
+
+// Run drains the outbox every PollInterval until ctx is done
+func (w *OutboxWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.Drain(); err != nil {
+			fmt.Printf("⚠️  Outbox: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain sends the emails that are due and returns how many were sent
+func (w *OutboxWorker) Drain() (int, error) {
+	sent := 0
+	for {
+		batch, err := w.outbox.Claim(w.BatchSize, time.Now().Add(w.Lease))
+		if err != nil {
+			return sent, err
+		}
+		if len(batch) == 0 {
+			return sent, nil
+		}
+
+		for _, email := range batch {
+			ok, err := w.deliver(email)
+			if err != nil {
+				return sent, err
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+}
+
+// deliver sends one email and records the outcome. Only bookkeeping errors
+// are returned; a failed send is recorded for retry.
+func (w *OutboxWorker) deliver(email models.OutboxEmail) (bool, error) {
+	sendErr := w.mailer.Send(Message{
+		To:      email.Recipient,
+		Subject: email.Subject,
+		HTML:    email.HTMLBody,
+		Text:    email.TextBody,
+	})
+	if sendErr == nil {
+		return true, w.outbox.MarkSent(email.ID)
+	}
+
+	attempts := email.Attempts + 1
+	if attempts >= w.MaxAttempts {
+		fmt.Printf("❌ Outbox: giving up on email %d to %s after %d attempts: %v\n", email.ID, email.Recipient, attempts, sendErr)
+		return false, w.outbox.MarkFailed(email.ID, sendErr.Error(), nil)
+	}
+
+	retryAt := time.Now().Add(w.backoff(attempts))
+	return false, w.outbox.MarkFailed(email.ID, sendErr.Error(), &retryAt)
+}
+
+// backoff is the wait after the given number of failed attempts
+func (w *OutboxWorker) backoff(attempts int) time.Duration {
+	d := w.BaseBackoff << (attempts - 1)
+	if d > w.MaxBackoff || d <= 0 {
+		return w.MaxBackoff
+	}
+	return d
+}
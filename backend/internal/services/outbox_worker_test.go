@@ -0,0 +1,102 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/config"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+func setupOutbox(t *testing.T) repository.OutboxRepository {
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=1")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, repository.RunMigrations(db, repository.DialectSQLite, "../../migrations"))
+	return repository.NewOutboxRepository(db)
+}
+
+func TestOutboxWorker_RetriesWithBackoff(t *testing.T) {
+	outbox := setupOutbox(t)
+	mailer := NewMemoryMailer()
+	worker := NewOutboxWorker(outbox, mailer)
+	worker.BaseBackoff = time.Millisecond
+	worker.MaxBackoff = 4 * time.Millisecond
+	worker.MaxAttempts = 3
+
+	emails, err := NewEmailService(&config.Config{}, outbox, nil)
+	require.NoError(t, err)
+	require.NoError(t, emails.SendVerificationEmail(0, "reader@example.com", "12345"))
+
+	// Mail is down: the email waits for its next attempt
+	mailer.Err = errors.New("connection refused")
+	sent, err := worker.Drain()
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+	queued, err := outbox.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, models.OutboxPending, queued.Status)
+	assert.Equal(t, 1, queued.Attempts)
+	assert.Equal(t, "connection refused", queued.LastError)
+	assert.True(t, queued.NextAttemptAt.After(time.Now()))
+
+	// Back up: delivered once the backoff has passed
+	mailer.Err = nil
+	time.Sleep(5 * time.Millisecond)
+	sent, err = worker.Drain()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, mailer.Sent(), 1)
+	assert.Equal(t, "reader@example.com", mailer.Sent()[0].To)
+	assert.Contains(t, mailer.Sent()[0].HTML, "12345")
+
+	delivered, err := outbox.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, models.OutboxSent, delivered.Status)
+	assert.NotNil(t, delivered.SentAt)
+
+	sent, err = worker.Drain()
+	require.NoError(t, err)
+	assert.Zero(t, sent, "sent emails are not sent again")
+}
+
+func TestOutboxWorker_GivesUp(t *testing.T) {
+	outbox := setupOutbox(t)
+	mailer := NewMemoryMailer()
+	mailer.Err = errors.New("mailbox unavailable")
+	worker := NewOutboxWorker(outbox, mailer)
+	worker.BaseBackoff = time.Millisecond
+	worker.MaxBackoff = time.Millisecond
+	worker.MaxAttempts = 2
+
+	require.NoError(t, outbox.Enqueue(&models.OutboxEmail{Recipient: "reader@example.com", Subject: "Hi", HTMLBody: "<p>Hi</p>"}))
+	for i := 0; i < 3; i++ {
+		_, err := worker.Drain()
+		require.NoError(t, err)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	email, err := outbox.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, models.OutboxFailed, email.Status)
+	assert.Equal(t, 2, email.Attempts)
+}
+
+func TestOutbox_ClaimLeasesEmails(t *testing.T) {
+	outbox := setupOutbox(t)
+	require.NoError(t, outbox.Enqueue(&models.OutboxEmail{Recipient: "reader@example.com", Subject: "Hi"}))
+
+	claimed, err := outbox.Claim(10, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	claimed, err = outbox.Claim(10, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "a leased email is not handed out twice")
+}
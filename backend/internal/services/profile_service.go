@@ -0,0 +1,138 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	// Timezones are validated against the IANA database even on hosts without one
+	_ "time/tzdata"
+	"unicode/utf8"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+// Longest accepted profile values, in characters
+const (
+	maxNameLength     = 100
+	maxBioLength      = 1000
+	maxCityLength     = 100
+	maxJobTitleLength = 100
+	maxPhotoURLLength = 500
+)
+
+// ProfileService backs PATCH /me and public profiles
+type ProfileService interface {
+	// UpdateProfile applies input to userID's profile and returns the user
+	UpdateProfile(userID int64, input *models.ProfileInput) (*models.User, error)
+	// PublicProfile returns the public fields of an active user
+	PublicProfile(userID int64) (*models.PublicProfile, error)
+}
+
+type profileService struct {
+	users repository.UserRepository
+}
+
+func NewProfileService(users repository.UserRepository) ProfileService {
+	return &profileService{users: users}
+}
+
+func (s *profileService) UpdateProfile(userID int64, input *models.ProfileInput) (*models.User, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	input.ApplyTo(user)
+	if err := s.validateProfile(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.users.Update(user); err != nil {
+		return nil, err
+	}
+	return s.users.GetByID(userID)
+}
+
+func (s *profileService) PublicProfile(userID int64) (*models.PublicProfile, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	// Unverified accounts are not public yet
+	if !user.IsActive {
+		return nil, fmt.Errorf("user %w", repository.ErrNotFound)
+	}
+	return user.PublicProfile(), nil
+}
+
+func (s *profileService) validateProfile(u *models.User) error {
+	lengths := []struct {
+		field string
+		value *string
+		max   int
+	}{
+		{"name", u.Name, maxNameLength},
+		{"bio", u.Bio, maxBioLength},
+		{"city", u.City, maxCityLength},
+		{"job_title", u.JobTitle, maxJobTitleLength},
+		{"photo_url", u.PhotoURL, maxPhotoURLLength},
+	}
+	for _, l := range lengths {
+		if l.value != nil && utf8.RuneCountInString(*l.value) > l.max {
+			return fmt.Errorf("%w: %s must be at most %d characters", ErrInvalidInput, l.field, l.max)
+		}
+	}
+
+	if tz := u.PreferredTimezone; tz != nil {
+		// LoadLocation also accepts "Local", which means nothing to other hosts
+		if _, err := time.LoadLocation(*tz); err != nil || *tz == "Local" {
+			return fmt.Errorf("%w: preferred_timezone %q is not an IANA timezone", ErrInvalidInput, *tz)
+		}
+	}
+
+	if f := u.PreferredDateFormat; f != nil && !isDateFormat(*f) {
+		return fmt.Errorf("%w: preferred_date_format must be one of %v", ErrInvalidInput, models.DateFormats)
+	}
+
+	if p := u.PhotoURL; p != nil && !strings.HasPrefix(*p, models.AvatarURLPrefix) {
+		parsed, err := url.Parse(*p)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return fmt.Errorf("%w: photo_url must be an http or https URL, or an uploaded avatar", ErrInvalidInput)
+		}
+	}
+
+	lookups := []struct {
+		field string
+		table string
+		id    *int64
+	}{
+		{"language_id", "languages", u.LanguageID},
+		{"currency_id", "currencies", u.CurrencyID},
+		{"country_id", "countries", u.CountryID},
+	}
+	for _, l := range lookups {
+		if l.id == nil {
+			continue
+		}
+		exists, err := s.users.LookupExists(l.table, *l.id)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("%w: %s %d does not exist", ErrInvalidInput, l.field, *l.id)
+		}
+	}
+
+	return nil
+}
+
+func isDateFormat(f string) bool {
+	for _, known := range models.DateFormats {
+		if f == known {
+			return true
+		}
+	}
+	return false
+}
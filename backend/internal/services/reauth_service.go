@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+var (
+	// ErrWrongPassword is returned when a password confirming a change does
+	// not match
+	ErrWrongPassword = fmt.Errorf("%w: the password is not correct", ErrInvalidInput)
+	// ErrInvalidReauthCode is returned for wrong, expired and used codes of
+	// POST /me/reauth/code
+	ErrInvalidReauthCode = fmt.Errorf("%w: the confirmation code is not correct or has expired", ErrInvalidInput)
+	// ErrInvalidReauthTelegram is returned for Telegram logins that are not
+	// signed, not fresh or for another Telegram account
+	ErrInvalidReauthTelegram = fmt.Errorf("%w: sign in with the Telegram account linked to this account again", ErrInvalidInput)
+	// ErrReauthUnavailable is returned for accounts with no password, no
+	// two-factor authentication and no real email, such as Telegram accounts
+	// while Telegram login is off
+	ErrReauthUnavailable = fmt.Errorf("%w: there is no way to confirm it's you; turn on two-factor authentication first", ErrInvalidInput)
+	// ErrNoReauthCode is returned when emailing a code to an account that
+	// confirms another way
+	ErrNoReauthCode = fmt.Errorf("%w: confirm with your password, two-factor code or Telegram instead", ErrInvalidInput)
+)
+
+// ReauthTelegramMaxAge is how long ago a Telegram login confirming a change
+// may have been signed; sign-ins accept TelegramAuthMaxAge
+const ReauthTelegramMaxAge = 10 * time.Minute
+
+// ReauthService makes sure the person behind an access token owns the
+// account before a change that could take it over, such as deleting it or
+// changing its email. Accounts confirm with their password and, when it is
+// on, a two-factor code; accounts with neither confirm with a code emailed
+// to them, or with a fresh Telegram login when they have no real email.
+type ReauthService interface {
+	// Methods lists what userID must send (models.Reauth*); all are required
+	Methods(userID int64) ([]string, error)
+	// SendCode emails a code to accounts that confirm with models.ReauthEmailCode
+	SendCode(userID int64) error
+	// Check verifies proof for every method of userID. Wrong proof is
+	// ErrWrongPassword, ErrInvalidTwoFactorCode, ErrInvalidReauthCode or
+	// ErrInvalidReauthTelegram.
+	Check(userID int64, proof models.ReauthRequest) error
+}
+
+type reauthService struct {
+	users     repository.UserRepository
+	twoFactor TwoFactorService
+	emails    *EmailService
+	// telegram is nil when Telegram login is off
+	telegram *TelegramAuth
+}
+
+func NewReauthService(users repository.UserRepository, twoFactor TwoFactorService, emails *EmailService, telegram *TelegramAuth) ReauthService {
+	return &reauthService{users: users, twoFactor: twoFactor, emails: emails, telegram: telegram}
+}
+
+func (s *reauthService) Methods(userID int64) ([]string, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.methods(user)
+}
+
+func (s *reauthService) methods(user *models.User) ([]string, error) {
+	var methods []string
+	if user.HasPassword() {
+		methods = append(methods, models.ReauthPassword)
+	}
+	enabled, err := s.twoFactor.Enabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		methods = append(methods, models.ReauthTwoFactor)
+	}
+
+	if len(methods) == 0 {
+		switch {
+		case !models.IsPlaceholderEmail(user.Email):
+			methods = append(methods, models.ReauthEmailCode)
+		case user.TelegramID != nil && s.telegram != nil:
+			methods = append(methods, models.ReauthTelegram)
+		default:
+			return nil, ErrReauthUnavailable
+		}
+	}
+	return methods, nil
+}
+
+func (s *reauthService) SendCode(userID int64) error {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	methods, err := s.methods(user)
+	if err != nil {
+		return err
+	}
+	if methods[0] != models.ReauthEmailCode {
+		return ErrNoReauthCode
+	}
+
+	code := s.emails.GenerateVerificationCode()
+	if err := s.users.CreateVerificationCode(user.ID, user.Email, code, models.CodePurposeReauth); err != nil {
+		return err
+	}
+	return s.emails.SendReauthCode(user.ID, user.Email, code)
+}
+
+func (s *reauthService) Check(userID int64, proof models.ReauthRequest) error {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	methods, err := s.methods(user)
+	if err != nil {
+		return err
+	}
+
+	for _, method := range methods {
+		switch method {
+		case models.ReauthPassword:
+			if !repository.CheckPassword(proof.Password, user.Password) {
+				return ErrWrongPassword
+			}
+		case models.ReauthTwoFactor:
+			if err := s.twoFactor.Verify(user.ID, proof.Code); err != nil {
+				return err
+			}
+		case models.ReauthEmailCode:
+			vc, err := s.users.GetVerificationCode(user.Email, proof.Code, models.CodePurposeReauth)
+			if err != nil || vc.UserID != user.ID {
+				if err := s.users.RecordCodeFailure(user.Email, models.CodePurposeReauth); err != nil {
+					return err
+				}
+				return ErrInvalidReauthCode
+			}
+			if err := s.users.MarkVerificationCodeAsUsed(vc.ID); err != nil {
+				return ErrInvalidReauthCode
+			}
+		case models.ReauthTelegram:
+			tg, err := s.telegram.Verify(proof.Telegram.Fields())
+			if err != nil || tg.ID != *user.TelegramID ||
+				s.telegram.Now().Sub(time.Unix(tg.AuthDate, 0)) > ReauthTelegramMaxAge {
+				return ErrInvalidReauthTelegram
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/config"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+type reauthFixture struct {
+	db        *sql.DB
+	users     repository.UserRepository
+	twoFactor *twoFactorService
+	telegram  *TelegramAuth
+	service   ReauthService
+}
+
+func setupReauth(t *testing.T) *reauthFixture {
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=1")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, repository.RunMigrations(db, repository.DialectSQLite, "../../migrations"))
+
+	users := repository.NewUserRepository(db)
+	twoFactor := NewTwoFactorService(repository.NewTwoFactorRepository(db), "RealFreedom").(*twoFactorService)
+	emails, err := NewEmailService(&config.Config{}, repository.NewOutboxRepository(db), nil)
+	require.NoError(t, err)
+	telegram := NewTelegramAuth(testBotToken)
+	telegram.Now = func() time.Time { return time.Unix(1760000000, 0).Add(time.Minute) }
+	return &reauthFixture{db: db, users: users, twoFactor: twoFactor, telegram: telegram,
+		service: NewReauthService(users, twoFactor, emails, telegram)}
+}
+
+func (f *reauthFixture) createUser(t *testing.T, email string, source *string) *models.User {
+	user := &models.User{Email: email, Password: "password", RegistrationSrc: source}
+	require.NoError(t, f.users.Create(user))
+	return user
+}
+
+func TestReauthService_Password(t *testing.T) {
+	f := setupReauth(t)
+	user := f.createUser(t, "reader@example.com", nil)
+
+	methods, err := f.service.Methods(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.ReauthPassword}, methods)
+	assert.ErrorIs(t, f.service.SendCode(user.ID), ErrNoReauthCode)
+
+	assert.ErrorIs(t, f.service.Check(user.ID, models.ReauthRequest{Password: "wrong"}), ErrWrongPassword)
+	assert.NoError(t, f.service.Check(user.ID, models.ReauthRequest{Password: "password"}))
+
+	secret, _ := enableTwoFactor(t, f.twoFactor, user)
+	methods, err = f.service.Methods(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.ReauthPassword, models.ReauthTwoFactor}, methods)
+
+	assert.ErrorIs(t, f.service.Check(user.ID, models.ReauthRequest{Password: "password"}), ErrInvalidTwoFactorCode,
+		"the password alone is not enough once two-factor authentication is on")
+	tick(f.twoFactor)
+	code := codeAt(t, secret, f.twoFactor.now())
+	assert.NoError(t, f.service.Check(user.ID, models.ReauthRequest{Password: "password", Code: code}))
+}
+
+func TestReauthService_EmailCode(t *testing.T) {
+	f := setupReauth(t)
+	source := models.ProviderGoogle
+	user := f.createUser(t, "reader@example.com", &source)
+
+	methods, err := f.service.Methods(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.ReauthEmailCode}, methods, "the random password of a Google account proves nothing")
+	assert.ErrorIs(t, f.service.Check(user.ID, models.ReauthRequest{Password: "password"}), ErrInvalidReauthCode)
+
+	require.NoError(t, f.service.SendCode(user.ID))
+	var code string
+	require.NoError(t, f.db.QueryRow(
+		`SELECT code FROM email_verification_codes WHERE user_id = ? AND purpose = ?`, user.ID, models.CodePurposeReauth,
+	).Scan(&code))
+	queued, err := repository.NewOutboxRepository(f.db).Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, user.Email, queued.Recipient)
+	assert.Contains(t, queued.TextBody, code)
+
+	require.NoError(t, f.service.Check(user.ID, models.ReauthRequest{Code: code}))
+	assert.ErrorIs(t, f.service.Check(user.ID, models.ReauthRequest{Code: code}), ErrInvalidReauthCode, "codes are single-use")
+
+	// Two-factor codes replace the emailed code
+	enableTwoFactor(t, f.twoFactor, user)
+	assert.ErrorIs(t, f.service.SendCode(user.ID), ErrNoReauthCode)
+}
+
+func TestReauthService_PasswordSetByReset(t *testing.T) {
+	f := setupReauth(t)
+	source := models.ProviderGoogle
+	user := f.createUser(t, "reader@example.com", &source)
+	require.NoError(t, f.users.ResetPassword(user.ID, "new password"))
+
+	methods, err := f.service.Methods(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.ReauthPassword}, methods, "a password set with a reset is one they know")
+	assert.ErrorIs(t, f.service.SendCode(user.ID), ErrNoReauthCode)
+	assert.ErrorIs(t, f.service.Check(user.ID, models.ReauthRequest{Password: "password"}), ErrWrongPassword)
+	assert.NoError(t, f.service.Check(user.ID, models.ReauthRequest{Password: "new password"}))
+}
+
+func TestReauthService_Unavailable(t *testing.T) {
+	f := setupReauth(t)
+	source := models.RegistrationSrcTelegram
+	user := f.createUser(t, models.TelegramPlaceholderEmail(42), &source)
+
+	_, err := f.service.Methods(user.ID)
+	assert.ErrorIs(t, err, ErrReauthUnavailable)
+	assert.ErrorIs(t, f.service.SendCode(user.ID), ErrReauthUnavailable)
+	assert.ErrorIs(t, f.service.Check(user.ID, models.ReauthRequest{}), ErrReauthUnavailable)
+
+	enableTwoFactor(t, f.twoFactor, user)
+	methods, err := f.service.Methods(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.ReauthTwoFactor}, methods)
+}
+
+func TestReauthService_Telegram(t *testing.T) {
+	f := setupReauth(t)
+	source := models.RegistrationSrcTelegram
+	telegramID := int64(987654321)
+	user := &models.User{Email: models.TelegramPlaceholderEmail(telegramID), Password: "password",
+		RegistrationSrc: &source, TelegramID: &telegramID}
+	require.NoError(t, f.users.Create(user))
+
+	methods, err := f.service.Methods(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.ReauthTelegram}, methods)
+	assert.ErrorIs(t, f.service.SendCode(user.ID), ErrNoReauthCode)
+
+	login := func(fields map[string]string) models.TelegramLogin {
+		raw := make(models.TelegramLogin, len(fields))
+		for k, v := range fields {
+			raw[k], _ = json.Marshal(v)
+		}
+		return raw
+	}
+	assert.ErrorIs(t, f.service.Check(user.ID, models.ReauthRequest{}), ErrInvalidReauthTelegram)
+	tampered := signedTelegramLogin()
+	tampered["username"] = "someone"
+	assert.ErrorIs(t, f.service.Check(user.ID, models.ReauthRequest{Telegram: login(tampered)}), ErrInvalidReauthTelegram)
+	assert.NoError(t, f.service.Check(user.ID, models.ReauthRequest{Telegram: login(signedTelegramLogin())}))
+
+	// A login good enough to sign in is too old to confirm a change
+	f.telegram.Now = func() time.Time { return time.Unix(1760000000, 0).Add(time.Hour) }
+	assert.ErrorIs(t, f.service.Check(user.ID, models.ReauthRequest{Telegram: login(signedTelegramLogin())}), ErrInvalidReauthTelegram)
+
+	// The login must be for the Telegram account linked to this one
+	otherID := int64(111)
+	other := &models.User{Email: models.TelegramPlaceholderEmail(otherID), Password: "password",
+		RegistrationSrc: &source, TelegramID: &otherID}
+	require.NoError(t, f.users.Create(other))
+	f.telegram.Now = func() time.Time { return time.Unix(1760000000, 0).Add(time.Minute) }
+	assert.ErrorIs(t, f.service.Check(other.ID, models.ReauthRequest{Telegram: login(signedTelegramLogin())}), ErrInvalidReauthTelegram)
+
+	// Two-factor codes take over once they are on
+	enableTwoFactor(t, f.twoFactor, user)
+	methods, err = f.service.Methods(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.ReauthTwoFactor}, methods)
+}
+
+func TestReauthService_TelegramLoginOff(t *testing.T) {
+	f := setupReauth(t)
+	service := NewReauthService(f.users, f.twoFactor, nil, nil)
+	source := models.RegistrationSrcTelegram
+	telegramID := int64(987654321)
+	user := &models.User{Email: models.TelegramPlaceholderEmail(telegramID), Password: "password",
+		RegistrationSrc: &source, TelegramID: &telegramID}
+	require.NoError(t, f.users.Create(user))
+
+	_, err := service.Methods(user.ID)
+	assert.ErrorIs(t, err, ErrReauthUnavailable)
+}
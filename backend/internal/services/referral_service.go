@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+// ErrInvalidReferralCode is returned for referral codes that match nobody
+var ErrInvalidReferralCode = fmt.Errorf("%w: unknown referral code", ErrInvalidInput)
+
+// historyLimit is how many ledger entries the referral overview shows
+const historyLimit = 100
+
+// ReferralService handles invite codes and the points they earn
+type ReferralService interface {
+	// Inviter returns the ID of the user whose referral code this is; an empty
+	// code means nobody invited the new user
+	Inviter(code string) (*int64, error)
+	// CreditVerified credits the inviter of a user who just verified their
+	// email. Crediting the same invitee twice does nothing.
+	CreditVerified(user *models.User) error
+	Overview(userID int64) (*models.ReferralOverview, error)
+}
+
+type referralService struct {
+	users  repository.UserRepository
+	points repository.PointsRepository
+}
+
+func NewReferralService(users repository.UserRepository, points repository.PointsRepository) ReferralService {
+	return &referralService{users: users, points: points}
+}
+
+func (s *referralService) Inviter(code string) (*int64, error) {
+	if repository.NormalizeReferralCode(code) == "" {
+		return nil, nil
+	}
+	inviter, err := s.users.GetByReferralCode(code)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidReferralCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &inviter.ID, nil
+}
+
+func (s *referralService) CreditVerified(user *models.User) error {
+	if user.InvitedBy == nil {
+		return nil
+	}
+	err := s.points.Credit(&models.PointsEntry{
+		UserID:       *user.InvitedBy,
+		Amount:       models.ReferralPoints,
+		Reason:       models.PointsReasonReferral,
+		SourceUserID: &user.ID,
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		return nil
+	}
+	return err
+}
+
+func (s *referralService) Overview(userID int64) (*models.ReferralOverview, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	invitees, err := s.points.Invitees(userID, models.MaxReferralDepth)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.points.History(userID, historyLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ReferralOverview{
+		ReferralCode: user.ReferralCode,
+		Points:       user.Points,
+		Invitees:     referralTree(userID, invitees),
+		History:      history,
+	}, nil
+}
+
+// referralTree nests invitees under whoever invited them. Invitees arrive
+// ordered by depth, so every inviter is placed before their invitees.
+func referralTree(rootID int64, invitees []models.Referral) []*models.Referral {
+	roots := []*models.Referral{}
+	byID := make(map[int64]*models.Referral, len(invitees))
+	for i := range invitees {
+		ref := &invitees[i]
+		ref.Invitees = []*models.Referral{}
+		byID[ref.UserID] = ref
+
+		if ref.InvitedBy == rootID {
+			roots = append(roots, ref)
+		} else if parent, ok := byID[ref.InvitedBy]; ok {
+			parent.Invitees = append(parent.Invitees, ref)
+		}
+	}
+	return roots
+}
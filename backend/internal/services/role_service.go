@@ -0,0 +1,48 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+// RoleService backs the admin user and role API
+type RoleService interface {
+	ListRoles() ([]models.Role, error)
+	// SetUserRole changes userID's role on behalf of actorID
+	SetUserRole(actorID, userID int64, role string) (*models.User, error)
+}
+
+type roleService struct {
+	roles repository.RoleRepository
+	users repository.UserRepository
+}
+
+func NewRoleService(roles repository.RoleRepository, users repository.UserRepository) RoleService {
+	return &roleService{roles: roles, users: users}
+}
+
+func (s *roleService) ListRoles() ([]models.Role, error) {
+	return s.roles.List()
+}
+
+func (s *roleService) SetUserRole(actorID, userID int64, role string) (*models.User, error) {
+	// An admin demoting themselves could leave nobody able to undo it
+	if actorID == userID {
+		return nil, fmt.Errorf("%w: you cannot change your own role", ErrInvalidInput)
+	}
+
+	exists, err := s.roles.Exists(role)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidInput, role)
+	}
+
+	if err := s.users.SetRole(userID, role); err != nil {
+		return nil, err
+	}
+	return s.users.GetByID(userID)
+}
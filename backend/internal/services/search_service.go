@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"unicode/utf8"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"github.com/whatisrealfreedom/freedom-website/internal/utils"
+)
+
+const (
+	maxSearchTerms   = 8
+	maxSearchResults = 50
+	snippetLength    = 200
+)
+
+// ErrSearchQueryTooShort is returned for queries without any searchable word
+var ErrSearchQueryTooShort = errors.New("search query must contain at least 2 characters")
+
+type SearchService interface {
+	Search(query, locale string, limit int) ([]models.SearchResult, error)
+}
+
+type searchService struct {
+	repo repository.SearchRepository
+}
+
+func NewSearchService(repo repository.SearchRepository) SearchService {
+	return &searchService{repo: repo}
+}
+
+func (s *searchService) Search(query, locale string, limit int) ([]models.SearchResult, error) {
+	terms := utils.SearchTerms(query, maxSearchTerms)
+	runes := 0
+	for _, t := range terms {
+		runes += utf8.RuneCountInString(t)
+	}
+	if runes < 2 {
+		return nil, ErrSearchQueryTooShort
+	}
+
+	if limit <= 0 || limit > maxSearchResults {
+		limit = maxSearchResults
+	}
+
+	results, err := s.repo.Search(terms, locale, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		source := results[i].Content
+		if utils.MatchScore(terms, []float64{1}, source) == 0 {
+			source = results[i].Description
+		}
+		results[i].Snippet = utils.HighlightSnippet(source, terms, snippetLength)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+	"github.com/whatisrealfreedom/freedom-website/internal/utils"
+)
+
+// ErrInvalidRefreshToken is returned for unknown, expired, revoked and reused
+// refresh tokens alike
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// ErrTokenRevoked is returned by CheckToken for tokens that are validly signed
+// but no longer honoured
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// SessionService issues short-lived access tokens together with rotating
+// refresh tokens, one session per login
+type SessionService interface {
+	// Start opens a session for user and issues its first token pair
+	Start(user *models.User, userAgent, ipAddress string) (*models.TokenPair, error)
+	// Refresh exchanges a refresh token for a new pair. Presenting a token
+	// that was already exchanged revokes its whole session.
+	Refresh(refreshToken, userAgent, ipAddress string) (*models.TokenPair, error)
+	// Logout ends the session of refreshToken; unknown tokens are ignored
+	Logout(refreshToken string) error
+	List(userID int64, currentSessionID string) ([]models.Session, error)
+	Revoke(userID int64, sessionID string) error
+	// RevokeAll ends every session of userID except keep ("" for none)
+	RevokeAll(userID int64, keep string) (int64, error)
+	// CheckToken reports whether an access token is still honoured: the
+	// user's token_version has not moved past it and its session is active
+	CheckToken(claims *utils.Claims) error
+}
+
+type sessionService struct {
+	sessions      repository.SessionRepository
+	users         repository.UserRepository
+	accessExpiry  time.Duration
+	refreshExpiry time.Duration
+}
+
+func NewSessionService(sessions repository.SessionRepository, users repository.UserRepository, accessExpiry, refreshExpiry time.Duration) SessionService {
+	return &sessionService{
+		sessions:      sessions,
+		users:         users,
+		accessExpiry:  accessExpiry,
+		refreshExpiry: refreshExpiry,
+	}
+}
+
+func (s *sessionService) Start(user *models.User, userAgent, ipAddress string) (*models.TokenPair, error) {
+	id, err := randomToken(16, hex.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := randomToken(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{
+		ID:        id,
+		UserID:    user.ID,
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		ExpiresAt: time.Now().Add(s.refreshExpiry),
+	}
+	if err := s.sessions.Create(session, hashToken(refreshToken)); err != nil {
+		return nil, err
+	}
+
+	return s.issue(user, session, refreshToken)
+}
+
+func (s *sessionService) Refresh(refreshToken, userAgent, ipAddress string) (*models.TokenPair, error) {
+	next, err := randomToken(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessions.Rotate(hashToken(refreshToken), hashToken(next), time.Now().Add(s.refreshExpiry), userAgent, ipAddress)
+	if errors.Is(err, repository.ErrRefreshTokenReused) {
+		fmt.Printf("⚠️  Refresh token reuse detected, revoked its session (user agent %q, ip %s)\n", userAgent, ipAddress)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRefreshToken, err)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetByID(session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return s.issue(user, session, next)
+}
+
+func (s *sessionService) issue(user *models.User, session *models.Session, refreshToken string) (*models.TokenPair, error) {
+	token, err := utils.GenerateSessionToken(user.ID, user.Email, user.Role, user.TokenVersion, session.ID, s.accessExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		Token:            token,
+		ExpiresAt:        time.Now().Add(s.accessExpiry).UTC(),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt.UTC(),
+	}, nil
+}
+
+func (s *sessionService) Logout(refreshToken string) error {
+	err := s.sessions.RevokeByToken(hashToken(refreshToken))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *sessionService) List(userID int64, currentSessionID string) ([]models.Session, error) {
+	sessions, err := s.sessions.List(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+func (s *sessionService) Revoke(userID int64, sessionID string) error {
+	return s.sessions.Revoke(userID, sessionID)
+}
+
+func (s *sessionService) RevokeAll(userID int64, keep string) (int64, error) {
+	return s.sessions.RevokeAll(userID, keep)
+}
+
+func (s *sessionService) CheckToken(claims *utils.Claims) error {
+	version, err := s.users.GetTokenVersion(claims.UserID)
+	if err != nil {
+		return err
+	}
+	if claims.Version != version {
+		return ErrTokenRevoked
+	}
+
+	// Tokens issued before sessions existed carry no sid
+	if claims.SessionID == "" {
+		return nil
+	}
+	active, err := s.sessions.IsActive(claims.SessionID)
+	if err != nil {
+		return err
+	}
+	if !active {
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// randomToken returns n random bytes in the given encoding
+func randomToken(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return encode(b), nil
+}
+
+// hashToken is how refresh tokens are stored: they are long and random, so a
+// plain SHA-256 is enough and allows lookup by hash
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
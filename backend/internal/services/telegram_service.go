@@ -0,0 +1,197 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+// ErrInvalidTelegramLogin is returned for payloads Telegram did not sign, or
+// signed too long ago
+var ErrInvalidTelegramLogin = errors.New("invalid Telegram login")
+
+// TelegramAuthMaxAge is how long a signed Telegram login payload is accepted
+const TelegramAuthMaxAge = 24 * time.Hour
+
+// TelegramAuth verifies Telegram Login Widget payloads for one bot.
+// See https://core.telegram.org/widgets/login#checking-authorization
+type TelegramAuth struct {
+	secret []byte
+	// Now is the clock auth_date is checked against
+	Now func() time.Time
+}
+
+func NewTelegramAuth(botToken string) *TelegramAuth {
+	// The HMAC key is the SHA-256 of the bot token, not the token itself
+	secret := sha256.Sum256([]byte(botToken))
+	return &TelegramAuth{secret: secret[:], Now: time.Now}
+}
+
+// Verify checks the hash of fields, every field the widget sent including
+// "hash", and returns the Telegram user
+func (a *TelegramAuth) Verify(fields map[string]string) (*models.TelegramUser, error) {
+	hash, err := hex.DecodeString(fields["hash"])
+	if err != nil || len(hash) != sha256.Size {
+		return nil, ErrInvalidTelegramLogin
+	}
+
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(telegramDataCheckString(fields)))
+	if !hmac.Equal(mac.Sum(nil), hash) {
+		return nil, ErrInvalidTelegramLogin
+	}
+
+	user := &models.TelegramUser{
+		FirstName: fields["first_name"],
+		LastName:  fields["last_name"],
+		Username:  fields["username"],
+		PhotoURL:  fields["photo_url"],
+	}
+	user.ID, err = strconv.ParseInt(fields["id"], 10, 64)
+	if err != nil || user.ID <= 0 {
+		return nil, ErrInvalidTelegramLogin
+	}
+	user.AuthDate, err = strconv.ParseInt(fields["auth_date"], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidTelegramLogin
+	}
+
+	// A captured payload stays valid forever otherwise
+	age := a.Now().Sub(time.Unix(user.AuthDate, 0))
+	if age > TelegramAuthMaxAge || age < -5*time.Minute {
+		return nil, ErrInvalidTelegramLogin
+	}
+
+	return user, nil
+}
+
+// telegramDataCheckString is every field but hash as key=value, sorted by
+// key and joined by newlines
+func telegramDataCheckString(fields map[string]string) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if k != "hash" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = k + "=" + fields[k]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ErrTelegramOnlyAccount is returned when unlinking Telegram would leave a
+// user with no way to sign in
+var ErrTelegramOnlyAccount = fmt.Errorf("%w: this account signs in only with Telegram; link Google or GitHub, or set an email and password first", ErrInvalidInput)
+
+// TelegramService signs users in with Telegram and links Telegram accounts
+type TelegramService interface {
+	// SignIn returns the user linked to tg, registering one when there is
+	// none; created tells which. referralCode only applies to new users.
+	SignIn(tg *models.TelegramUser, referralCode string) (user *models.User, created bool, err error)
+	Link(userID int64, tg *models.TelegramUser) error
+	Unlink(userID int64) error
+}
+
+type telegramService struct {
+	users      repository.UserRepository
+	identities repository.IdentityRepository
+	referrals  ReferralService
+}
+
+func NewTelegramService(users repository.UserRepository, identities repository.IdentityRepository, referrals ReferralService) TelegramService {
+	return &telegramService{users: users, identities: identities, referrals: referrals}
+}
+
+func (s *telegramService) SignIn(tg *models.TelegramUser, referralCode string) (*models.User, bool, error) {
+	user, err := s.users.GetByTelegramID(tg.ID)
+	if err == nil {
+		return user, false, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, false, err
+	}
+
+	invitedBy, err := s.referrals.Inviter(referralCode)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Nobody knows this password; the user signs in with Telegram until they
+	// set an email and password
+	password, err := randomToken(32, hex.EncodeToString)
+	if err != nil {
+		return nil, false, err
+	}
+	telegramID := tg.ID
+	source := models.RegistrationSrcTelegram
+	user = &models.User{
+		Email:           models.TelegramPlaceholderEmail(tg.ID),
+		Password:        password,
+		TelegramID:      &telegramID,
+		IsActive:        true, // Telegram vouches for the account
+		InvitedBy:       invitedBy,
+		RegistrationSrc: &source,
+	}
+	if name := tg.DisplayName(); name != "" {
+		user.Name = &name
+	}
+	if strings.HasPrefix(tg.PhotoURL, "https://") {
+		photo := tg.PhotoURL
+		user.PhotoURL = &photo
+	}
+
+	if err := s.users.Create(user); err != nil {
+		// Another request signed the same account up first
+		if errors.Is(err, repository.ErrDuplicate) {
+			if existing, getErr := s.users.GetByTelegramID(tg.ID); getErr == nil {
+				return existing, false, nil
+			}
+		}
+		return nil, false, err
+	}
+	if err := s.referrals.CreditVerified(user); err != nil {
+		fmt.Printf("⚠️  Failed to credit referral for user %d: %v\n", user.ID, err)
+	}
+
+	created, err := s.users.GetByID(user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	return created, true, nil
+}
+
+func (s *telegramService) Link(userID int64, tg *models.TelegramUser) error {
+	telegramID := tg.ID
+	return s.users.SetTelegramID(userID, &telegramID)
+}
+
+func (s *telegramService) Unlink(userID int64) error {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	// Users without a real email sign in only with Telegram or a provider
+	if models.IsPlaceholderEmail(user.Email) {
+		identities, err := s.identities.List(userID)
+		if err != nil {
+			return err
+		}
+		if len(identities) == 0 {
+			return ErrTelegramOnlyAccount
+		}
+	}
+	return s.users.SetTelegramID(userID, nil)
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Signed with the bot token below; hashes computed outside Go
+const testBotToken = "123456:TEST-BOT-TOKEN"
+
+func signedTelegramLogin() map[string]string {
+	return map[string]string{
+		"id":         "987654321",
+		"first_name": "علی",
+		"last_name":  "Rezaei",
+		"username":   "alirezaei",
+		"photo_url":  "https://t.me/i/userpic/320/ali.jpg",
+		"auth_date":  "1760000000",
+		"hash":       "4b19582c7d907cbf56e670a4ff0324007e1cc31a2d3dc98d13171d1cca31b043",
+	}
+}
+
+func TestTelegramAuth_Verify(t *testing.T) {
+	auth := NewTelegramAuth(testBotToken)
+	auth.Now = func() time.Time { return time.Unix(1760000000, 0).Add(time.Minute) }
+
+	tg, err := auth.Verify(signedTelegramLogin())
+	require.NoError(t, err)
+	assert.Equal(t, int64(987654321), tg.ID)
+	assert.Equal(t, "علی Rezaei", tg.DisplayName())
+	assert.Equal(t, "alirezaei", tg.Username)
+
+	t.Run("tampered fields", func(t *testing.T) {
+		fields := signedTelegramLogin()
+		fields["id"] = "1"
+		_, err := auth.Verify(fields)
+		assert.ErrorIs(t, err, ErrInvalidTelegramLogin)
+
+		fields = signedTelegramLogin()
+		fields["is_admin"] = "true"
+		_, err = auth.Verify(fields)
+		assert.ErrorIs(t, err, ErrInvalidTelegramLogin, "added fields are covered by the hash")
+	})
+
+	t.Run("missing or malformed hash", func(t *testing.T) {
+		for _, hash := range []string{"", "zz", "4b19582c"} {
+			fields := signedTelegramLogin()
+			fields["hash"] = hash
+			_, err := auth.Verify(fields)
+			assert.ErrorIs(t, err, ErrInvalidTelegramLogin, hash)
+		}
+	})
+
+	t.Run("other bot", func(t *testing.T) {
+		other := NewTelegramAuth("654321:OTHER-BOT")
+		other.Now = auth.Now
+		_, err := other.Verify(signedTelegramLogin())
+		assert.ErrorIs(t, err, ErrInvalidTelegramLogin)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		stale := NewTelegramAuth(testBotToken)
+		stale.Now = func() time.Time { return time.Unix(1760000000, 0).Add(TelegramAuthMaxAge + time.Minute) }
+		_, err := stale.Verify(signedTelegramLogin())
+		assert.ErrorIs(t, err, ErrInvalidTelegramLogin)
+	})
+}
+
+func TestTelegramDataCheckString(t *testing.T) {
+	assert.Equal(t, "auth_date=1\nfirst_name=A\nid=2", telegramDataCheckString(map[string]string{
+		"id": "2", "hash": "x", "first_name": "A", "auth_date": "1",
+	}))
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The RFC 6238 SHA-1 test key, "12345678901234567890", in base32
+const rfcTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestMatchTOTP(t *testing.T) {
+	// RFC 6238 appendix B, truncated to six digits
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, code := range vectors {
+		step, ok := matchTOTP(rfcTOTPSecret, code, time.Unix(unix, 0))
+		assert.True(t, ok, unix)
+		assert.Equal(t, unix/totpPeriod, step, unix)
+	}
+
+	t.Run("clock skew", func(t *testing.T) {
+		at := time.Unix(1111111109, 0)
+		_, ok := matchTOTP(rfcTOTPSecret, "081804", at.Add(totpPeriod*time.Second))
+		assert.True(t, ok, "a step late")
+		_, ok = matchTOTP(rfcTOTPSecret, "081804", at.Add(-totpPeriod*time.Second))
+		assert.True(t, ok, "a step early")
+		_, ok = matchTOTP(rfcTOTPSecret, "081804", at.Add(3*totpPeriod*time.Second))
+		assert.False(t, ok, "too late")
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, code := range []string{"", "28708", "2870822", "abcdef"} {
+			_, ok := matchTOTP(rfcTOTPSecret, code, time.Unix(59, 0))
+			assert.False(t, ok, code)
+		}
+		_, ok := matchTOTP("not base32!", "287082", time.Unix(59, 0))
+		assert.False(t, ok)
+	})
+}
+
+func TestOTPAuthURL(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	u, err := url.Parse(otpauthURL("RealFreedom", "reader@example.com", secret))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/RealFreedom:reader@example.com", u.Path)
+	assert.Equal(t, secret, u.Query().Get("secret"))
+	assert.Equal(t, "RealFreedom", u.Query().Get("issuer"))
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+var (
+	// ErrInvalidTwoFactorCode is returned for wrong, expired and already used
+	// TOTP and recovery codes
+	ErrInvalidTwoFactorCode = fmt.Errorf("%w: invalid two-factor code", ErrInvalidInput)
+	// ErrInvalidChallenge is returned for unknown, expired and exhausted
+	// login challenges
+	ErrInvalidChallenge = errors.New("invalid or expired two-factor challenge")
+	ErrTwoFactorEnabled = fmt.Errorf("%w: two-factor authentication is already on; turn it off first", repository.ErrDuplicate)
+	ErrTwoFactorOff     = fmt.Errorf("%w: two-factor authentication is not on", ErrInvalidInput)
+	ErrTwoFactorNoSetup = fmt.Errorf("%w: start the two-factor setup first", ErrInvalidInput)
+)
+
+// TwoFactorService enrolls users in TOTP two-factor authentication and
+// checks the second factor of their logins
+type TwoFactorService interface {
+	Status(userID int64) (*models.TwoFactorStatus, error)
+	// Setup draws a new secret; it takes effect once Enable confirms a code
+	Setup(user *models.User) (*models.TwoFactorSetup, error)
+	// Enable checks a code of the new secret, turns two-factor
+	// authentication on and returns the recovery codes, shown only now
+	Enable(userID int64, code string) ([]string, error)
+	// Disable turns two-factor authentication off, given a TOTP or recovery code
+	Disable(userID int64, code string) error
+	// RegenerateRecoveryCodes replaces the recovery codes, given a TOTP or
+	// recovery code
+	RegenerateRecoveryCodes(userID int64, code string) ([]string, error)
+	Enabled(userID int64) (bool, error)
+	// Verify checks a TOTP or recovery code of a user with two-factor
+	// authentication on; either works once
+	Verify(userID int64, code string) error
+
+	// Challenge starts the second step of a login. It returns nil when the
+	// user has no two-factor authentication and may sign in right away.
+	Challenge(userID int64) (*models.TwoFactorChallenge, error)
+	// ChallengeUser returns the user a challenge token belongs to
+	ChallengeUser(token string) (int64, error)
+	// Complete checks a TOTP or recovery code for a challenge and returns the
+	// user; the challenge works once
+	Complete(token, code string) (int64, error)
+}
+
+type twoFactorService struct {
+	repo   repository.TwoFactorRepository
+	issuer string
+	now    func() time.Time
+}
+
+// NewTwoFactorService creates the service; issuer names the site in
+// authenticator apps
+func NewTwoFactorService(repo repository.TwoFactorRepository, issuer string) TwoFactorService {
+	return &twoFactorService{repo: repo, issuer: issuer, now: time.Now}
+}
+
+func (s *twoFactorService) Status(userID int64) (*models.TwoFactorStatus, error) {
+	totp, err := s.repo.GetTOTP(userID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && totp.EnabledAt == nil) {
+		return &models.TwoFactorStatus{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	left, err := s.repo.CountRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorStatus{Enabled: true, EnabledAt: totp.EnabledAt, RecoveryCodesLeft: left}, nil
+}
+
+func (s *twoFactorService) Setup(user *models.User) (*models.TwoFactorSetup, error) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveTOTP(user.ID, secret); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrTwoFactorEnabled
+		}
+		return nil, err
+	}
+
+	account := user.Email
+	if models.IsPlaceholderEmail(account) {
+		account = fmt.Sprintf("user %d", user.ID)
+	}
+	uri := otpauthURL(s.issuer, account, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render QR code: %w", err)
+	}
+
+	return &models.TwoFactorSetup{
+		Secret:     secret,
+		OTPAuthURL: uri,
+		QRCodePNG:  "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+func (s *twoFactorService) Enable(userID int64, code string) ([]string, error) {
+	totp, err := s.repo.GetTOTP(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrTwoFactorNoSetup
+	}
+	if err != nil {
+		return nil, err
+	}
+	if totp.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	step, ok := matchTOTP(totp.Secret, normalizeTOTPCode(code), s.now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.EnableTOTP(userID, step, hashes); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTwoFactorEnabled
+		}
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *twoFactorService) Disable(userID int64, code string) error {
+	if err := s.verify(userID, code); err != nil {
+		return err
+	}
+	return s.repo.DeleteTOTP(userID)
+}
+
+func (s *twoFactorService) RegenerateRecoveryCodes(userID int64, code string) ([]string, error) {
+	if err := s.verify(userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *twoFactorService) Enabled(userID int64) (bool, error) {
+	return s.repo.IsEnabled(userID)
+}
+
+func (s *twoFactorService) Verify(userID int64, code string) error {
+	return s.verify(userID, code)
+}
+
+// verify checks a TOTP code, or else a recovery code, of a user with
+// two-factor authentication on. Each works once.
+func (s *twoFactorService) verify(userID int64, code string) error {
+	totp, err := s.repo.GetTOTP(userID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && totp.EnabledAt == nil) {
+		return ErrTwoFactorOff
+	}
+	if err != nil {
+		return err
+	}
+
+	if digits := normalizeTOTPCode(code); len(digits) == totpDigits {
+		step, ok := matchTOTP(totp.Secret, digits, s.now())
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		// A code seen on someone's screen must not work a second time
+		fresh, err := s.repo.UseStep(userID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	used, err := s.repo.UseRecoveryCode(userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func (s *twoFactorService) Challenge(userID int64) (*models.TwoFactorChallenge, error) {
+	enabled, err := s.repo.IsEnabled(userID)
+	if err != nil || !enabled {
+		return nil, err
+	}
+
+	token, err := randomToken(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := s.now().Add(models.TwoFactorChallengeTTL)
+	if err := s.repo.CreateChallenge(hashToken(token), userID, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorChallenge{TwoFactorRequired: true, ChallengeToken: token, ExpiresAt: expiresAt}, nil
+}
+
+func (s *twoFactorService) ChallengeUser(token string) (int64, error) {
+	userID, err := s.repo.GetChallenge(hashToken(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, ErrInvalidChallenge
+	}
+	return userID, err
+}
+
+func (s *twoFactorService) Complete(token, code string) (int64, error) {
+	hash := hashToken(token)
+	userID, err := s.ChallengeUser(token)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.verify(userID, code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if failErr := s.repo.FailChallenge(hash); failErr != nil {
+				return 0, failErr
+			}
+		}
+		return 0, err
+	}
+
+	if err := s.repo.DeleteChallenge(hash); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return 0, ErrInvalidChallenge
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+// normalizeTOTPCode drops the spaces apps put in codes such as "123 456"
+func normalizeTOTPCode(code string) string {
+	return strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+}
+
+// recoveryEncoding spells recovery codes in lowercase base32
+var recoveryEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns models.RecoveryCodeCount codes such as
+// "k3fq-7xbm-2pwa-zt4n" and their hashes. 80 random bits each make a plain
+// SHA-256, like refresh tokens, safe to store.
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	for range models.RecoveryCodeCount {
+		code, err := randomToken(10, recoveryEncoding.EncodeToString)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code[0:4]+"-"+code[4:8]+"-"+code[8:12]+"-"+code[12:16])
+		hashes = append(hashes, hashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode makes codes typed by hand comparable
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whatisrealfreedom/freedom-website/internal/models"
+	"github.com/whatisrealfreedom/freedom-website/internal/repository"
+)
+
+func setupTwoFactor(t *testing.T) (*twoFactorService, *models.User) {
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=1")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, repository.RunMigrations(db, repository.DialectSQLite, "../../migrations"))
+
+	users := repository.NewUserRepository(db)
+	user := &models.User{Email: "moderator@example.com", Password: "password"}
+	require.NoError(t, users.Create(user))
+
+	service := NewTwoFactorService(repository.NewTwoFactorRepository(db), "RealFreedom").(*twoFactorService)
+	// Challenges expire by the database's clock, so stay close to it
+	clock := time.Now()
+	service.now = func() time.Time { return clock }
+	return service, user
+}
+
+// codeAt is what an authenticator app shows for secret at t
+func codeAt(t *testing.T, secret string, at time.Time) string {
+	key, err := totpEncoding.DecodeString(secret)
+	require.NoError(t, err)
+	return totpCode(key, totpStep(at))
+}
+
+// tick moves the service clock to the next time step
+func tick(s *twoFactorService) {
+	now := s.now().Add(totpPeriod * time.Second)
+	s.now = func() time.Time { return now }
+}
+
+func enableTwoFactor(t *testing.T, s *twoFactorService, user *models.User) (string, []string) {
+	setup, err := s.Setup(user)
+	require.NoError(t, err)
+	codes, err := s.Enable(user.ID, codeAt(t, setup.Secret, s.now()))
+	require.NoError(t, err)
+	return setup.Secret, codes
+}
+
+func TestTwoFactorService_Enable(t *testing.T) {
+	s, user := setupTwoFactor(t)
+
+	_, err := s.Enable(user.ID, "123456")
+	assert.ErrorIs(t, err, ErrTwoFactorNoSetup)
+
+	setup, err := s.Setup(user)
+	require.NoError(t, err)
+	assert.Contains(t, setup.OTPAuthURL, "secret="+setup.Secret)
+	assert.True(t, strings.HasPrefix(setup.QRCodePNG, "data:image/png;base64,"))
+
+	status, err := s.Status(user.ID)
+	require.NoError(t, err)
+	assert.False(t, status.Enabled, "not on until a code is confirmed")
+
+	_, err = s.Enable(user.ID, "000000")
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+	codes, err := s.Enable(user.ID, codeAt(t, setup.Secret, s.now()))
+	require.NoError(t, err)
+	assert.Len(t, codes, models.RecoveryCodeCount)
+
+	status, err = s.Status(user.ID)
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, models.RecoveryCodeCount, status.RecoveryCodesLeft)
+
+	_, err = s.Setup(user)
+	assert.ErrorIs(t, err, ErrTwoFactorEnabled, "a new setup must not replace the secret in use")
+}
+
+func TestTwoFactorService_Challenge(t *testing.T) {
+	s, user := setupTwoFactor(t)
+
+	challenge, err := s.Challenge(user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, challenge, "no second step without two-factor authentication")
+
+	secret, codes := enableTwoFactor(t, s, user)
+
+	t.Run("code used to enable cannot sign in", func(t *testing.T) {
+		challenge, err := s.Challenge(user.ID)
+		require.NoError(t, err)
+		_, err = s.Complete(challenge.ChallengeToken, codeAt(t, secret, s.now()))
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	})
+
+	t.Run("TOTP code", func(t *testing.T) {
+		tick(s)
+		challenge, err := s.Challenge(user.ID)
+		require.NoError(t, err)
+		require.NotNil(t, challenge)
+		assert.True(t, challenge.TwoFactorRequired)
+
+		code := codeAt(t, secret, s.now())
+		userID, err := s.Complete(challenge.ChallengeToken, code[:3]+" "+code[3:])
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, userID)
+
+		_, err = s.Complete(challenge.ChallengeToken, code)
+		assert.ErrorIs(t, err, ErrInvalidChallenge, "a challenge works once")
+
+		challenge, err = s.Challenge(user.ID)
+		require.NoError(t, err)
+		_, err = s.Complete(challenge.ChallengeToken, code)
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode, "a code works once")
+	})
+
+	t.Run("recovery code", func(t *testing.T) {
+		challenge, err := s.Challenge(user.ID)
+		require.NoError(t, err)
+		_, err = s.Complete(challenge.ChallengeToken, strings.ToUpper(codes[0]))
+		require.NoError(t, err)
+
+		challenge, err = s.Challenge(user.ID)
+		require.NoError(t, err)
+		_, err = s.Complete(challenge.ChallengeToken, codes[0])
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode, "a recovery code works once")
+
+		status, err := s.Status(user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.RecoveryCodeCount-1, status.RecoveryCodesLeft)
+	})
+
+	t.Run("attempts", func(t *testing.T) {
+		challenge, err := s.Challenge(user.ID)
+		require.NoError(t, err)
+		for range models.MaxCodeAttempts {
+			_, err = s.Complete(challenge.ChallengeToken, "000000")
+			assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+		}
+		tick(s)
+		_, err = s.Complete(challenge.ChallengeToken, codeAt(t, secret, s.now()))
+		assert.ErrorIs(t, err, ErrInvalidChallenge)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		challenge, err := s.Challenge(user.ID)
+		require.NoError(t, err)
+		challenge.ChallengeToken += "x"
+		_, err = s.Complete(challenge.ChallengeToken, "000000")
+		assert.ErrorIs(t, err, ErrInvalidChallenge)
+	})
+}
+
+func TestTwoFactorService_Disable(t *testing.T) {
+	s, user := setupTwoFactor(t)
+
+	assert.ErrorIs(t, s.Disable(user.ID, "123456"), ErrTwoFactorOff)
+
+	secret, codes := enableTwoFactor(t, s, user)
+
+	tick(s)
+	fresh, err := s.RegenerateRecoveryCodes(user.ID, codeAt(t, secret, s.now()))
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.Disable(user.ID, codes[0]), ErrInvalidTwoFactorCode, "old recovery codes stop working")
+
+	require.NoError(t, s.Disable(user.ID, fresh[0]))
+	enabled, err := s.Enabled(user.ID)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+}
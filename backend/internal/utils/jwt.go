@@ -1,42 +1,208 @@
 package utils
 
 import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"errors"
+	"fmt"
+	"os"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret []byte
+var (
+	// jwtSecret signs HS256 tokens when no signing key is loaded (development)
+	jwtSecret []byte
+	// signingKey signs EdDSA tokens; verifyKeys holds its public key and those
+	// of retired keys still accepted during rotation, by kid
+	signingKey *jwtKey
+	verifyKeys map[string]ed25519.PublicKey
+)
+
+type jwtKey struct {
+	id      string
+	private ed25519.PrivateKey
+}
 
-// InitJWT initializes the JWT secret
+// InitJWT initializes the JWT secret. Tokens are signed with HS256 until
+// InitJWTKeys loads a signing key.
 func InitJWT(secret string) {
 	jwtSecret = []byte(secret)
+	signingKey = nil
+	verifyKeys = nil
+}
+
+// InitJWTKeys switches to EdDSA: tokens are signed with the Ed25519 private key
+// in signingKeyFile and verified against it and verifyKeyFiles (retired keys,
+// private or public PEM). HS256 tokens are no longer accepted.
+func InitJWTKeys(signingKeyFile string, verifyKeyFiles []string) error {
+	private, err := loadPrivateKey(signingKeyFile)
+	if err != nil {
+		return err
+	}
+
+	public := private.Public().(ed25519.PublicKey)
+	key := &jwtKey{id: keyID(public), private: private}
+	keys := map[string]ed25519.PublicKey{key.id: public}
+	for _, file := range verifyKeyFiles {
+		pub, err := loadPublicKey(file)
+		if err != nil {
+			return err
+		}
+		keys[keyID(pub)] = pub
+	}
+
+	jwtSecret = nil
+	signingKey = key
+	verifyKeys = keys
+	return nil
+}
+
+// loadPrivateKey reads a PKCS#8 Ed25519 key, as written by
+// `openssl genpkey -algorithm ed25519`
+func loadPrivateKey(file string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT key %s: %w", file, err)
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("JWT key %s is not an Ed25519 key", file)
+	}
+	return private, nil
+}
+
+// loadPublicKey reads an Ed25519 public key, or the public half of a private key
+func loadPublicKey(file string) (ed25519.PublicKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == "PRIVATE KEY" {
+		private, err := loadPrivateKey(file)
+		if err != nil {
+			return nil, err
+		}
+		return private.Public().(ed25519.PublicKey), nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT key %s: %w", file, err)
+	}
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("JWT key %s is not an Ed25519 key", file)
+	}
+	return public, nil
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("JWT key %s is not PEM encoded", file)
+	}
+	return block, nil
+}
+
+// keyID is the RFC 7638 thumbprint of the key, so every service derives the
+// same kid from the key alone
+func keyID(public ed25519.PublicKey) string {
+	thumbprint := `{"crv":"Ed25519","kty":"OKP","x":"` + base64.RawURLEncoding.EncodeToString(public) + `"}`
+	sum := sha256.Sum256([]byte(thumbprint))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// JWK is a public key as published in the JWKS document
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+}
+
+// JWKS returns the public keys tokens are verified against, the signing key
+// first. It is empty while tokens are signed with the HS256 secret, which must
+// not be published.
+func JWKS() []JWK {
+	keys := []JWK{}
+	for kid, public := range verifyKeys {
+		keys = append(keys, JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(public),
+			Kid: kid,
+			Use: "sig",
+			Alg: "EdDSA",
+		})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if signing := keys[i].Kid == signingKey.id; signing != (keys[j].Kid == signingKey.id) {
+			return signing
+		}
+		return keys[i].Kid < keys[j].Kid
+	})
+	return keys
 }
 
 // Claims represents JWT claims
 type Claims struct {
 	UserID int64  `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role,omitempty"`
+	// Version is users.token_version at issue time; the token stops working
+	// once the user's version moves past it
+	Version int `json:"ver"`
+	// SessionID is the sessions row the token was issued for; revoking the
+	// session revokes the token
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // GenerateToken generates a JWT token for a user
-func GenerateToken(userID int64, email string, expiry time.Duration) (string, error) {
-	if len(jwtSecret) == 0 {
+func GenerateToken(userID int64, email, role string, version int, expiry time.Duration) (string, error) {
+	return GenerateSessionToken(userID, email, role, version, "", expiry)
+}
+
+// GenerateSessionToken generates a JWT token bound to a session
+func GenerateSessionToken(userID int64, email, role string, version int, sessionID string, expiry time.Duration) (string, error) {
+	if signingKey == nil && len(jwtSecret) == 0 {
 		return "", errors.New("JWT secret not initialized")
 	}
 
 	expirationTime := time.Now().Add(expiry)
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		Version:   version,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
+	if signingKey != nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+		token.Header["kid"] = signingKey.id
+		return token.SignedString(signingKey.private)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString(jwtSecret)
 	if err != nil {
@@ -48,16 +214,26 @@ func GenerateToken(userID int64, email string, expiry time.Duration) (string, er
 
 // ValidateToken validates a JWT token and returns the claims
 func ValidateToken(tokenString string) (*Claims, error) {
-	if len(jwtSecret) == 0 {
+	if signingKey == nil && len(jwtSecret) == 0 {
 		return nil, errors.New("JWT secret not initialized")
 	}
 
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
+		switch token.Method.(type) {
+		case *jwt.SigningMethodEd25519:
+			kid, _ := token.Header["kid"].(string)
+			if key, ok := verifyKeys[kid]; ok {
+				return key, nil
+			}
+			return nil, errors.New("unknown signing key")
+		case *jwt.SigningMethodHMAC:
+			// Once keys are loaded the secret must not be able to mint tokens
+			if signingKey == nil {
+				return jwtSecret, nil
+			}
 		}
-		return jwtSecret, nil
+		return nil, errors.New("unexpected signing method")
 	})
 
 	if err != nil {
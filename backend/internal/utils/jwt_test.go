@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKey writes a new Ed25519 key as PKCS#8 PEM, like openssl genpkey
+func writeKey(t *testing.T, dir, name string) (string, ed25519.PublicKey) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	file := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return file, public
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKey, oldPublic := writeKey(t, dir, "old.pem")
+	newKey, newPublic := writeKey(t, dir, "new.pem")
+	t.Cleanup(func() { InitJWT("") })
+
+	InitJWT("dev-secret")
+	hsToken, err := GenerateToken(1, "a@example.com", "user", 0, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, JWKS(), "the HMAC secret is never published")
+
+	require.NoError(t, InitJWTKeys(oldKey, nil))
+	oldToken, err := GenerateToken(1, "a@example.com", "user", 0, time.Hour)
+	require.NoError(t, err)
+	_, err = ValidateToken(hsToken)
+	assert.Error(t, err, "HS256 tokens are rejected once keys are loaded")
+
+	// Rotate: the old key only verifies now
+	require.NoError(t, InitJWTKeys(newKey, []string{oldKey}))
+	newToken, err := GenerateSessionToken(1, "a@example.com", "user", 0, "session", time.Hour)
+	require.NoError(t, err)
+
+	claims, err := ValidateToken(newToken)
+	require.NoError(t, err)
+	assert.Equal(t, "session", claims.SessionID)
+	_, err = ValidateToken(oldToken)
+	assert.NoError(t, err)
+
+	keys := JWKS()
+	require.Len(t, keys, 2)
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(newPublic), keys[0].X, "the signing key is listed first")
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(oldPublic), keys[1].X)
+	assert.Equal(t, keyID(newPublic), keys[0].Kid)
+	assert.Equal(t, "EdDSA", keys[0].Alg)
+
+	// Retired for good: tokens of the old key stop working
+	require.NoError(t, InitJWTKeys(newKey, nil))
+	_, err = ValidateToken(oldToken)
+	assert.Error(t, err)
+}
+
+func TestInitJWTKeys_RejectsBadFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Cleanup(func() { InitJWT("") })
+
+	assert.Error(t, InitJWTKeys(filepath.Join(dir, "missing.pem"), nil))
+
+	garbage := filepath.Join(dir, "garbage.pem")
+	require.NoError(t, os.WriteFile(garbage, []byte("not a key"), 0o600))
+	assert.Error(t, InitJWTKeys(garbage, nil))
+
+	key, _ := writeKey(t, dir, "key.pem")
+	assert.Error(t, InitJWTKeys(key, []string{garbage}))
+}
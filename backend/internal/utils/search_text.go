@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"html"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	htmlBlockRe = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	htmlTagRe   = regexp.MustCompile(`(?s)<[^>]*>`)
+	spaceRe     = regexp.MustCompile(`\s+`)
+)
+
+// StripHTML turns chapter HTML into plain text suitable for indexing and snippets
+func StripHTML(s string) string {
+	s = htmlBlockRe.ReplaceAllString(s, " ")
+	s = htmlTagRe.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	return strings.TrimSpace(spaceRe.ReplaceAllString(s, " "))
+}
+
+// normalizeRune folds Arabic and Persian letter variants so that the same word
+// typed on different keyboards matches. It returns -1 for runes that are dropped:
+// ZWNJ/ZWJ (so "می‌خواهم" and "میخواهم" are one word), tatweel and harakat.
+func normalizeRune(r rune) rune {
+	switch {
+	case r == '\u200c', r == '\u200d', r == '\u0640': // ZWNJ, ZWJ, tatweel
+		return -1
+	case r >= '\u064b' && r <= '\u065f', r == '\u0670': // harakat
+		return -1
+	case r == 'ي', r == 'ى', r == 'ئ':
+		return 'ی'
+	case r == 'ك':
+		return 'ک'
+	case r == 'ة':
+		return 'ه'
+	case r == 'أ', r == 'إ', r == 'آ', r == 'ٱ':
+		return 'ا'
+	case r == 'ؤ':
+		return 'و'
+	case r >= '۰' && r <= '۹':
+		return '0' + (r - '۰')
+	case r >= '٠' && r <= '٩':
+		return '0' + (r - '٠')
+	}
+	return unicode.ToLower(r)
+}
+
+// NormalizeSearchText applies the search normalization to a whole string
+func NormalizeSearchText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if n := normalizeRune(r); n >= 0 {
+			b.WriteRune(n)
+		}
+	}
+	return b.String()
+}
+
+func isTokenRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// SearchTokens normalizes s and splits it into words, the same way the FTS5
+// unicode61 tokenizer splits the normalized index text
+func SearchTokens(s string) []string {
+	return strings.FieldsFunc(NormalizeSearchText(s), func(r rune) bool { return !isTokenRune(r) })
+}
+
+// SearchTerms extracts up to max distinct query terms from a user query
+func SearchTerms(query string, max int) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, t := range SearchTokens(query) {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		terms = append(terms, t)
+		if len(terms) == max {
+			break
+		}
+	}
+	return terms
+}
+
+// MatchScore ranks fields against terms for databases without FTS5. Every term
+// must prefix-match a word in at least one field; weights favour earlier fields.
+// Returns 0 when the fields do not match.
+func MatchScore(terms []string, weights []float64, fields ...string) float64 {
+	counts := make([][]int, len(fields))
+	lengths := make([]int, len(fields))
+	for i, f := range fields {
+		tokens := SearchTokens(f)
+		lengths[i] = len(tokens)
+		counts[i] = make([]int, len(terms))
+		for _, tok := range tokens {
+			for j, term := range terms {
+				if strings.HasPrefix(tok, term) {
+					counts[i][j]++
+				}
+			}
+		}
+	}
+
+	score := 0.0
+	for j := range terms {
+		matched := false
+		for i := range fields {
+			if counts[i][j] == 0 {
+				continue
+			}
+			matched = true
+			score += weights[i] * float64(counts[i][j]) / math.Sqrt(float64(lengths[i]))
+		}
+		if !matched {
+			return 0
+		}
+	}
+	return score
+}
+
+// HighlightSnippet returns an HTML-escaped excerpt of text around the first
+// prefix match of any term, with matches wrapped in <mark>. Matching is done
+// on normalized text but the excerpt keeps the original characters.
+func HighlightSnippet(text string, terms []string, maxRunes int) string {
+	orig := []rune(text)
+
+	// Normalized runes, each remembering its position in orig
+	norm := make([]rune, 0, len(orig))
+	pos := make([]int, 0, len(orig))
+	for i, r := range orig {
+		if n := normalizeRune(r); n >= 0 {
+			norm = append(norm, n)
+			pos = append(pos, i)
+		}
+	}
+
+	type span struct{ start, end int } // in orig
+	var spans []span
+	for i := 0; i < len(norm); {
+		if !isTokenRune(norm[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(norm) && isTokenRune(norm[j]) {
+			j++
+		}
+		word := string(norm[i:j])
+		for _, term := range terms {
+			if strings.HasPrefix(word, term) {
+				end := i + len([]rune(term))
+				// Extend to the original end of the prefix, keeping dropped runes (e.g. ZWNJ) inside
+				origEnd := len(orig)
+				if end < len(pos) {
+					origEnd = pos[end]
+				}
+				spans = append(spans, span{pos[i], origEnd})
+				break
+			}
+		}
+		i = j
+	}
+
+	if len(spans) == 0 {
+		if len(orig) > maxRunes {
+			return html.EscapeString(strings.TrimSpace(string(orig[:maxRunes]))) + "…"
+		}
+		return html.EscapeString(text)
+	}
+
+	// Window centred loosely on the first match, snapped to word boundaries
+	start := spans[0].start - maxRunes/4
+	if start < 0 {
+		start = 0
+	}
+	for start > 0 && !unicode.IsSpace(orig[start-1]) && spans[0].start-start < maxRunes/2 {
+		start--
+	}
+	end := start + maxRunes
+	if end > len(orig) {
+		end = len(orig)
+	}
+	for end < len(orig) && !unicode.IsSpace(orig[end]) && end-start < maxRunes+20 {
+		end++
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	cursor := start
+	for _, s := range spans {
+		if s.start < cursor || s.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(orig[cursor:s.start])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(orig[s.start:s.end])))
+		b.WriteString("</mark>")
+		cursor = s.end
+	}
+	b.WriteString(html.EscapeString(string(orig[cursor:end])))
+	if end < len(orig) {
+		b.WriteString("…")
+	}
+
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeSearchText(t *testing.T) {
+	// Arabic yeh/kaf, ZWNJ, tatweel, harakat and Persian digits
+	assert.Equal(t, NormalizeSearchText("آزادی"), NormalizeSearchText("آزادي"))
+	assert.Equal(t, "کتاب", NormalizeSearchText("كتاب"))
+	assert.Equal(t, "میخواهم", NormalizeSearchText("می‌خواهم"))
+	assert.Equal(t, "علم", NormalizeSearchText("عـــلم"))
+	assert.Equal(t, "حریت", NormalizeSearchText("حُرِّیَّت"))
+	assert.Equal(t, "فصل 12", NormalizeSearchText("فصل ۱۲"))
+	assert.Equal(t, "freedom", NormalizeSearchText("Freedom"))
+}
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, []string{"ازادی", "مالکیت"}, SearchTerms("  آزادي، مالکیت آزادی ", 8))
+	assert.Equal(t, []string{"a", "b"}, SearchTerms("a b c", 2))
+	assert.Empty(t, SearchTerms("!!! ؟", 8))
+}
+
+func TestStripHTML(t *testing.T) {
+	assert.Equal(t, "Title & body text", StripHTML("<h1>Title &amp;</h1><script>x()</script><p>body\n  text</p>"))
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	text := "مقدمه. ما می‌خواهیم آزادي را تعریف کنیم."
+	snippet := HighlightSnippet(text, SearchTerms("آزادی میخواهیم", 8), 200)
+
+	// Original spelling (Arabic yeh, ZWNJ) is kept inside the marks
+	assert.Contains(t, snippet, "<mark>آزادي</mark>")
+	assert.Contains(t, snippet, "<mark>می‌خواهیم</mark>")
+
+	assert.Equal(t, "&lt;b&gt; <mark>free</mark>dom", HighlightSnippet("<b> freedom", []string{"free"}, 200))
+}
+
+func TestMatchScore(t *testing.T) {
+	weights := []float64{10, 1}
+	title := MatchScore([]string{"freedom"}, weights, "Freedom as property", "other words")
+	body := MatchScore([]string{"freedom"}, weights, "Chapter one", "about freedom")
+
+	assert.Greater(t, title, body)
+	assert.Zero(t, MatchScore([]string{"freedom", "missing"}, weights, "Freedom", "text"))
+}
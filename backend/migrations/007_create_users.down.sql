@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS email_verification_codes;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS countries;
+DROP TABLE IF EXISTS currencies;
+DROP TABLE IF EXISTS languages;
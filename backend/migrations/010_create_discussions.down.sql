@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS thread_drafts;
+DROP TABLE IF EXISTS reactions;
+DROP TABLE IF EXISTS votes;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS threads;
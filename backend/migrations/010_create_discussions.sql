@@ -141,27 +141,4 @@ BEGIN
     ) WHERE id = NEW.thread_id;
 END;
 
--- Create trigger to update thread.score when votes are added/updated
-DROP TRIGGER IF EXISTS update_thread_score_on_vote;
-CREATE TRIGGER update_thread_score_on_vote
-AFTER INSERT OR UPDATE OR DELETE ON votes
-WHEN NEW.thread_id IS NOT NULL OR OLD.thread_id IS NOT NULL
-BEGIN
-    UPDATE threads SET score = (
-        SELECT COALESCE(SUM(vote_type), 0) FROM votes 
-        WHERE thread_id = COALESCE(NEW.thread_id, OLD.thread_id)
-    ) WHERE id = COALESCE(NEW.thread_id, OLD.thread_id);
-END;
-
--- Create trigger to update comment.score when votes are added/updated
-DROP TRIGGER IF EXISTS update_comment_score_on_vote;
-CREATE TRIGGER update_comment_score_on_vote
-AFTER INSERT OR UPDATE OR DELETE ON votes
-WHEN NEW.comment_id IS NOT NULL OR OLD.comment_id IS NOT NULL
-BEGIN
-    UPDATE comments SET score = (
-        SELECT COALESCE(SUM(vote_type), 0) FROM votes 
-        WHERE comment_id = COALESCE(NEW.comment_id, OLD.comment_id)
-    ) WHERE id = COALESCE(NEW.comment_id, OLD.comment_id);
-END;
-
+-- Vote score triggers live in 011_fix_vote_score_triggers.sql
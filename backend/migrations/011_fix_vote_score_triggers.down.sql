@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS update_thread_score_on_vote_insert;
+DROP TRIGGER IF EXISTS update_thread_score_on_vote_update;
+DROP TRIGGER IF EXISTS update_thread_score_on_vote_delete;
+DROP TRIGGER IF EXISTS update_comment_score_on_vote_insert;
+DROP TRIGGER IF EXISTS update_comment_score_on_vote_update;
+DROP TRIGGER IF EXISTS update_comment_score_on_vote_delete;
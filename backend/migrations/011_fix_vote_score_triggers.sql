@@ -0,0 +1,75 @@
+-- Keep threads.score and comments.score in sync with votes.
+-- SQLite triggers fire on a single event, so insert/update/delete each get their own.
+-- Replaces the combined triggers from 010, which SQLite rejected.
+
+DROP TRIGGER IF EXISTS update_thread_score_on_vote;
+DROP TRIGGER IF EXISTS update_comment_score_on_vote;
+
+DROP TRIGGER IF EXISTS update_thread_score_on_vote_insert;
+CREATE TRIGGER update_thread_score_on_vote_insert
+AFTER INSERT ON votes
+WHEN NEW.thread_id IS NOT NULL
+BEGIN
+    UPDATE threads SET score = (
+        SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE thread_id = NEW.thread_id
+    ) WHERE id = NEW.thread_id;
+END;
+
+DROP TRIGGER IF EXISTS update_thread_score_on_vote_update;
+CREATE TRIGGER update_thread_score_on_vote_update
+AFTER UPDATE ON votes
+WHEN NEW.thread_id IS NOT NULL
+BEGIN
+    UPDATE threads SET score = (
+        SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE thread_id = NEW.thread_id
+    ) WHERE id = NEW.thread_id;
+END;
+
+DROP TRIGGER IF EXISTS update_thread_score_on_vote_delete;
+CREATE TRIGGER update_thread_score_on_vote_delete
+AFTER DELETE ON votes
+WHEN OLD.thread_id IS NOT NULL
+BEGIN
+    UPDATE threads SET score = (
+        SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE thread_id = OLD.thread_id
+    ) WHERE id = OLD.thread_id;
+END;
+
+DROP TRIGGER IF EXISTS update_comment_score_on_vote_insert;
+CREATE TRIGGER update_comment_score_on_vote_insert
+AFTER INSERT ON votes
+WHEN NEW.comment_id IS NOT NULL
+BEGIN
+    UPDATE comments SET score = (
+        SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE comment_id = NEW.comment_id
+    ) WHERE id = NEW.comment_id;
+END;
+
+DROP TRIGGER IF EXISTS update_comment_score_on_vote_update;
+CREATE TRIGGER update_comment_score_on_vote_update
+AFTER UPDATE ON votes
+WHEN NEW.comment_id IS NOT NULL
+BEGIN
+    UPDATE comments SET score = (
+        SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE comment_id = NEW.comment_id
+    ) WHERE id = NEW.comment_id;
+END;
+
+DROP TRIGGER IF EXISTS update_comment_score_on_vote_delete;
+CREATE TRIGGER update_comment_score_on_vote_delete
+AFTER DELETE ON votes
+WHEN OLD.comment_id IS NOT NULL
+BEGIN
+    UPDATE comments SET score = (
+        SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE comment_id = OLD.comment_id
+    ) WHERE id = OLD.comment_id;
+END;
+
+-- Votes cast while the triggers were missing never touched the scores
+UPDATE threads SET score = (
+    SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE votes.thread_id = threads.id
+) WHERE score != (SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE votes.thread_id = threads.id);
+
+UPDATE comments SET score = (
+    SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE votes.comment_id = comments.id
+) WHERE score != (SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE votes.comment_id = comments.id);
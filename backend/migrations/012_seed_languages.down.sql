@@ -0,0 +1,3 @@
+DELETE FROM languages
+WHERE code IN ('fa', 'en')
+  AND id NOT IN (SELECT language_id FROM users WHERE language_id IS NOT NULL);
@@ -0,0 +1,4 @@
+-- Languages that chapter translations exist for; users.language_id points here
+-- and drives chapter locale negotiation
+INSERT OR IGNORE INTO languages (code, name) VALUES ('fa', 'فارسی');
+INSERT OR IGNORE INTO languages (code, name) VALUES ('en', 'English');
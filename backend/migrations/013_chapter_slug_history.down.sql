@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS release_chapter_slug_on_insert;
+DROP TRIGGER IF EXISTS record_chapter_slug_change;
+DROP TABLE IF EXISTS chapter_slug_history;
+DROP INDEX IF EXISTS idx_chapter_translations_locale_slug;
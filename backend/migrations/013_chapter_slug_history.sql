@@ -0,0 +1,34 @@
+-- Slugs are unique per locale so /chapters/by-slug/:locale/:slug resolves to one translation
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chapter_translations_locale_slug ON chapter_translations(locale, slug);
+
+-- Previous slugs of chapter translations, so old links can be redirected
+CREATE TABLE IF NOT EXISTS chapter_slug_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    chapter_id INTEGER NOT NULL,
+    locale VARCHAR(10) NOT NULL,
+    slug TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (chapter_id) REFERENCES chapters(id) ON DELETE CASCADE,
+    UNIQUE(locale, slug)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chapter_slug_history_chapter_id ON chapter_slug_history(chapter_id);
+
+-- Record the old slug whenever a translation's slug changes. A slug that is
+-- taken into use again stops redirecting.
+DROP TRIGGER IF EXISTS record_chapter_slug_change;
+CREATE TRIGGER record_chapter_slug_change
+AFTER UPDATE OF slug ON chapter_translations
+WHEN OLD.slug != NEW.slug
+BEGIN
+    INSERT OR REPLACE INTO chapter_slug_history (chapter_id, locale, slug)
+    VALUES (OLD.chapter_id, OLD.locale, OLD.slug);
+    DELETE FROM chapter_slug_history WHERE locale = NEW.locale AND slug = NEW.slug;
+END;
+
+DROP TRIGGER IF EXISTS release_chapter_slug_on_insert;
+CREATE TRIGGER release_chapter_slug_on_insert
+AFTER INSERT ON chapter_translations
+BEGIN
+    DELETE FROM chapter_slug_history WHERE locale = NEW.locale AND slug = NEW.slug;
+END;
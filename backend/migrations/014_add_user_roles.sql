@@ -0,0 +1,4 @@
+-- Role of each user: reader (default), moderator or admin.
+-- Promote the first admin by hand:
+--   UPDATE users SET role = 'admin' WHERE email = 'you@example.com';
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'reader';
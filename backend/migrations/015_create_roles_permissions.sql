@@ -0,0 +1,34 @@
+-- Roles and the permissions they grant. users.role names a row in roles;
+-- routes are guarded by permission (middleware.RequirePermission), so what a
+-- role may do can be changed here without a deploy.
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(20) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(20) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('reader', 'Registered reader; can take part in discussions'),
+    ('moderator', 'Moderates discussions'),
+    ('admin', 'Manages content and users');
+
+INSERT INTO permissions (name, description) VALUES
+    ('chapters.manage', 'Create, edit, reorder and delete chapters and translations'),
+    ('discussions.moderate', 'Pin, lock, delete and restore threads and comments'),
+    ('users.manage', 'List roles and change user roles');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('moderator', 'discussions.moderate'),
+    ('admin', 'chapters.manage'),
+    ('admin', 'discussions.moderate'),
+    ('admin', 'users.manage');
@@ -0,0 +1,34 @@
+-- Moderation: soft-deleted threads, a log of moderator actions and a
+-- comment_count that also follows restored comments.
+
+ALTER TABLE threads ADD COLUMN is_deleted BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- One row per moderator action on a thread or comment; rows outlive the user
+-- and item they refer to
+CREATE TABLE IF NOT EXISTS moderation_actions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    moderator_id INTEGER,
+    thread_id INTEGER,
+    comment_id INTEGER,
+    action VARCHAR(20) NOT NULL, -- pin, unpin, lock, unlock, delete, restore
+    reason TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (moderator_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (thread_id) REFERENCES threads(id) ON DELETE SET NULL,
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_thread_id ON moderation_actions(thread_id);
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_comment_id ON moderation_actions(comment_id);
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_created_at ON moderation_actions(created_at DESC);
+
+DROP TRIGGER IF EXISTS update_thread_comment_count_restore;
+CREATE TRIGGER update_thread_comment_count_restore
+AFTER UPDATE ON comments
+WHEN NEW.is_deleted = 0 AND OLD.is_deleted = 1
+BEGIN
+    UPDATE threads SET comment_count = (
+        SELECT COUNT(*) FROM comments
+        WHERE thread_id = NEW.thread_id AND is_deleted = 0
+    ) WHERE id = NEW.thread_id;
+END;
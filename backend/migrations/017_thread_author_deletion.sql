@@ -0,0 +1,4 @@
+-- Threads deleted by their author. A thread with comments stays listed as a
+-- "[deleted]" tombstone so the conversation still reads; one without comments
+-- is also marked is_deleted and disappears.
+ALTER TABLE threads ADD COLUMN author_deleted BOOLEAN NOT NULL DEFAULT FALSE;
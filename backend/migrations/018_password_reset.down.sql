@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_email_verification_codes_email_purpose;
+ALTER TABLE users DROP COLUMN token_version;
+ALTER TABLE email_verification_codes DROP COLUMN purpose;
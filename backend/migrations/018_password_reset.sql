@@ -0,0 +1,7 @@
+-- Password reset codes share email_verification_codes, told apart by purpose.
+-- users.token_version is embedded in issued tokens; bumping it (on password
+-- reset) makes AuthMiddleware reject every token issued before.
+ALTER TABLE email_verification_codes ADD COLUMN purpose VARCHAR(20) NOT NULL DEFAULT 'verify_email';
+ALTER TABLE users ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_email_verification_codes_email_purpose ON email_verification_codes(email, purpose);
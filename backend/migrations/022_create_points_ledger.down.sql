@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_invited_by;
+DROP TABLE IF EXISTS points_ledger;
@@ -0,0 +1,25 @@
+-- Append-only record of points; users.points is the running balance. A row is
+-- never updated: corrections are new rows with a negative amount.
+CREATE TABLE IF NOT EXISTS points_ledger (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    amount INTEGER NOT NULL,
+    reason VARCHAR(50) NOT NULL, -- e.g. referral
+    source_user_id INTEGER, -- the invitee a referral was credited for
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (source_user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_points_ledger_user ON points_ledger(user_id, created_at);
+-- Each invitee earns their inviter a referral credit once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_points_ledger_once ON points_ledger(user_id, reason, source_user_id);
+
+DROP TRIGGER IF EXISTS points_ledger_append_only;
+CREATE TRIGGER points_ledger_append_only
+BEFORE UPDATE OF user_id, amount, reason, created_at ON points_ledger
+BEGIN
+    SELECT RAISE(ABORT, 'points_ledger is append-only');
+END;
+
+CREATE INDEX IF NOT EXISTS idx_users_invited_by ON users(invited_by);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_telegram_id;
+CREATE INDEX IF NOT EXISTS idx_users_telegram_id ON users(telegram_id);
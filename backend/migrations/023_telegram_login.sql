@@ -0,0 +1,4 @@
+-- A Telegram account signs in to at most one user. Replaces the plain index
+-- created in 007.
+DROP INDEX IF EXISTS idx_users_telegram_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_telegram_id ON users(telegram_id);
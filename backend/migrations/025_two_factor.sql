@@ -0,0 +1,32 @@
+-- TOTP (RFC 6238) two-factor authentication. enabled_at stays NULL until the
+-- user has entered a code from the new authenticator; last_step is the time
+-- step of the last accepted code, so a code cannot be replayed.
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    last_step INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    enabled_at DATETIME
+);
+
+-- Single-use codes for when the authenticator is lost, stored hashed
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    created_at DATETIME NOT NULL,
+    used_at DATETIME,
+    UNIQUE (user_id, code_hash)
+);
+
+-- Logins waiting for their second factor. The token handed to the client is
+-- stored hashed; a challenge dies after a few wrong codes.
+CREATE TABLE IF NOT EXISTS two_factor_challenges (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_two_factor_challenges_expires_at ON two_factor_challenges(expires_at);
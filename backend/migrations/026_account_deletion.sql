@@ -0,0 +1,8 @@
+-- Account deletion. DELETE /me sets deletion_requested_at; once the grace
+-- period is over the user is anonymized in place and deleted_at is set. The
+-- row itself stays, so the ON DELETE CASCADE on threads and comments never
+-- takes their discussions with it.
+ALTER TABLE users ADD COLUMN deletion_requested_at DATETIME;
+ALTER TABLE users ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_requested_at ON users(deletion_requested_at);
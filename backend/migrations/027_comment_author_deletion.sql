@@ -0,0 +1,10 @@
+-- Comments deleted by their author, told apart from moderator deletions so a
+-- moderator restore cannot bring them back.
+ALTER TABLE comments ADD COLUMN author_deleted BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Deleted comments without a moderator deletion on record were deleted by
+-- their author
+UPDATE comments SET author_deleted = TRUE
+WHERE is_deleted = TRUE AND id NOT IN (
+    SELECT comment_id FROM moderation_actions WHERE comment_id IS NOT NULL AND action = 'delete'
+);
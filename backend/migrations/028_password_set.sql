@@ -0,0 +1,9 @@
+-- Whether users know their password. Telegram, Google and GitHub sign-ups
+-- get a random one they never see, until they set their own with a
+-- password reset.
+ALTER TABLE users ADD COLUMN password_set BOOLEAN NOT NULL DEFAULT TRUE;
+
+UPDATE users SET password_set = FALSE
+WHERE registration_src IS NOT NULL AND id NOT IN (
+    SELECT user_id FROM email_verification_codes WHERE purpose = 'password_reset' AND used = TRUE
+);
@@ -0,0 +1,41 @@
+-- Postgres version of 001_initial.sql
+
+-- Create chapters table
+CREATE TABLE IF NOT EXISTS chapters (
+    id SERIAL PRIMARY KEY,
+    number INTEGER NOT NULL UNIQUE,
+    title TEXT NOT NULL,
+    slug TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL,
+    content TEXT,
+    icon TEXT,
+    pages INTEGER DEFAULT 0,
+    read_time INTEGER DEFAULT 0,
+    featured BOOLEAN DEFAULT FALSE,
+    "order" INTEGER DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create resources table
+CREATE TABLE IF NOT EXISTS resources (
+    id SERIAL PRIMARY KEY,
+    type TEXT NOT NULL, -- pdf, audio, book
+    number INTEGER,
+    title TEXT NOT NULL,
+    description TEXT,
+    file_url TEXT NOT NULL,
+    file_size BIGINT DEFAULT 0,
+    pages INTEGER DEFAULT 0,
+    duration INTEGER DEFAULT 0, -- for audio in minutes
+    icon TEXT,
+    "order" INTEGER DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create index for faster lookups
+CREATE INDEX IF NOT EXISTS idx_chapters_slug ON chapters(slug);
+CREATE INDEX IF NOT EXISTS idx_chapters_order ON chapters("order");
+CREATE INDEX IF NOT EXISTS idx_resources_type ON resources(type);
+CREATE INDEX IF NOT EXISTS idx_resources_number ON resources(number);
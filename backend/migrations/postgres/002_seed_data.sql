@@ -0,0 +1,52 @@
+-- Postgres version of 002_seed_data.sql
+-- INSERT OR REPLACE becomes an upsert and featured flags use boolean literals
+
+-- Insert chapters
+INSERT INTO chapters (id, number, title, slug, description, content, icon, pages, read_time, featured, "order") VALUES
+(1, 1, 'آزادی واقعی = حقوق مالکیت مطلق', 'real-freedom-property-rights', 'بازتعریف آزادی به عنوان مالکیت مطلق بر چهار حوزه: جسم، ذهن، زمان و دارایی', '', 'key', 15, 10, FALSE, 1),
+(2, 2, 'نظام صوری آکسیوماتیک چیست؟', 'axiomatic-formal-system', 'دین به عنوان پایدارترین نظام منطقی تاریخ برای تضمین آزادی', '', 'cogs', 22, 18, FALSE, 2),
+(3, 3, 'دین، نگهبان واقعی آزادی', 'religion-guardian-freedom', 'چگونه دین واقعی بزرگ‌ترین دشمن دولت‌سالاری و حامی آزادی است', '', 'shield-alt', 18, 14, FALSE, 3),
+(4, 4, 'کاربرد در ایران معاصر', 'application-contemporary-iran', 'راه‌حل دینی برای پایان تورم، سرکوب و نقض حریم خصوصی', '', 'flag', 25, 20, FALSE, 4),
+(5, 5, 'ایران: مهد حقوق مالکیت و آزادی واقعی', 'iran-cradle-of-freedom-property-rights', 'از اسطوره‌های باستانی تا امروز — چرا ایران، سرزمین دفاع از مالکیت و آزادی است', '', 'flag', 30, 20, TRUE, 5),
+(6, 6, 'تحلیل فلسفی آزادی', 'philosophical-analysis-freedom', 'بررسی عمیق آزادی در فلسفه اسلامی و غربی', '', 'brain', 32, 28, TRUE, 6),
+(7, 7, 'عدل الهی و حقوق بشر', 'divine-justice-human-rights', 'ارتباط اصل عدل در دین با حقوق مالکیت مطلق', '', 'balance-scale', 27, 22, FALSE, 7),
+(8, 8, 'جامعه منتظر و آزادی', 'awaiting-society-freedom', 'نقش امامت و مهدویت در جامعه آزاد', '', 'users', 20, 16, FALSE, 8),
+(9, 9, 'آزادی در جهان مدرن', 'freedom-modern-world', 'کاربرد نظریه در عصر دیجیتال و جهانی‌شدن', '', 'globe', 24, 19, FALSE, 9),
+(10, 10, 'آینده آزادی', 'future-of-freedom', 'چشم‌انداز جهانی آزادی بر پایه دین واقعی', '', 'rocket', 19, 15, FALSE, 10)
+ON CONFLICT (id) DO UPDATE SET
+    number = EXCLUDED.number,
+    title = EXCLUDED.title,
+    slug = EXCLUDED.slug,
+    description = EXCLUDED.description,
+    content = EXCLUDED.content,
+    icon = EXCLUDED.icon,
+    pages = EXCLUDED.pages,
+    read_time = EXCLUDED.read_time,
+    featured = EXCLUDED.featured,
+    "order" = EXCLUDED."order";
+
+-- Insert PDF resources
+INSERT INTO resources (id, type, number, title, description, file_url, file_size, pages, icon, "order") VALUES
+(1, 'pdf', 1, 'آزادی واقعی چیست؟', 'بازتعریف بنیادی آزادی به عنوان مالکیت مطلق بر وجود انسان', '/files/Freedom1.pdf', 11243527, 15, 'file-pdf', 1),
+(2, 'pdf', 2, 'نظام صوری آکسیوماتیک', 'دین به عنوان پایدارترین نظام منطقی تاریخ برای تضمین آزادی', '/files/Freedom2.pdf', 9123919, 22, 'file-pdf', 2),
+(3, 'pdf', 3, 'دین، نگهبان واقعی آزادی', 'چگونه دین واقعی بزرگ‌ترین دشمن دولت‌سالاری و حامی آزادی است', '/files/Freedom3.pdf', 6827586, 18, 'file-pdf', 3),
+(4, 'pdf', 4, 'کاربرد در ایران معاصر', 'راه‌حل دینی برای پایان تورم، سرکوب و نقض حریم خصوصی', '/files/Freedom4.pdf', 20595597, 25, 'file-pdf', 4),
+(5, 'pdf', 5, 'تحلیل فلسفی آزادی', 'بررسی عمیق آزادی در فلسفه اسلامی و غربی', '/files/Freedom5.pdf', 14362896, 32, 'file-pdf', 5),
+(6, 'pdf', 6, 'عدل الهی و حقوق بشر', 'ارتباط اصل عدل در دین با حقوق مالکیت مطلق', '/files/Freedom6.pdf', 20197576, 27, 'file-pdf', 6),
+(7, 'pdf', 7, 'جامعه منتظر و آزادی', 'نقش امامت و مهدویت در جامعه آزاد', '/files/Freedom7.pdf', 17605722, 20, 'file-pdf', 7),
+(8, 'pdf', 8, 'آزادی در جهان مدرن', 'کاربرد نظریه در عصر دیجیتال و جهانی‌شدن', '/files/Freedom8.pdf', 20625492, 24, 'file-pdf', 8),
+(9, 'pdf', 9, 'آینده آزادی', 'چشم‌انداز جهانی آزادی بر پایه دین واقعی', '/files/Freedom9.pdf', 14009992, 19, 'file-pdf', 9)
+ON CONFLICT (id) DO UPDATE SET
+    type = EXCLUDED.type,
+    number = EXCLUDED.number,
+    title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    file_url = EXCLUDED.file_url,
+    file_size = EXCLUDED.file_size,
+    pages = EXCLUDED.pages,
+    icon = EXCLUDED.icon,
+    "order" = EXCLUDED."order";
+
+-- Explicit ids do not advance the serial sequences in Postgres
+SELECT setval(pg_get_serial_sequence('chapters', 'id'), COALESCE((SELECT MAX(id) FROM chapters), 1));
+SELECT setval(pg_get_serial_sequence('resources', 'id'), COALESCE((SELECT MAX(id) FROM resources), 1));
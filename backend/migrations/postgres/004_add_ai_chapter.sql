@@ -0,0 +1,106 @@
+-- Postgres version of 004_add_ai_chapter.sql
+
+-- Add new chapter about AI and freedom
+INSERT INTO chapters (id, number, title, slug, description, content, icon, pages, read_time, featured, "order") VALUES
+(10, 10, 'آزادی در عصر هوش مصنوعی', 'freedom-in-age-of-ai', 'کاربرد نظریه آزادی جنت‌خواه در عصر هوش مصنوعی: دین به عنوان کد اخلاقی برای AI عادل و غیرظالم', 
+'<h1>آزادی در عصر هوش مصنوعی: دین به عنوان کد اخلاقی</h1>
+
+<p>در عصر هوش مصنوعی، سوال اساسی این است: چگونه می‌توانیم AI را طوری برنامه‌ریزی کنیم که عادل باشد و حقوق مالکیت انسان را نقض نکند؟ جنت‌خواه پاسخ جالبی دارد: <strong>دین به عنوان کد اخلاقی برای ماشین</strong>.</p>
+
+<h2>مشکل هوش مصنوعی امروز</h2>
+
+<p>هوش مصنوعی امروز با مشکلات جدی روبرو است:</p>
+
+<ul>
+<li><strong>نبود چارچوب اخلاقی پایدار:</strong> AI بدون اصول اخلاقی ثابت، می‌تواند به ابزار ظلم تبدیل شود</li>
+<li><strong>وابستگی به برنامه‌نویسان:</strong> ارزش‌های برنامه‌نویسان می‌تواند در AI نفوذ کند</li>
+<li><strong>نبود مکانیزم دفاع از حقوق مالکیت:</strong> AI ممکن است حریم خصوصی و حقوق مالکیت را نقض کند</li>
+</ul>
+
+<h2>راه‌حل: ترجمه دین به کد</h2>
+
+<p>جنت‌خواه می‌گوید: <strong>اصول پنج‌گانه دین (توحید، نبوت، معاد، عدل، امامت) را می‌توان به قوانین منطقی برای AI تبدیل کرد</strong>.</p>
+
+<blockquote>
+«اگر می‌خواهیم AI عادل و غیرظالم داشته باشیم، باید اصول دین را به زبان ماشین ترجمه کنیم — این تنها راه برای تضمین آزادی در عصر هوش مصنوعی است.»
+</blockquote>
+
+<h3>مثال عملی: چگونه دین به کد تبدیل می‌شود</h3>
+
+<table>
+<thead>
+<tr>
+<th>اصل دین</th>
+<th>ترجمه به کد AI</th>
+<th>نتیجه</th>
+</tr>
+</thead>
+<tbody>
+<tr>
+<td><strong>توحید</strong></td>
+<td>هیچ موجودی (حتی AI) نمی‌تواند خود را مالک انسان بداند</td>
+<td>AI نمی‌تواند دستورات ظالمانه بدهد</td>
+</tr>
+<tr>
+<td><strong>عدل</strong></td>
+<td>AI نمی‌تواند حقوق مالکیت (جسم، ذهن، زمان، دارایی) را نقض کند</td>
+<td>AI محافظ حریم خصوصی و حقوق مالکیت است</td>
+</tr>
+<tr>
+<td><strong>معاد</strong></td>
+<td>هر عمل AI باید قابل ردیابی و پاسخگو باشد</td>
+<td>شفافیت کامل در تصمیم‌گیری AI</td>
+</tr>
+<tr>
+<td><strong>نبوت</strong></td>
+<td>AI باید از الگوهای عادلانه پیروی کند (مثل رفتار پیامبران)</td>
+<td>AI بر اساس اخلاق الهی عمل می‌کند</td>
+</tr>
+<tr>
+<td><strong>امامت</strong></td>
+<td>AI در انتظار جامعه عادلانه عمل می‌کند، نه جامعه ظالم</td>
+<td>AI برای آزادی و عدالت کار می‌کند</td>
+</tr>
+</tbody>
+</table>
+
+<h2>آینده: AI عادل بر پایه دین</h2>
+
+<p>اگر این نظریه را به کار ببریم:</p>
+
+<ul>
+<li>✅ AI هرگز نمی‌تواند دستورات ظالمانه بدهد</li>
+<li>✅ AI حریم خصوصی را محافظت می‌کند</li>
+<li>✅ AI برای آزادی انسان کار می‌کند، نه علیه آن</li>
+<li>✅ AI قابل اعتماد و پیش‌بینی‌پذیر است</li>
+</ul>
+
+<h2>نتیجه‌گیری</h2>
+
+<p>در عصر هوش مصنوعی، نظریه جنت‌خواه راهنمای ما برای ساخت AI عادل است. دین به عنوان کد اخلاقی می‌تواند آزادی را در آینده تضمین کند.</p>
+
+<blockquote>
+«هوش مصنوعی می‌تواند بزرگ‌ترین تهدید یا بزرگ‌ترین فرصت برای آزادی باشد — انتخاب با ماست. با ترجمه اصول دین به کد، می‌توانیم AI عادل و آزادی‌ساز داشته باشیم.»
+</blockquote>
+
+<p style="text-align: center; margin-top: 3rem; font-size: 1.25rem; color: #1a5fb4;">
+<strong>این فصل نشان می‌دهد که نظریه آزادی جنت‌خواه فقط برای امروز نیست — برای آینده است.</strong>
+</p>', 
+'brain', 28, 22, TRUE, 10)
+ON CONFLICT (id) DO UPDATE SET
+    number = EXCLUDED.number,
+    title = EXCLUDED.title,
+    slug = EXCLUDED.slug,
+    description = EXCLUDED.description,
+    content = EXCLUDED.content,
+    icon = EXCLUDED.icon,
+    pages = EXCLUDED.pages,
+    read_time = EXCLUDED.read_time,
+    featured = EXCLUDED.featured,
+    "order" = EXCLUDED."order";
+
+-- Update order of existing chapters (if needed, to ensure proper ordering)
+UPDATE chapters SET "order" = "order" WHERE id <= 9;
+
+-- Explicit ids do not advance the serial sequences in Postgres
+SELECT setval(pg_get_serial_sequence('chapters', 'id'), COALESCE((SELECT MAX(id) FROM chapters), 1));
@@ -0,0 +1,80 @@
+-- Postgres version of 007_create_users.sql
+
+-- Create languages table (if not exists)
+CREATE TABLE IF NOT EXISTS languages (
+    id BIGSERIAL PRIMARY KEY,
+    code TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create currencies table (if not exists)
+CREATE TABLE IF NOT EXISTS currencies (
+    id BIGSERIAL PRIMARY KEY,
+    code TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    symbol TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create countries table (if not exists)
+CREATE TABLE IF NOT EXISTS countries (
+    id BIGSERIAL PRIMARY KEY,
+    code TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create users table
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    password TEXT NOT NULL,
+    name TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    telegram_id BIGINT,
+    language_id BIGINT REFERENCES languages(id) ON DELETE SET NULL,
+    currency_id BIGINT REFERENCES currencies(id) ON DELETE SET NULL,
+    country_id BIGINT REFERENCES countries(id) ON DELETE SET NULL,
+    preferred_date_format TEXT,
+    preferred_timezone TEXT,
+    number_format_preference TEXT,
+    currency_display_preference TEXT,
+    city TEXT,
+    address TEXT, -- JSON stored as TEXT to match SQLite
+    job_title TEXT,
+    bio TEXT,
+    mobile TEXT,
+    phone TEXT,
+    is_active BOOLEAN DEFAULT FALSE,
+    birthdate DATE,
+    email_verified_at TIMESTAMPTZ,
+    mobile_verified_at TIMESTAMPTZ,
+    phone_verified_at TIMESTAMPTZ,
+    photo_url TEXT,
+    referral_code TEXT NOT NULL UNIQUE,
+    invited_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    points INTEGER DEFAULT 0,
+    registration_src TEXT
+);
+
+-- Create email_verification_codes table
+CREATE TABLE IF NOT EXISTS email_verification_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code TEXT NOT NULL,
+    email TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+CREATE INDEX IF NOT EXISTS idx_users_referral_code ON users(referral_code);
+CREATE INDEX IF NOT EXISTS idx_users_invited_by ON users(invited_by);
+CREATE INDEX IF NOT EXISTS idx_users_telegram_id ON users(telegram_id);
+CREATE INDEX IF NOT EXISTS idx_email_verification_codes_user_id ON email_verification_codes(user_id);
+CREATE INDEX IF NOT EXISTS idx_email_verification_codes_code ON email_verification_codes(code);
+CREATE INDEX IF NOT EXISTS idx_email_verification_codes_email ON email_verification_codes(email);
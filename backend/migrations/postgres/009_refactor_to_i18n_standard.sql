@@ -0,0 +1,39 @@
+-- Postgres version of 009_refactor_to_i18n_standard.sql
+
+-- Step 1: Create chapter_translations table
+CREATE TABLE IF NOT EXISTS chapter_translations (
+    id SERIAL PRIMARY KEY,
+    chapter_id INTEGER NOT NULL REFERENCES chapters(id) ON DELETE CASCADE,
+    locale VARCHAR(10) NOT NULL, -- 'fa', 'en', 'ar', etc.
+    title TEXT NOT NULL,
+    slug TEXT NOT NULL,
+    description TEXT NOT NULL,
+    content TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(chapter_id, locale)
+);
+
+-- Step 2: Create index for faster lookups
+CREATE INDEX IF NOT EXISTS idx_chapter_translations_chapter_id ON chapter_translations(chapter_id);
+CREATE INDEX IF NOT EXISTS idx_chapter_translations_locale ON chapter_translations(locale);
+CREATE INDEX IF NOT EXISTS idx_chapter_translations_slug ON chapter_translations(slug);
+
+-- Step 3: Migrate existing data
+-- Copy Persian (fa) data from chapters table
+INSERT INTO chapter_translations (chapter_id, locale, title, slug, description, content)
+SELECT id, 'fa', title, slug, description, content
+FROM chapters
+WHERE content IS NOT NULL OR title IS NOT NULL
+ON CONFLICT (chapter_id, locale) DO NOTHING;
+
+-- Copy English (en) data from chapters table (content_en was added in migration 006)
+INSERT INTO chapter_translations (chapter_id, locale, title, slug, description, content)
+SELECT id, 'en',
+    title || ' (English)',  -- Temporary title, should be updated manually
+    slug || '-en',          -- Temporary slug
+    description || ' (English)', -- Temporary description
+    content_en
+FROM chapters
+WHERE content_en IS NOT NULL AND content_en != ''
+ON CONFLICT (chapter_id, locale) DO NOTHING;
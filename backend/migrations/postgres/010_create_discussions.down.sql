@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS thread_drafts;
+DROP TABLE IF EXISTS reactions;
+DROP TABLE IF EXISTS votes;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS threads;
+
+DROP FUNCTION IF EXISTS update_score_on_vote();
+DROP FUNCTION IF EXISTS update_thread_comment_count();
+DROP FUNCTION IF EXISTS set_updated_at();
@@ -0,0 +1,168 @@
+-- ============================================
+-- Discussions System Migration (Postgres version)
+-- ============================================
+-- Same tables as 010_create_discussions.sql; triggers are written as
+-- PL/pgSQL functions instead of SQLite trigger bodies
+
+-- Create threads table (main discussion posts/questions)
+CREATE TABLE IF NOT EXISTS threads (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    score INTEGER DEFAULT 0,
+    comment_count INTEGER DEFAULT 0,
+    view_count INTEGER DEFAULT 0,
+    is_pinned BOOLEAN DEFAULT FALSE,
+    is_locked BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    edited_at TIMESTAMPTZ
+);
+
+-- Create comments table (nested comments with unlimited depth)
+CREATE TABLE IF NOT EXISTS comments (
+    id BIGSERIAL PRIMARY KEY,
+    thread_id BIGINT NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parent_id BIGINT REFERENCES comments(id) ON DELETE CASCADE, -- NULL for top-level comments
+    content TEXT NOT NULL,
+    score INTEGER DEFAULT 0,
+    depth INTEGER DEFAULT 0,
+    is_deleted BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    edited_at TIMESTAMPTZ
+);
+
+-- Create votes table (upvotes/downvotes for threads and comments)
+CREATE TABLE IF NOT EXISTS votes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    thread_id BIGINT REFERENCES threads(id) ON DELETE CASCADE,
+    comment_id BIGINT REFERENCES comments(id) ON DELETE CASCADE,
+    vote_type INTEGER NOT NULL, -- 1 for upvote, -1 for downvote
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, thread_id, comment_id),
+    CHECK((thread_id IS NOT NULL AND comment_id IS NULL) OR (thread_id IS NULL AND comment_id IS NOT NULL))
+);
+
+-- Create reactions table (emoji reactions)
+CREATE TABLE IF NOT EXISTS reactions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    thread_id BIGINT REFERENCES threads(id) ON DELETE CASCADE,
+    comment_id BIGINT REFERENCES comments(id) ON DELETE CASCADE,
+    reaction_type TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, thread_id, comment_id, reaction_type),
+    CHECK((thread_id IS NOT NULL AND comment_id IS NULL) OR (thread_id IS NULL AND comment_id IS NOT NULL)),
+    CHECK(reaction_type IN ('heart', 'clap', 'thumbs_up', 'thumbs_down'))
+);
+
+-- Create thread_drafts table (for saving draft posts)
+CREATE TABLE IF NOT EXISTS thread_drafts (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title TEXT,
+    content TEXT,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_threads_user_id ON threads(user_id);
+CREATE INDEX IF NOT EXISTS idx_threads_created_at ON threads(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_threads_score ON threads(score DESC);
+CREATE INDEX IF NOT EXISTS idx_threads_is_pinned ON threads(is_pinned DESC);
+
+CREATE INDEX IF NOT EXISTS idx_comments_thread_id ON comments(thread_id);
+CREATE INDEX IF NOT EXISTS idx_comments_user_id ON comments(user_id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(parent_id);
+CREATE INDEX IF NOT EXISTS idx_comments_created_at ON comments(created_at);
+CREATE INDEX IF NOT EXISTS idx_comments_depth ON comments(depth);
+
+CREATE INDEX IF NOT EXISTS idx_votes_user_id ON votes(user_id);
+CREATE INDEX IF NOT EXISTS idx_votes_thread_id ON votes(thread_id);
+CREATE INDEX IF NOT EXISTS idx_votes_comment_id ON votes(comment_id);
+
+CREATE INDEX IF NOT EXISTS idx_reactions_user_id ON reactions(user_id);
+CREATE INDEX IF NOT EXISTS idx_reactions_thread_id ON reactions(thread_id);
+CREATE INDEX IF NOT EXISTS idx_reactions_comment_id ON reactions(comment_id);
+
+CREATE INDEX IF NOT EXISTS idx_thread_drafts_user_id ON thread_drafts(user_id);
+
+-- Keep updated_at current on threads and comments
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS update_threads_updated_at ON threads;
+CREATE TRIGGER update_threads_updated_at
+BEFORE UPDATE ON threads
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+DROP TRIGGER IF EXISTS update_comments_updated_at ON comments;
+CREATE TRIGGER update_comments_updated_at
+BEFORE UPDATE ON comments
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Keep thread.comment_count in sync when comments are added/deleted
+CREATE OR REPLACE FUNCTION update_thread_comment_count() RETURNS trigger AS $$
+BEGIN
+    UPDATE threads SET comment_count = (
+        SELECT COUNT(*) FROM comments
+        WHERE thread_id = NEW.thread_id AND is_deleted = FALSE
+    ) WHERE id = NEW.thread_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS update_thread_comment_count_insert ON comments;
+CREATE TRIGGER update_thread_comment_count_insert
+AFTER INSERT ON comments
+FOR EACH ROW EXECUTE FUNCTION update_thread_comment_count();
+
+DROP TRIGGER IF EXISTS update_thread_comment_count_delete ON comments;
+CREATE TRIGGER update_thread_comment_count_delete
+AFTER UPDATE OF is_deleted ON comments
+FOR EACH ROW
+WHEN (NEW.is_deleted = TRUE AND OLD.is_deleted = FALSE)
+EXECUTE FUNCTION update_thread_comment_count();
+
+-- Keep thread.score and comment.score in sync with votes
+CREATE OR REPLACE FUNCTION update_score_on_vote() RETURNS trigger AS $$
+DECLARE
+    v_thread_id BIGINT;
+    v_comment_id BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        v_thread_id := OLD.thread_id;
+        v_comment_id := OLD.comment_id;
+    ELSE
+        v_thread_id := NEW.thread_id;
+        v_comment_id := NEW.comment_id;
+    END IF;
+
+    IF v_thread_id IS NOT NULL THEN
+        UPDATE threads SET score = (
+            SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE thread_id = v_thread_id
+        ) WHERE id = v_thread_id;
+    END IF;
+
+    IF v_comment_id IS NOT NULL THEN
+        UPDATE comments SET score = (
+            SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE comment_id = v_comment_id
+        ) WHERE id = v_comment_id;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS update_score_on_vote ON votes;
+CREATE TRIGGER update_score_on_vote
+AFTER INSERT OR UPDATE OR DELETE ON votes
+FOR EACH ROW EXECUTE FUNCTION update_score_on_vote();
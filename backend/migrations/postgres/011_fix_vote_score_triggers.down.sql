@@ -0,0 +1 @@
+-- Nothing to undo: the score recompute is not reversible and no schema changed.
@@ -0,0 +1,9 @@
+-- The PL/pgSQL score trigger from 010 already handles insert/update/delete.
+-- Only recompute scores, matching the SQLite migration.
+UPDATE threads SET score = (
+    SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE votes.thread_id = threads.id
+) WHERE score != (SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE votes.thread_id = threads.id);
+
+UPDATE comments SET score = (
+    SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE votes.comment_id = comments.id
+) WHERE score != (SELECT COALESCE(SUM(vote_type), 0) FROM votes WHERE votes.comment_id = comments.id);
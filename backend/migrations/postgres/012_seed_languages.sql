@@ -0,0 +1,4 @@
+-- Languages that chapter translations exist for; users.language_id points here
+-- and drives chapter locale negotiation
+INSERT INTO languages (code, name) VALUES ('fa', 'فارسی') ON CONFLICT (code) DO NOTHING;
+INSERT INTO languages (code, name) VALUES ('en', 'English') ON CONFLICT (code) DO NOTHING;
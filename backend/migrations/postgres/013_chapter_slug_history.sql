@@ -0,0 +1,32 @@
+-- Postgres version of 013_chapter_slug_history.sql
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chapter_translations_locale_slug ON chapter_translations(locale, slug);
+
+CREATE TABLE IF NOT EXISTS chapter_slug_history (
+    id SERIAL PRIMARY KEY,
+    chapter_id INTEGER NOT NULL REFERENCES chapters(id) ON DELETE CASCADE,
+    locale VARCHAR(10) NOT NULL,
+    slug TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(locale, slug)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chapter_slug_history_chapter_id ON chapter_slug_history(chapter_id);
+
+CREATE OR REPLACE FUNCTION record_chapter_slug_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD.slug <> NEW.slug THEN
+        INSERT INTO chapter_slug_history (chapter_id, locale, slug)
+        VALUES (OLD.chapter_id, OLD.locale, OLD.slug)
+        ON CONFLICT (locale, slug) DO UPDATE
+            SET chapter_id = EXCLUDED.chapter_id, created_at = CURRENT_TIMESTAMP;
+    END IF;
+    DELETE FROM chapter_slug_history WHERE locale = NEW.locale AND slug = NEW.slug;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS record_chapter_slug_change ON chapter_translations;
+CREATE TRIGGER record_chapter_slug_change
+AFTER INSERT OR UPDATE OF slug ON chapter_translations
+FOR EACH ROW EXECUTE FUNCTION record_chapter_slug_change();
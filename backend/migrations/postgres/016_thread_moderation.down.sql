@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS update_thread_comment_count_restore ON comments;
+DROP TABLE IF EXISTS moderation_actions;
+ALTER TABLE threads DROP COLUMN IF EXISTS is_deleted;
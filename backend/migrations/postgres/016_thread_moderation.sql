@@ -0,0 +1,24 @@
+-- Postgres version of 016_thread_moderation.sql
+
+ALTER TABLE threads ADD COLUMN IF NOT EXISTS is_deleted BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS moderation_actions (
+    id SERIAL PRIMARY KEY,
+    moderator_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    thread_id INTEGER REFERENCES threads(id) ON DELETE SET NULL,
+    comment_id INTEGER REFERENCES comments(id) ON DELETE SET NULL,
+    action VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_thread_id ON moderation_actions(thread_id);
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_comment_id ON moderation_actions(comment_id);
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_created_at ON moderation_actions(created_at DESC);
+
+DROP TRIGGER IF EXISTS update_thread_comment_count_restore ON comments;
+CREATE TRIGGER update_thread_comment_count_restore
+AFTER UPDATE OF is_deleted ON comments
+FOR EACH ROW
+WHEN (NEW.is_deleted = FALSE AND OLD.is_deleted = TRUE)
+EXECUTE FUNCTION update_thread_comment_count();
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_invited_by;
+DROP TABLE IF EXISTS points_ledger;
+DROP FUNCTION IF EXISTS points_ledger_append_only();
@@ -0,0 +1,26 @@
+-- Postgres version of 022_create_points_ledger.sql
+
+CREATE TABLE IF NOT EXISTS points_ledger (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INTEGER NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+    source_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_points_ledger_user ON points_ledger(user_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_points_ledger_once ON points_ledger(user_id, reason, source_user_id);
+
+CREATE OR REPLACE FUNCTION points_ledger_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'points_ledger is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS points_ledger_append_only ON points_ledger;
+CREATE TRIGGER points_ledger_append_only
+BEFORE UPDATE OF user_id, amount, reason, created_at ON points_ledger
+FOR EACH ROW EXECUTE FUNCTION points_ledger_append_only();
+
+CREATE INDEX IF NOT EXISTS idx_users_invited_by ON users(invited_by);
@@ -0,0 +1,8 @@
+-- Postgres version of 027_comment_author_deletion.sql
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS author_deleted BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE comments SET author_deleted = TRUE
+WHERE is_deleted = TRUE AND id NOT IN (
+    SELECT comment_id FROM moderation_actions WHERE comment_id IS NOT NULL AND action = 'delete'
+);
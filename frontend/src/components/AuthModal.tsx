@@ -1,6 +1,6 @@
 import React, { useState, useEffect, useRef } from 'react';
 import { motion, AnimatePresence } from 'framer-motion';
-import { authApi, setAuthToken } from '../services/api';
+import { authApi, isTwoFactorChallenge, setAuthToken } from '../services/api';
 import { useLocale } from '../i18n/LocaleProvider';
 import { 
   EnvelopeIcon, 
@@ -33,6 +33,9 @@ const AuthModal: React.FC<AuthModalProps> = ({ isOpen, onClose, initialTab = 'lo
   const [loginLoading, setLoginLoading] = useState(false);
   const [loginError, setLoginError] = useState('');
   const [loginSuccess, setLoginSuccess] = useState('');
+  // Set while the login waits for a two-factor code
+  const [challengeToken, setChallengeToken] = useState('');
+  const [twoFactorCode, setTwoFactorCode] = useState('');
 
   // Register state
   const [registerData, setRegisterData] = useState({
@@ -100,8 +103,14 @@ const AuthModal: React.FC<AuthModalProps> = ({ isOpen, onClose, initialTab = 'lo
     setLoginLoading(true);
 
     try {
-      const response = await authApi.login(loginData);
-      setAuthToken(response.token);
+      const response = challengeToken
+        ? await authApi.completeTwoFactor(challengeToken, twoFactorCode)
+        : await authApi.login(loginData);
+      if (isTwoFactorChallenge(response)) {
+        setChallengeToken(response.challenge_token);
+        return;
+      }
+      setAuthToken(response.token, response.refresh_token);
       setLoginSuccess(isRTL ? 'ورود موفق! در حال انتقال...' : 'Login successful! Redirecting...');
       setTimeout(() => {
         onClose();
@@ -111,6 +120,12 @@ const AuthModal: React.FC<AuthModalProps> = ({ isOpen, onClose, initialTab = 'lo
       }, 1000);
     } catch (err: any) {
       const errorMessage = err.response?.data?.error || (isRTL ? 'ورود ناموفق بود. لطفا دوباره تلاش کنید.' : 'Login failed. Please try again.');
+
+      // The challenge expired or ran out of attempts; start over
+      if (errorMessage.includes('Login expired')) {
+        setChallengeToken('');
+        setTwoFactorCode('');
+      }
       
       // Check if email is not verified
       if (errorMessage.includes('Email not verified') || errorMessage.includes('email not verified')) {
@@ -172,7 +187,7 @@ const AuthModal: React.FC<AuthModalProps> = ({ isOpen, onClose, initialTab = 'lo
         email: emailToVerify,
         code: verificationCode,
       });
-      setAuthToken(response.token);
+      setAuthToken(response.token, response.refresh_token);
       setRegisterSuccess(isRTL ? 'ایمیل تایید شد! در حال انتقال...' : 'Email verified! Redirecting...');
       setTimeout(() => {
         onClose();
@@ -325,6 +340,29 @@ const AuthModal: React.FC<AuthModalProps> = ({ isOpen, onClose, initialTab = 'lo
                         </motion.div>
                       )}
 
+                      {challengeToken ? (
+                      <div>
+                        <label className="block text-sm font-semibold text-gray-700 mb-2.5">
+                          {isRTL ? 'کد تایید دو مرحله‌ای' : 'Two-factor code'}
+                        </label>
+                        <input
+                          type="text"
+                          required
+                          autoFocus
+                          autoComplete="one-time-code"
+                          value={twoFactorCode}
+                          onChange={(e) => setTwoFactorCode(e.target.value)}
+                          className="w-full px-4 py-3.5 border-2 border-gray-200 rounded-xl focus:ring-2 focus:ring-primary-500 focus:border-primary-500 transition-all bg-gray-50 focus:bg-white text-center text-xl tracking-widest font-mono"
+                          placeholder="000000"
+                        />
+                        <p className="mt-2 text-xs text-gray-500">
+                          {isRTL
+                            ? 'کد برنامه احراز هویت یا یکی از کدهای بازیابی را وارد کنید.'
+                            : 'Enter the code from your authenticator app, or one of your recovery codes.'}
+                        </p>
+                      </div>
+                      ) : (
+                      <>
                       <div>
                         <label className="block text-sm font-semibold text-gray-700 mb-2.5">
                           {isRTL ? 'ایمیل' : 'Email'}
@@ -369,6 +407,8 @@ const AuthModal: React.FC<AuthModalProps> = ({ isOpen, onClose, initialTab = 'lo
                           </button>
                         </div>
                       </div>
+                      </>
+                      )}
 
                       <button
                         type="submit"
@@ -1,7 +1,7 @@
 import React, { useState } from 'react';
 import { Link, useNavigate, useParams } from 'react-router-dom';
 import { motion } from 'framer-motion';
-import { authApi, setAuthToken } from '../services/api';
+import { authApi, isTwoFactorChallenge, setAuthToken } from '../services/api';
 import { useLocale } from '../i18n/LocaleProvider';
 import { withLocalePath } from '../i18n/localePath';
 import { Locale } from '../i18n/messages';
@@ -31,6 +31,8 @@ const Login: React.FC = () => {
   const [verificationCode, setVerificationCode] = useState('');
   const [verifying, setVerifying] = useState(false);
   const [resendingCode, setResendingCode] = useState(false);
+  // Set while the login waits for a two-factor code
+  const [challengeToken, setChallengeToken] = useState('');
 
   const handleChange = (e: React.ChangeEvent<HTMLInputElement>) => {
     setFormData({
@@ -48,7 +50,11 @@ const Login: React.FC = () => {
 
     try {
       const response = await authApi.login(formData);
-      setAuthToken(response.token);
+      if (isTwoFactorChallenge(response)) {
+        setChallengeToken(response.challenge_token);
+        return;
+      }
+      setAuthToken(response.token, response.refresh_token);
       setSuccess('Login successful! Redirecting...');
       setTimeout(() => {
         const validLocale: Locale = (locale === 'fa' || locale === 'en') ? locale : 'fa';
@@ -85,18 +91,28 @@ const Login: React.FC = () => {
     setVerifying(true);
 
     try {
-      const response = await authApi.verifyEmail({
-        email: formData.email,
-        code: verificationCode,
-      });
-      setAuthToken(response.token);
-      setSuccess(isRTL ? 'ایمیل تایید شد! در حال انتقال...' : 'Email verified! Redirecting...');
+      const response = challengeToken
+        ? await authApi.completeTwoFactor(challengeToken, verificationCode)
+        : await authApi.verifyEmail({
+            email: formData.email,
+            code: verificationCode,
+          });
+      setAuthToken(response.token, response.refresh_token);
+      setSuccess(challengeToken
+        ? (isRTL ? 'ورود موفق! در حال انتقال...' : 'Login successful! Redirecting...')
+        : (isRTL ? 'ایمیل تایید شد! در حال انتقال...' : 'Email verified! Redirecting...'));
       setTimeout(() => {
         const validLocale: Locale = (locale === 'fa' || locale === 'en') ? locale : 'fa';
         navigate(withLocalePath(validLocale, '/'));
       }, 1000);
     } catch (err: any) {
-      setError(err.response?.data?.error || (isRTL ? 'کد تایید نامعتبر است' : 'Invalid verification code'));
+      const errorMessage = err.response?.data?.error || (isRTL ? 'کد تایید نامعتبر است' : 'Invalid verification code');
+      // The challenge expired or ran out of attempts; start over
+      if (challengeToken && errorMessage.includes('Login expired')) {
+        setChallengeToken('');
+        setVerificationCode('');
+      }
+      setError(errorMessage);
     } finally {
       setVerifying(false);
     }
@@ -117,8 +133,9 @@ const Login: React.FC = () => {
   };
 
 
-  // Show verification form if email is not verified
-  if (showVerification) {
+  // Show the code form if email is not verified, or to finish a two-factor login
+  if (showVerification || challengeToken) {
+    const twoFactor = challengeToken !== '';
     return (
       <div className="min-h-screen bg-gradient-to-br from-primary-50 via-white to-primary-100 flex items-center justify-center p-4">
         <motion.div
@@ -134,7 +151,9 @@ const Login: React.FC = () => {
                 RealFreedom
               </h1>
               <p className="text-gray-600 text-sm md:text-base">
-                {isRTL ? 'تایید ایمیل' : 'Verify Email'}
+                {twoFactor
+                  ? (isRTL ? 'ورود دو مرحله‌ای' : 'Two-Factor Login')
+                  : (isRTL ? 'تایید ایمیل' : 'Verify Email')}
               </p>
             </div>
 
@@ -165,7 +184,11 @@ const Login: React.FC = () => {
             <form onSubmit={handleVerify} className="space-y-6">
               <div>
                 <p className="text-sm text-gray-600 mb-4 text-center">
-                  {isRTL 
+                  {twoFactor
+                    ? (isRTL
+                      ? 'کد برنامه احراز هویت یا یکی از کدهای بازیابی را وارد کنید.'
+                      : 'Enter the code from your authenticator app, or one of your recovery codes.')
+                    : isRTL 
                     ? `کد تایید 5 رقمی به ${formData.email} ارسال شد. لطفا کد را وارد کنید.`
                     : `A 5-digit verification code has been sent to ${formData.email}. Please enter the code.`
                   }
@@ -177,22 +200,24 @@ const Login: React.FC = () => {
                   id="code"
                   name="code"
                   type="text"
-                  maxLength={5}
+                  maxLength={twoFactor ? 19 : 5}
                   required
+                  autoComplete={twoFactor ? 'one-time-code' : undefined}
                   value={verificationCode}
                   onChange={(e) => {
-                    const value = e.target.value.replace(/\D/g, '').slice(0, 5);
+                    // Recovery codes have letters and dashes
+                    const value = twoFactor ? e.target.value : e.target.value.replace(/\D/g, '').slice(0, 5);
                     setVerificationCode(value);
                     setError('');
                   }}
                   className="block w-full px-4 py-3 border border-gray-300 rounded-lg focus:ring-2 focus:ring-primary-500 focus:border-primary-500 transition-all text-center text-2xl tracking-widest font-mono"
-                  placeholder="00000"
+                  placeholder={twoFactor ? '000000' : '00000'}
                 />
               </div>
 
               <button
                 type="submit"
-                disabled={verifying || verificationCode.length !== 5}
+                disabled={verifying || (twoFactor ? verificationCode.length < 6 : verificationCode.length !== 5)}
                 className="w-full bg-gradient-to-r from-primary-600 to-primary-700 text-white py-3 px-4 rounded-lg font-semibold shadow-lg hover:shadow-xl transform hover:-translate-y-0.5 transition-all duration-200 disabled:opacity-50 disabled:cursor-not-allowed disabled:transform-none"
               >
                 {verifying ? (
@@ -210,6 +235,7 @@ const Login: React.FC = () => {
             </form>
 
             <div className="mt-6 space-y-3">
+              {!twoFactor && (
               <button
                 onClick={handleResendCode}
                 disabled={resendingCode}
@@ -221,9 +247,11 @@ const Login: React.FC = () => {
                   isRTL ? 'ارسال مجدد کد' : 'Resend Code'
                 )}
               </button>
+              )}
               <button
                 onClick={() => {
                   setShowVerification(false);
+                  setChallengeToken('');
                   setVerificationCode('');
                   setError('');
                   setSuccess('');
@@ -1,5 +1,5 @@
 import React, { useState } from 'react';
-import { Link, useNavigate, useParams } from 'react-router-dom';
+import { Link, useNavigate, useParams, useSearchParams } from 'react-router-dom';
 import { motion } from 'framer-motion';
 import { authApi, setAuthToken } from '../services/api';
 import { useLocale } from '../i18n/LocaleProvider';
@@ -19,6 +19,9 @@ const Register: React.FC = () => {
   const { locale } = useParams<{ locale: string }>();
   const { isRTL } = useLocale();
   const navigate = useNavigate();
+  // Invite links look like /fa/register?ref=RF7KQ2M9XD
+  const [searchParams] = useSearchParams();
+  const referralCode = searchParams.get('ref') || undefined;
   
   const [formData, setFormData] = useState({
     email: '',
@@ -66,6 +69,7 @@ const Register: React.FC = () => {
         email: formData.email,
         password: formData.password,
         name: formData.name || undefined,
+        referral_code: referralCode,
       });
       setSuccess(isRTL ? 'ثبت نام موفق! لطفا ایمیل خود را بررسی کنید.' : 'Registration successful! Please check your email.');
       setStep('verify');
@@ -86,7 +90,7 @@ const Register: React.FC = () => {
         email: formData.email,
         code: verificationCode,
       });
-      setAuthToken(response.token);
+      setAuthToken(response.token, response.refresh_token);
       setSuccess(isRTL ? 'ایمیل تایید شد! در حال انتقال...' : 'Email verified! Redirecting...');
       setTimeout(() => {
         const validLocale: Locale = (locale === 'fa' || locale === 'en') ? locale : 'fa';
@@ -149,6 +149,7 @@ export interface RegisterRequest {
   email: string;
   password: string;
   name?: string;
+  referral_code?: string;
 }
 
 export interface LoginRequest {
@@ -161,11 +162,29 @@ export interface VerifyEmailRequest {
   code: string;
 }
 
-export interface AuthResponse {
+export interface TokenPair {
   token: string;
+  expires_at: string;
+  refresh_token: string;
+  refresh_expires_at: string;
+}
+
+export interface AuthResponse extends TokenPair {
   user: User;
 }
 
+// Login answers this instead of tokens when the user has two-factor
+// authentication on; completeTwoFactor finishes the login
+export interface TwoFactorChallenge {
+  two_factor_required: true;
+  challenge_token: string;
+  expires_at: string;
+}
+
+export const isTwoFactorChallenge = (
+  response: AuthResponse | TwoFactorChallenge
+): response is TwoFactorChallenge => 'two_factor_required' in response;
+
 // Auth API
 export const authApi = {
   register: async (data: RegisterRequest): Promise<{ message: string; user_id: number }> => {
@@ -183,11 +202,17 @@ export const authApi = {
     return response.data;
   },
 
-  login: async (data: LoginRequest): Promise<AuthResponse> => {
+  login: async (data: LoginRequest): Promise<AuthResponse | TwoFactorChallenge> => {
     const response = await api.post('/auth/login', data);
     return response.data;
   },
 
+  // code is a code from the authenticator app or a recovery code
+  completeTwoFactor: async (challengeToken: string, code: string): Promise<AuthResponse> => {
+    const response = await api.post('/auth/2fa', { challenge_token: challengeToken, code });
+    return response.data;
+  },
+
   getMe: async (): Promise<User> => {
     const token = localStorage.getItem('auth_token');
     if (!token) {
@@ -332,17 +357,64 @@ export const discussionApi = {
 };
 
 // Helper function to set auth token
-export const setAuthToken = (token: string) => {
+export const setAuthToken = (token: string, refreshToken?: string) => {
   localStorage.setItem('auth_token', token);
+  if (refreshToken) {
+    localStorage.setItem('refresh_token', refreshToken);
+  }
   api.defaults.headers.common['Authorization'] = `Bearer ${token}`;
 };
 
-// Helper function to remove auth token
+// Helper function to remove auth token, ending the session on the server
 export const removeAuthToken = () => {
+  const refreshToken = localStorage.getItem('refresh_token');
+  if (refreshToken) {
+    api.post('/auth/logout', { refresh_token: refreshToken }).catch(() => {});
+  }
   localStorage.removeItem('auth_token');
+  localStorage.removeItem('refresh_token');
   delete api.defaults.headers.common['Authorization'];
 };
 
+// Access tokens are short-lived: on a 401, exchange the refresh token once and
+// retry. Concurrent failures share one refresh, since each token works once.
+let refreshing: Promise<string> | null = null;
+
+const refreshAuthToken = (): Promise<string> => {
+  if (!refreshing) {
+    const refreshToken = localStorage.getItem('refresh_token');
+    refreshing = (refreshToken
+      ? api.post<TokenPair>('/auth/refresh', { refresh_token: refreshToken }).then((response) => {
+          setAuthToken(response.data.token, response.data.refresh_token);
+          return response.data.token;
+        })
+      : Promise.reject(new Error('No refresh token found'))
+    ).finally(() => {
+      refreshing = null;
+    });
+  }
+  return refreshing;
+};
+
+api.interceptors.response.use(undefined, async (error) => {
+  const request = error.config;
+  if (error.response?.status !== 401 || !request || request._retried || request.url?.startsWith('/auth/')) {
+    return Promise.reject(error);
+  }
+
+  try {
+    const token = await refreshAuthToken();
+    request._retried = true;
+    request.headers.Authorization = `Bearer ${token}`;
+    return api(request);
+  } catch {
+    localStorage.removeItem('auth_token');
+    localStorage.removeItem('refresh_token');
+    delete api.defaults.headers.common['Authorization'];
+    return Promise.reject(error);
+  }
+});
+
 // Initialize auth token if exists
 const token = localStorage.getItem('auth_token');
 if (token) {